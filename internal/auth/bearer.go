@@ -6,7 +6,9 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 )
 
@@ -21,3 +23,11 @@ func CreateBearerToken() (string, error) {
 
 	return token, nil
 }
+
+// HashBearerToken returns the hash of the bearer token which is used
+// to store the token's record in the database.
+func HashBearerToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ClientAuthMethodNone          string = "none"
+	ClientAuthMethodSecretBasic   string = "client_secret_basic"
+	ClientAuthMethodSecretPost    string = "client_secret_post"
+	ClientAuthMethodPrivateKeyJWT string = "private_key_jwt"
+
+	// ClientAssertionType is the type of client assertion used with private_key_jwt (RFC 7523).
+	ClientAssertionType string = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+var (
+	ErrInvalidPublicKey       = errors.New("the public key must be a PEM encoded RSA, ECDSA or Ed25519 public key")
+	ErrMissingAssertionID     = errors.New("the client assertion does not contain the 'jti' claim")
+	ErrAssertionLifetimeLimit = errors.New("the client assertion expires too far in the future")
+)
+
+// maxAssertionLifetime is the maximum time until the client assertion expires.
+// This limits how long the IDs of the used assertions need to be remembered.
+const maxAssertionLifetime = 10 * time.Minute
+
+// ClientAuthMethods returns the supported client authentication methods
+// for the confidential clients.
+func ClientAuthMethods() []string {
+	return []string{
+		ClientAuthMethodSecretBasic,
+		ClientAuthMethodSecretPost,
+		ClientAuthMethodPrivateKeyJWT,
+	}
+}
+
+// CreateClientSecret creates a new random client secret.
+func CreateClientSecret() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to create random bytes: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ParsePublicKey parses the PEM encoded public key of a client that authenticates
+// with private_key_jwt.
+func ParsePublicKey(pemData string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrInvalidPublicKey
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPublicKey, err)
+	}
+
+	if len(signingMethodsForKey(publicKey)) == 0 {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return publicKey, nil
+}
+
+// ClientAssertion contains the claims of a verified client assertion.
+type ClientAssertion struct {
+	Issuer    string
+	Subject   string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// VerifyClientAssertion verifies the signature and the claims of the client assertion
+// sent by a client authenticating with private_key_jwt. The assertion must be intended
+// for one of the given audiences. The caller is responsible for checking the issuer and
+// subject against the client ID and for rejecting assertions that have been used before.
+func VerifyClientAssertion(assertion string, publicKey crypto.PublicKey, audiences []string) (ClientAssertion, error) {
+	keyFunc := func(_ *jwt.Token) (any, error) {
+		return publicKey, nil
+	}
+
+	token, err := jwt.ParseWithClaims(
+		assertion,
+		&jwt.RegisteredClaims{},
+		keyFunc,
+		jwt.WithValidMethods(signingMethodsForKey(publicKey)),
+		jwt.WithAudience(audiences...),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return ClientAssertion{}, fmt.Errorf("client assertion parsing failed: %w", err)
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok {
+		return ClientAssertion{}, errors.New("unknown claims type")
+	}
+
+	if claims.ID == "" {
+		return ClientAssertion{}, ErrMissingAssertionID
+	}
+
+	if time.Until(claims.ExpiresAt.Time) > maxAssertionLifetime {
+		return ClientAssertion{}, ErrAssertionLifetimeLimit
+	}
+
+	return ClientAssertion{
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		ID:        claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// ClientAssertionSigningMethods returns the supported signing algorithms
+// for the client assertions.
+func ClientAssertionSigningMethods() []string {
+	return slices.Concat(rsaSigningMethods(), ecdsaSigningMethods(), ed25519SigningMethods())
+}
+
+func signingMethodsForKey(publicKey crypto.PublicKey) []string {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsaSigningMethods()
+	case *ecdsa.PublicKey:
+		return ecdsaSigningMethods()
+	case ed25519.PublicKey:
+		return ed25519SigningMethods()
+	default:
+		return nil
+	}
+}
+
+func rsaSigningMethods() []string {
+	return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+}
+
+func ecdsaSigningMethods() []string {
+	return []string{"ES256", "ES384", "ES512"}
+}
+
+func ed25519SigningMethods() []string {
+	return []string{"EdDSA"}
+}
+
+// ValidClientAuthMethod returns true if the authentication method is supported
+// for the confidential clients.
+func ValidClientAuthMethod(method string) bool {
+	return slices.Contains(ClientAuthMethods(), method)
+}
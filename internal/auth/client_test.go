@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyClientAssertion(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to generate the key pair: %v", t.Name(), err)
+	}
+
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to marshal the public key: %v", t.Name(), err)
+	}
+
+	parsedKey, err := auth.ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})))
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to parse the public key: %v", t.Name(), err)
+	}
+
+	clientID := "https://internal.example.org/"
+	audience := "https://auth.example.net/indieauth/token"
+
+	signAssertion := func(claims jwt.RegisteredClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to sign the client assertion: %v", t.Name(), err)
+		}
+
+		return signed
+	}
+
+	validClaims := jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{audience},
+		ID:        "assertion-1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Minute)),
+	}
+
+	got, err := auth.VerifyClientAssertion(signAssertion(validClaims), parsedKey, []string{audience})
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Received an error verifying a valid client assertion.\ngot: %q",
+			t.Name(),
+			err.Error(),
+		)
+	}
+
+	if got.Issuer != clientID || got.Subject != clientID || got.ID != "assertion-1" {
+		t.Errorf(
+			"FAILED test %s: Unexpected claims returned from the client assertion.\ngot: %+v",
+			t.Name(),
+			got,
+		)
+	} else {
+		t.Logf("Expected claims returned from the client assertion.\ngot: %+v", got)
+	}
+
+	wrongAudience := validClaims
+	wrongAudience.Audience = jwt.ClaimStrings{"https://other.example.net/token"}
+
+	if _, err := auth.VerifyClientAssertion(signAssertion(wrongAudience), parsedKey, []string{audience}); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error received for a client assertion with the wrong audience.\ngot: %v",
+			t.Name(),
+			err,
+		)
+	} else {
+		t.Logf("Expected error received for a client assertion with the wrong audience.\ngot: %q", err.Error())
+	}
+
+	missingID := validClaims
+	missingID.ID = ""
+
+	if _, err := auth.VerifyClientAssertion(signAssertion(missingID), parsedKey, []string{audience}); !errors.Is(err, auth.ErrMissingAssertionID) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error received for a client assertion without an ID.\ngot: %v",
+			t.Name(),
+			err,
+		)
+	} else {
+		t.Logf("Expected error received for a client assertion without an ID.\ngot: %q", err.Error())
+	}
+
+	longLived := validClaims
+	longLived.ExpiresAt = jwt.NewNumericDate(time.Now().Add(24 * time.Hour))
+
+	if _, err := auth.VerifyClientAssertion(signAssertion(longLived), parsedKey, []string{audience}); !errors.Is(err, auth.ErrAssertionLifetimeLimit) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error received for a long lived client assertion.\ngot: %v",
+			t.Name(),
+			err,
+		)
+	} else {
+		t.Logf("Expected error received for a long lived client assertion.\ngot: %q", err.Error())
+	}
+
+	if _, err := auth.ParsePublicKey("not a public key"); !errors.Is(err, auth.ErrInvalidPublicKey) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error received after parsing an invalid public key.\ngot: %v",
+			t.Name(),
+			err,
+		)
+	}
+}
@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	dpopProofType = "dpop+jwt"
+
+	// DPoPProofWindow is how far the issue time of a DPoP proof may be from the current
+	// time. Proofs are rejected outside of this window so the IDs of the used proofs only
+	// need to be remembered for this long.
+	DPoPProofWindow = 5 * time.Minute
+)
+
+var (
+	ErrInvalidDPoPProofType  = errors.New("the DPoP proof does not have the 'dpop+jwt' type")
+	ErrMissingDPoPKey        = errors.New("the DPoP proof does not contain a public key")
+	ErrPrivateDPoPKey        = errors.New("the DPoP proof contains a private key")
+	ErrUnsupportedDPoPKey    = errors.New("the key in the DPoP proof is not a supported RSA, EC or OKP key")
+	ErrMissingDPoPProofID    = errors.New("the DPoP proof does not contain the 'jti' claim")
+	ErrMismatchedDPoPMethod  = errors.New("the HTTP method in the DPoP proof does not match the request")
+	ErrMismatchedDPoPURI     = errors.New("the HTTP URI in the DPoP proof does not match the request")
+	ErrDPoPProofOutsideRange = errors.New("the DPoP proof was not issued within the acceptable time window")
+)
+
+// jwk is a JSON Web Key (RFC 7517) containing a public key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+}
+
+type dpopClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	jwt.RegisteredClaims
+}
+
+// DPoPProof contains the verified details of a DPoP proof.
+type DPoPProof struct {
+	// KeyThumbprint is the JWK SHA-256 thumbprint (RFC 7638) of the proof's public key.
+	KeyThumbprint string
+
+	ID       string
+	IssuedAt time.Time
+}
+
+// VerifyDPoPProof verifies the DPoP proof (RFC 9449) sent with the HTTP request identified by
+// the method and the URI. The caller is responsible for rejecting proofs that have been used before.
+func VerifyDPoPProof(proof, method, uri string) (DPoPProof, error) {
+	var key jwk
+
+	keyFunc := func(token *jwt.Token) (any, error) {
+		if typ, _ := token.Header["typ"].(string); typ != dpopProofType {
+			return nil, ErrInvalidDPoPProofType
+		}
+
+		header, ok := token.Header["jwk"]
+		if !ok {
+			return nil, ErrMissingDPoPKey
+		}
+
+		data, err := json.Marshal(header)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding the JWK: %w", err)
+		}
+
+		if err := json.Unmarshal(data, &key); err != nil {
+			return nil, fmt.Errorf("error decoding the JWK: %w", err)
+		}
+
+		return key.publicKey()
+	}
+
+	token, err := jwt.ParseWithClaims(
+		proof,
+		&dpopClaims{},
+		keyFunc,
+		jwt.WithValidMethods(ClientAssertionSigningMethods()),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return DPoPProof{}, fmt.Errorf("DPoP proof parsing failed: %w", err)
+	}
+
+	claims, ok := token.Claims.(*dpopClaims)
+	if !ok {
+		return DPoPProof{}, errors.New("unknown claims type")
+	}
+
+	if claims.ID == "" {
+		return DPoPProof{}, ErrMissingDPoPProofID
+	}
+
+	if claims.HTM != method {
+		return DPoPProof{}, ErrMismatchedDPoPMethod
+	}
+
+	if !sameHTTPURI(claims.HTU, uri) {
+		return DPoPProof{}, ErrMismatchedDPoPURI
+	}
+
+	if claims.IssuedAt == nil {
+		return DPoPProof{}, ErrDPoPProofOutsideRange
+	}
+
+	if age := time.Since(claims.IssuedAt.Time); age > DPoPProofWindow || age < -DPoPProofWindow {
+		return DPoPProof{}, ErrDPoPProofOutsideRange
+	}
+
+	thumbprint, err := key.thumbprint()
+	if err != nil {
+		return DPoPProof{}, err
+	}
+
+	return DPoPProof{
+		KeyThumbprint: thumbprint,
+		ID:            claims.ID,
+		IssuedAt:      claims.IssuedAt.Time,
+	}, nil
+}
+
+// sameHTTPURI compares the URIs without their query and fragment components (RFC 9449, section 4.3).
+func sameHTTPURI(proofURI, requestURI string) bool {
+	parsedProofURI, err := url.Parse(proofURI)
+	if err != nil {
+		return false
+	}
+
+	parsedRequestURI, err := url.Parse(requestURI)
+	if err != nil {
+		return false
+	}
+
+	return parsedProofURI.Scheme == parsedRequestURI.Scheme &&
+		parsedProofURI.Host == parsedRequestURI.Host &&
+		parsedProofURI.Path == parsedRequestURI.Path
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	if k.D != "" {
+		return nil, ErrPrivateDPoPKey
+	}
+
+	switch k.Kty {
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedDPoPKey
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, ErrUnsupportedDPoPKey
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, ErrUnsupportedDPoPKey
+		}
+
+		// The coordinates are encoded in the uncompressed form which also
+		// validates that the point is on the curve.
+		point := append(append([]byte{4}, x...), y...)
+
+		publicKey, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+		if err != nil {
+			return nil, ErrUnsupportedDPoPKey
+		}
+
+		return publicKey, nil
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || n.BitLen() < 2048 {
+			return nil, ErrUnsupportedDPoPKey
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, ErrUnsupportedDPoPKey
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedDPoPKey
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrUnsupportedDPoPKey
+	}
+}
+
+// thumbprint returns the JWK SHA-256 thumbprint (RFC 7638) of the key. The thumbprint
+// is calculated from the required members of the key in lexicographic order.
+func (k jwk) thumbprint() (string, error) {
+	var members string
+
+	switch k.Kty {
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, k.Crv, k.Kty, k.X, k.Y)
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, k.E, k.Kty, k.N)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, k.Crv, k.Kty, k.X)
+	default:
+		return "", ErrUnsupportedDPoPKey
+	}
+
+	hash := sha256.Sum256([]byte(members))
+
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, ErrUnsupportedDPoPKey
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyDPoPProof(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to generate the key pair: %v", t.Name(), err)
+	}
+
+	point, err := privateKey.PublicKey.Bytes()
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to encode the public key: %v", t.Name(), err)
+	}
+
+	x := base64.RawURLEncoding.EncodeToString(point[1:33])
+	y := base64.RawURLEncoding.EncodeToString(point[33:])
+	publicJWK := map[string]any{"kty": "EC", "crv": "P-256", "x": x, "y": y}
+
+	thumbprint := sha256.Sum256([]byte(`{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`))
+	wantThumbprint := base64.RawURLEncoding.EncodeToString(thumbprint[:])
+
+	endpoint := "https://auth.example.net/indieauth/token"
+
+	signProof := func(typ string, key map[string]any, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		token.Header["typ"] = typ
+		token.Header["jwk"] = key
+
+		signed, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to sign the DPoP proof: %v", t.Name(), err)
+		}
+
+		return signed
+	}
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"jti": "proof-1",
+			"htm": "POST",
+			"htu": endpoint,
+			"iat": time.Now().Unix(),
+		}
+	}
+
+	got, err := auth.VerifyDPoPProof(signProof("dpop+jwt", publicJWK, validClaims()), "POST", endpoint)
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Received an error verifying a valid DPoP proof.\ngot: %q",
+			t.Name(),
+			err.Error(),
+		)
+	}
+
+	if got.KeyThumbprint != wantThumbprint || got.ID != "proof-1" {
+		t.Errorf(
+			"FAILED test %s: Unexpected details returned from the DPoP proof.\nwant thumbprint: %s\ngot: %+v",
+			t.Name(),
+			wantThumbprint,
+			got,
+		)
+	} else {
+		t.Logf("Expected details returned from the DPoP proof.\ngot: %+v", got)
+	}
+
+	wrongMethod := validClaims()
+	wrongMethod["htm"] = "GET"
+
+	wrongURI := validClaims()
+	wrongURI["htu"] = "https://auth.example.net/indieauth/par"
+
+	expired := validClaims()
+	expired["iat"] = time.Now().Add(-10 * time.Minute).Unix()
+
+	missingID := validClaims()
+	delete(missingID, "jti")
+
+	privateJWK := map[string]any{"kty": "EC", "crv": "P-256", "x": x, "y": y, "d": "c2VjcmV0"}
+
+	testCases := []struct {
+		name  string
+		proof string
+		want  error
+	}{
+		{
+			name:  "wrong type",
+			proof: signProof("JWT", publicJWK, validClaims()),
+			want:  auth.ErrInvalidDPoPProofType,
+		},
+		{
+			name:  "private key",
+			proof: signProof("dpop+jwt", privateJWK, validClaims()),
+			want:  auth.ErrPrivateDPoPKey,
+		},
+		{
+			name:  "wrong method",
+			proof: signProof("dpop+jwt", publicJWK, wrongMethod),
+			want:  auth.ErrMismatchedDPoPMethod,
+		},
+		{
+			name:  "wrong URI",
+			proof: signProof("dpop+jwt", publicJWK, wrongURI),
+			want:  auth.ErrMismatchedDPoPURI,
+		},
+		{
+			name:  "expired",
+			proof: signProof("dpop+jwt", publicJWK, expired),
+			want:  auth.ErrDPoPProofOutsideRange,
+		},
+		{
+			name:  "missing ID",
+			proof: signProof("dpop+jwt", publicJWK, missingID),
+			want:  auth.ErrMissingDPoPProofID,
+		},
+	}
+
+	for _, tc := range testCases {
+		if _, err := auth.VerifyDPoPProof(tc.proof, "POST", endpoint); !errors.Is(err, tc.want) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error for the DPoP proof with the %s.\nwant: %v\ngot: %v",
+				t.Name(),
+				tc.name,
+				tc.want,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received for the DPoP proof with the %s.", tc.name)
+		}
+	}
+}
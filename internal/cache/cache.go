@@ -5,85 +5,552 @@
 package cache
 
 import (
-	"maps"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
 )
 
+// numShards is the number of shards that the entries are spread across. Each shard
+// has its own lock so that requests for different keys rarely wait for each other.
+const numShards = 16
+
+var ErrCacheFull = errors.New("the cache is full")
+
+// Cache is a cache of short-lived entries. The values are stored as JSON. The number
+// of entries can be bounded so that the cache cannot be flooded. When a shard is full
+// the expired entries are removed and, if none have expired, the entry closest to
+// expiring is evicted. A cache created with WithoutEviction rejects the new entry
+// instead.
 type Cache struct {
-	mu              *sync.Mutex
-	entries         map[string]Entry
+	shards          [numShards]*shard
+	seed            maphash.Seed
+	maxShardEntries int
 	cleanupInterval time.Duration
+	noEviction      bool
+
+	// store is where the entries are persisted if the cache is persistent.
+	// The changes are queued and written in batches by the persistLoop so that
+	// the requests don't wait for the database.
+	store     database.Store
+	namespace string
+	pendingMu sync.Mutex
+	pending   map[string]*database.CacheEntry
+	flush     chan struct{}
+
+	done      chan struct{}
+	stopped   sync.WaitGroup
+	closeOnce sync.Once
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+	rejected  atomic.Uint64
+}
+
+// Option is an option for a new cache.
+type Option func(*Cache)
+
+// WithoutEviction is the option for a cache whose entries must be kept until they
+// expire. When a shard of the bounded cache is full and none of its entries have
+// expired, the new entry is rejected with ErrCacheFull.
+func WithoutEviction() Option {
+	return func(c *Cache) {
+		c.noEviction = true
+	}
+}
+
+type shard struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// Stats are the counters of the cache.
+type Stats struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Evictions  uint64 `json:"evictions"`
+	Rejected   uint64 `json:"rejected"`
+	Size       int    `json:"size"`
+	MaxEntries int    `json:"max_entries"`
+}
+
+// NewCache returns a new cache that holds up to roughly the maximum number of
+// entries. The number of entries is not bounded if the maximum is not positive.
+// The expired entries are removed at every cleanup interval until the cache
+// is closed.
+func NewCache(cleanupInterval time.Duration, maxEntries int, options ...Option) *Cache {
+	cache := newCache(cleanupInterval, maxEntries, options)
+
+	cache.stopped.Go(cache.readLoop)
+
+	return cache
+}
+
+// NewPersistentCache returns a cache that saves its entries in the namespace of the
+// store so that the entries survive a restart. The entries that have not expired are
+// loaded from the store. The changes are saved in the background so the cache must be
+// closed to save the last changes before the store is closed.
+func NewPersistentCache(
+	namespace string,
+	cleanupInterval time.Duration,
+	maxEntries int,
+	store database.Store,
+	options ...Option,
+) (*Cache, error) {
+	savedEntries, err := store.GetCacheEntries(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the cache entries: %w", err)
+	}
+
+	cache := newCache(cleanupInterval, maxEntries, options)
+	cache.store = store
+	cache.namespace = namespace
+	cache.pending = make(map[string]*database.CacheEntry)
+	cache.flush = make(chan struct{}, 1)
+
+	// The entries that are expired or that don't fit in the cache are
+	// deleted from the store. The entries evicted to make room for the
+	// loaded entries are deleted in the background.
+	droppedKeys := make([]string, 0)
+
+	for _, savedEntry := range savedEntries {
+		entry := Entry{
+			expiresAt: savedEntry.ExpiresAt,
+			val:       savedEntry.Value,
+		}
+
+		if entry.Expired() || !json.Valid(entry.val) {
+			droppedKeys = append(droppedKeys, savedEntry.Key)
+
+			continue
+		}
+
+		// The loaded entries are already saved.
+		if err := cache.add(savedEntry.Key, entry, false); err != nil {
+			droppedKeys = append(droppedKeys, savedEntry.Key)
+		}
+	}
+
+	if len(droppedKeys) > 0 {
+		if err := store.DeleteCacheEntries(namespace, droppedKeys...); err != nil {
+			return nil, fmt.Errorf("error deleting the dropped cache entries: %w", err)
+		}
+	}
+
+	cache.stopped.Go(cache.readLoop)
+	cache.stopped.Go(cache.persistLoop)
+
+	return cache, nil
 }
 
-func NewCache(cleanupInterval time.Duration) *Cache {
+func newCache(cleanupInterval time.Duration, maxEntries int, options []Option) *Cache {
 	cache := Cache{
-		mu:              &sync.Mutex{},
-		entries:         make(map[string]Entry),
+		seed:            maphash.MakeSeed(),
 		cleanupInterval: cleanupInterval,
+		done:            make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(&cache)
+	}
+
+	if maxEntries > 0 {
+		cache.maxShardEntries = (maxEntries + numShards - 1) / numShards
 	}
 
-	go cache.readLoop()
+	for ind := range cache.shards {
+		cache.shards[ind] = &shard{entries: make(map[string]Entry)}
+	}
 
 	return &cache
 }
 
-func (c *Cache) Add(key string, val []byte, expiresAt time.Time) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// Add adds the JSON encoding of the value to the cache. An existing entry with the
+// same key is replaced. ErrCacheFull is returned if the cache does not evict entries
+// and there is no room for a new entry.
+func (c *Cache) Add(key string, value any, expiresAt time.Time) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding the value of the cache entry: %w", err)
+	}
+
+	return c.add(key, Entry{expiresAt: expiresAt, val: data}, true)
+}
+
+// AddIfAbsent adds the JSON encoding of the value to the cache in a single step if
+// there is no entry with the same key or if the entry has expired. The boolean value
+// is true if the value was added.
+func (c *Cache) AddIfAbsent(key string, value any, expiresAt time.Time) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("error encoding the value of the cache entry: %w", err)
+	}
+
+	s := c.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.entries[key]
+	if exists && !existing.Expired() {
+		return false, nil
+	}
+
+	if !exists && !c.makeRoom(s) {
+		return false, ErrCacheFull
+	}
+
+	entry := Entry{expiresAt: expiresAt, val: data}
+
+	s.entries[key] = entry
+	c.queueSave(key, entry)
+
+	return true, nil
+}
+
+// CompareAndSwap replaces the entry with the JSON encoding of the value in a single
+// step if the entry has not changed since the old entry was read. The boolean value
+// is true if the entry was replaced.
+func (c *Cache) CompareAndSwap(key string, old Entry, value any, expiresAt time.Time) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("error encoding the value of the cache entry: %w", err)
+	}
+
+	s := c.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.entries[key]
+	if !exists || !current.equal(old) {
+		return false, nil
+	}
+
+	entry := Entry{expiresAt: expiresAt, val: data}
+
+	s.entries[key] = entry
+	c.queueSave(key, entry)
+
+	return true, nil
+}
+
+func (c *Cache) add(key string, entry Entry, persist bool) error {
+	s := c.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[key]; !exists && !c.makeRoom(s) {
+		return ErrCacheFull
+	}
+
+	s.entries[key] = entry
+
+	if persist {
+		c.queueSave(key, entry)
+	}
+
+	return nil
+}
+
+// makeRoom returns true if there is room in the shard for a new entry. The expired
+// entries are removed from a full shard to make room. If none have expired then the
+// entry closest to expiring is evicted unless the cache does not evict entries.
+// The shard must be locked.
+func (c *Cache) makeRoom(s *shard) bool {
+	if c.maxShardEntries <= 0 || len(s.entries) < c.maxShardEntries {
+		return true
+	}
+
+	if expiredKeys := s.removeExpired(); len(expiredKeys) > 0 {
+		c.queueDelete(expiredKeys...)
+
+		return true
+	}
+
+	if c.noEviction {
+		c.rejected.Add(1)
+
+		return false
+	}
 
-	entry := Entry{
-		expiresAt: expiresAt,
-		val:       val,
+	var (
+		evictKey  string
+		evictTime time.Time
+	)
+
+	for key, entry := range s.entries {
+		if evictKey == "" || entry.expiresAt.Before(evictTime) {
+			evictKey, evictTime = key, entry.expiresAt
+		}
 	}
 
-	c.entries[key] = entry
+	delete(s.entries, evictKey)
+	c.queueDelete(evictKey)
+	c.evictions.Add(1)
+
+	return true
 }
 
 func (c *Cache) Delete(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	s := c.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
 
-	delete(c.entries, key)
+	c.queueDelete(key)
 }
 
 func (c *Cache) Get(key string) (Entry, bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	s := c.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, exist := s.entries[key]
 
-	value, exist := c.entries[key]
+	c.count(exist)
 
 	return value, exist
 }
 
+// GetAndDelete returns the entry and deletes it from the cache in a single step
+// so that only one caller receives the entry.
+func (c *Cache) GetAndDelete(key string) (Entry, bool) {
+	s := c.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, exist := s.entries[key]
+	if exist {
+		delete(s.entries, key)
+		c.queueDelete(key)
+	}
+
+	c.count(exist)
+
+	return value, exist
+}
+
+// Stats returns the counters of the cache. The maximum number of entries is zero
+// if the cache is not bounded.
+func (c *Cache) Stats() Stats {
+	stats := Stats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Evictions:  c.evictions.Load(),
+		Rejected:   c.rejected.Load(),
+		MaxEntries: c.maxShardEntries * numShards,
+	}
+
+	for _, s := range c.shards {
+		s.mu.Lock()
+		stats.Size += len(s.entries)
+		s.mu.Unlock()
+	}
+
+	return stats
+}
+
+// Close stops the removal of the expired entries and, if the cache is persistent,
+// saves the remaining changes to the store. The cache can still be used after it
+// is closed but the changes are no longer saved.
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+
+	c.stopped.Wait()
+}
+
+func (c *Cache) shard(key string) *shard {
+	return c.shards[maphash.String(c.seed, key)%numShards]
+}
+
+func (c *Cache) count(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
 func (c *Cache) readLoop() {
-	ticker := time.Tick(c.cleanupInterval)
+	ticker := time.NewTicker(c.cleanupInterval)
+	defer ticker.Stop()
 
-	for range ticker {
-		c.cleanupEntries()
+	for {
+		select {
+		case <-ticker.C:
+			c.cleanupEntries()
+		case <-c.done:
+			return
+		}
 	}
 }
 
 func (c *Cache) cleanupEntries() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	for _, s := range c.shards {
+		s.mu.Lock()
+
+		if expiredKeys := s.removeExpired(); len(expiredKeys) > 0 {
+			c.queueDelete(expiredKeys...)
+		}
+
+		s.mu.Unlock()
+	}
+}
+
+// removeExpired removes the expired entries from the shard and returns their keys.
+// The shard must be locked.
+func (s *shard) removeExpired() []string {
+	expiredKeys := make([]string, 0)
+
+	for key, entry := range s.entries {
+		if entry.Expired() {
+			delete(s.entries, key)
+			expiredKeys = append(expiredKeys, key)
+		}
+	}
+
+	return expiredKeys
+}
+
+// queueSave queues the entry to be saved to the store if the cache is persistent.
+func (c *Cache) queueSave(key string, entry Entry) {
+	if c.store == nil {
+		return
+	}
+
+	c.queue(key, &database.CacheEntry{
+		Namespace: c.namespace,
+		Key:       key,
+		Value:     entry.val,
+		ExpiresAt: entry.expiresAt,
+	})
+}
+
+// queueDelete queues the entries to be deleted from the store if the cache
+// is persistent.
+func (c *Cache) queueDelete(keys ...string) {
+	if c.store == nil {
+		return
+	}
+
+	for _, key := range keys {
+		c.queue(key, nil)
+	}
+}
+
+// queue queues the change of the entry. Only the last change of each key is kept
+// so the order of the changes within a batch does not matter. A nil entry is
+// deleted from the store.
+func (c *Cache) queue(key string, entry *database.CacheEntry) {
+	c.pendingMu.Lock()
+	c.pending[key] = entry
+	c.pendingMu.Unlock()
+
+	select {
+	case c.flush <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Cache) persistLoop() {
+	for {
+		select {
+		case <-c.flush:
+			c.persist()
+		case <-c.done:
+			c.persist()
+
+			return
+		}
+	}
+}
+
+// persist writes the queued changes to the store. A failure is logged rather than
+// returned so that the cache keeps working in memory.
+func (c *Cache) persist() {
+	c.pendingMu.Lock()
+	pending := c.pending
+	c.pending = make(map[string]*database.CacheEntry)
+	c.pendingMu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	var (
+		saved   = make([]database.CacheEntry, 0, len(pending))
+		deleted = make([]string, 0)
+	)
+
+	for key, entry := range pending {
+		if entry == nil {
+			deleted = append(deleted, key)
+		} else {
+			saved = append(saved, *entry)
+		}
+	}
+
+	if len(saved) > 0 {
+		if err := c.store.SaveCacheEntries(saved...); err != nil {
+			logPersistenceError(err)
+		}
+	}
 
-	for key := range maps.All(c.entries) {
-		if c.entries[key].Expired() {
-			delete(c.entries, key)
+	if len(deleted) > 0 {
+		if err := c.store.DeleteCacheEntries(c.namespace, deleted...); err != nil {
+			logPersistenceError(err)
 		}
 	}
 }
 
+func logPersistenceError(err error) {
+	slog.LogAttrs(
+		context.Background(),
+		slog.LevelError,
+		"Unable to persist the changes to the cache.",
+		slog.Any("error", err),
+	)
+}
+
 type Entry struct {
 	expiresAt time.Time
-	val       []byte
+	val       json.RawMessage
+}
+
+// Decode decodes the JSON value of the entry into the value pointed to by v.
+func (e Entry) Decode(v any) error {
+	if err := json.Unmarshal(e.val, v); err != nil {
+		return fmt.Errorf("error decoding the value of the cache entry: %w", err)
+	}
+
+	return nil
 }
 
-func (e Entry) Value() []byte {
-	return e.val
+func (e Entry) ExpiresAt() time.Time {
+	return e.expiresAt
 }
 
 func (e Entry) Expired() bool {
 	return time.Now().After(e.expiresAt)
 }
+
+func (e Entry) equal(other Entry) bool {
+	return e.expiresAt.Equal(other.expiresAt) && bytes.Equal(e.val, other.val)
+}
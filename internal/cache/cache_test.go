@@ -5,13 +5,15 @@
 package cache_test
 
 import (
-	"bytes"
+	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/cache"
-	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
 )
 
 type TestData struct {
@@ -23,10 +25,11 @@ type TestData struct {
 func TestCache(t *testing.T) {
 	cleanupInterval := 1 * time.Second
 
-	testCache := cache.NewCache(cleanupInterval)
+	testCache := cache.NewCache(cleanupInterval, 0)
 
 	t.Run("Test Add Entry", testAddEntry(testCache))
 	t.Run("Test Delete Entry", testDeleteEntry(testCache))
+	t.Run("Test Get And Delete Entry", testGetAndDeleteEntry(testCache))
 	t.Run("Test Expired Entry", testExpiredEntry(testCache))
 	t.Run("Test Cache Cleanup", testCleanup(testCache))
 }
@@ -41,19 +44,12 @@ func testAddEntry(testCache *cache.Cache) func(t *testing.T) {
 			BoolVal: true,
 		}
 
-		dataBytes, err := utilities.GobEncode(data)
-		if err != nil {
-			t.Fatalf(
-				"FAILED test %s: Unable to encode the test data: %v",
-				t.Name(),
-				err,
-			)
-		}
-
 		key := t.Name()
 		expiresAt := time.Now().Add(1 * time.Minute)
 
-		testCache.Add(key, dataBytes, expiresAt)
+		if err := testCache.Add(key, data, expiresAt); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the test data to the cache: %v", t.Name(), err)
+		}
 
 		t.Log("Added the test data to the cache.")
 
@@ -70,7 +66,7 @@ func testAddEntry(testCache *cache.Cache) func(t *testing.T) {
 
 		var got TestData
 
-		if err := utilities.GobDecode(bytes.NewBuffer(entry.Value()), &got); err != nil {
+		if err := entry.Decode(&got); err != nil {
 			t.Fatalf(
 				"FAILED test %s: Unable to decode the data from the cache: %v",
 				t.Name(),
@@ -113,19 +109,12 @@ func testDeleteEntry(testCache *cache.Cache) func(t *testing.T) {
 			BoolVal: false,
 		}
 
-		dataBytes, err := utilities.GobEncode(data)
-		if err != nil {
-			t.Fatalf(
-				"FAILED test %s: Unable to encode the test data: %v",
-				t.Name(),
-				err,
-			)
-		}
-
 		key := t.Name()
 		expiresAt := time.Now().Add(1 * time.Minute)
 
-		testCache.Add(key, dataBytes, expiresAt)
+		if err := testCache.Add(key, data, expiresAt); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the test data to the cache: %v", t.Name(), err)
+		}
 
 		t.Log("Added the test data to the cache.")
 
@@ -147,6 +136,52 @@ func testDeleteEntry(testCache *cache.Cache) func(t *testing.T) {
 	}
 }
 
+func testGetAndDeleteEntry(testCache *cache.Cache) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		key := t.Name()
+
+		if err := testCache.Add(key, "authorization code", time.Now().Add(1*time.Minute)); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the test data to the cache: %v", t.Name(), err)
+		}
+
+		t.Log("Added the test data to the cache.")
+
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			received int
+		)
+
+		for range 20 {
+			wg.Go(func() {
+				if _, exists := testCache.GetAndDelete(key); exists {
+					mu.Lock()
+					received++
+					mu.Unlock()
+				}
+			})
+		}
+
+		wg.Wait()
+
+		if received != 1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of callers received the entry.\nwant: 1\ngot: %d",
+				t.Name(),
+				received,
+			)
+		} else {
+			t.Log("Only one caller received the entry.")
+		}
+
+		if _, exists := testCache.Get(key); exists {
+			t.Errorf("FAILED test %s: The key %q was found after getting and deleting it.", t.Name(), key)
+		}
+	}
+}
+
 func testExpiredEntry(testCache *cache.Cache) func(t *testing.T) {
 	return func(t *testing.T) {
 		t.Parallel()
@@ -157,19 +192,12 @@ func testExpiredEntry(testCache *cache.Cache) func(t *testing.T) {
 			BoolVal: true,
 		}
 
-		dataBytes, err := utilities.GobEncode(data)
-		if err != nil {
-			t.Fatalf(
-				"FAILED test %s: Unable to encode the test data: %v",
-				t.Name(),
-				err,
-			)
-		}
-
 		key := t.Name()
 		expiresAt := time.Now().Add(1 * time.Millisecond)
 
-		testCache.Add(key, dataBytes, expiresAt)
+		if err := testCache.Add(key, data, expiresAt); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the test data to the cache: %v", t.Name(), err)
+		}
 
 		t.Log("Added the test data to the cache.")
 
@@ -207,19 +235,12 @@ func testCleanup(testCache *cache.Cache) func(t *testing.T) {
 			BoolVal: false,
 		}
 
-		dataBytes, err := utilities.GobEncode(data)
-		if err != nil {
-			t.Fatalf(
-				"FAILED test %s: Unable to encode the test data: %v",
-				t.Name(),
-				err,
-			)
-		}
-
 		key := t.Name()
 		expiresAt := time.Now().Add(1 * time.Millisecond)
 
-		testCache.Add(key, dataBytes, expiresAt)
+		if err := testCache.Add(key, data, expiresAt); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the test data to the cache: %v", t.Name(), err)
+		}
 
 		t.Log("Added the test data to the cache.")
 		t.Log("Waiting for the next cache clean-up")
@@ -240,3 +261,430 @@ func testCleanup(testCache *cache.Cache) func(t *testing.T) {
 		}
 	}
 }
+
+func TestPersistentCache(t *testing.T) {
+	t.Parallel()
+
+	store := database.NewMemoryStore()
+
+	testCache, err := cache.NewPersistentCache("", time.Minute, 100, store)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to create the cache: %v", t.Name(), err)
+	}
+
+	entries := []struct {
+		key       string
+		value     TestData
+		expiresAt time.Time
+	}{
+		{key: "pending", value: TestData{Name: "authorization request"}, expiresAt: time.Now().Add(10 * time.Minute)},
+		{key: "expired", value: TestData{Name: "authorization code"}, expiresAt: time.Now().Add(-1 * time.Second)},
+		{key: "redeemed", value: TestData{Name: "authorization code"}, expiresAt: time.Now().Add(1 * time.Minute)},
+	}
+
+	for _, entry := range entries {
+		if err := testCache.Add(entry.key, entry.value, entry.expiresAt); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the entry %q to the cache: %v", t.Name(), entry.key, err)
+		}
+	}
+
+	testCache.Delete("redeemed")
+
+	// The changes are saved when the cache is closed.
+	testCache.Close()
+
+	t.Log("Creating a new cache from the same store as if the server restarted.")
+
+	restartedCache, err := cache.NewPersistentCache("", time.Minute, 100, store)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to create the cache after the restart: %v", t.Name(), err)
+	}
+	defer restartedCache.Close()
+
+	var got TestData
+
+	entry, exists := restartedCache.Get("pending")
+	if !exists {
+		t.Errorf("FAILED test %s: The pending entry was not restored after the restart.", t.Name())
+	} else if err := entry.Decode(&got); err != nil || got != entries[0].value {
+		t.Errorf(
+			"FAILED test %s: Unexpected value of the restored entry.\nwant: %+v\ngot: %+v\nerror: %v",
+			t.Name(),
+			entries[0].value,
+			got,
+			err,
+		)
+	} else {
+		t.Logf("The pending entry was restored after the restart.\ngot: %+v", got)
+	}
+
+	for _, key := range []string{"expired", "redeemed"} {
+		if _, exists := restartedCache.Get(key); exists {
+			t.Errorf("FAILED test %s: The entry %q was restored after the restart.", t.Name(), key)
+		}
+	}
+
+	savedEntries, err := store.GetCacheEntries("")
+	if err != nil || len(savedEntries) != 1 {
+		t.Errorf(
+			"FAILED test %s: Unexpected entries saved in the store.\ngot: %+v\nerror: %v",
+			t.Name(),
+			savedEntries,
+			err,
+		)
+	} else {
+		t.Log("Only the pending entry is saved in the store.")
+	}
+}
+
+func TestPersistentCacheLoadsWithinLimit(t *testing.T) {
+	t.Parallel()
+
+	store := database.NewMemoryStore()
+
+	testCache, err := cache.NewPersistentCache("", time.Minute, 0, store)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to create the cache: %v", t.Name(), err)
+	}
+
+	for ind := range 100 {
+		if err := testCache.Add(fmt.Sprintf("entry-%d", ind), ind, time.Now().Add(10*time.Minute)); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the entry to the cache: %v", t.Name(), err)
+		}
+	}
+
+	testCache.Close()
+
+	t.Log("Restarting with a smaller limit so that some of the saved entries are not loaded.")
+
+	restartedCache, err := cache.NewPersistentCache("", time.Minute, 16, store)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to create the cache after the restart: %v", t.Name(), err)
+	}
+
+	size := restartedCache.Stats().Size
+
+	restartedCache.Close()
+
+	savedEntries, err := store.GetCacheEntries("")
+	if err != nil || len(savedEntries) != size {
+		t.Errorf(
+			"FAILED test %s: The entries that were not loaded are still saved in the store.\nwant: %d entries\ngot: %d entries\nerror: %v",
+			t.Name(),
+			size,
+			len(savedEntries),
+			err,
+		)
+	} else {
+		t.Logf("The store holds the %d entries that were loaded.", size)
+	}
+}
+
+func TestCacheLimit(t *testing.T) {
+	t.Parallel()
+
+	maxEntries := 32
+
+	testCache := cache.NewCache(time.Minute, maxEntries)
+	defer testCache.Close()
+
+	// The entry closest to expiring is evicted first.
+	if err := testCache.Add("expiring soon", "value", time.Now().Add(1*time.Second)); err != nil {
+		t.Fatalf("FAILED test %s: Unable to add the entry to the cache: %v", t.Name(), err)
+	}
+
+	for ind := range 1000 {
+		if err := testCache.Add(fmt.Sprintf("entry-%d", ind), "value", time.Now().Add(10*time.Minute)); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the entry to the full cache: %v", t.Name(), err)
+		}
+	}
+
+	stats := testCache.Stats()
+
+	if stats.Size > stats.MaxEntries || stats.MaxEntries < maxEntries {
+		t.Errorf(
+			"FAILED test %s: The cache grew beyond the maximum number of entries.\nmaximum: %d\ngot: %d",
+			t.Name(),
+			stats.MaxEntries,
+			stats.Size,
+		)
+	} else {
+		t.Logf("The cache holds %d of a maximum of %d entries.", stats.Size, stats.MaxEntries)
+	}
+
+	if want := 1001 - stats.Size; stats.Evictions != uint64(want) || stats.Rejected != 0 {
+		t.Errorf(
+			"FAILED test %s: Unexpected number of evicted entries.\nwant: %d evicted, 0 rejected\ngot: %d evicted, %d rejected",
+			t.Name(),
+			want,
+			stats.Evictions,
+			stats.Rejected,
+		)
+	} else {
+		t.Logf("%d entries were evicted from the full cache.", stats.Evictions)
+	}
+
+	if _, exists := testCache.Get("expiring soon"); exists {
+		t.Errorf("FAILED test %s: The entry closest to expiring was not evicted from the full cache.", t.Name())
+	} else {
+		t.Log("The entry closest to expiring was evicted from the full cache.")
+	}
+
+	if _, exists := testCache.Get("entry-999"); !exists {
+		t.Errorf("FAILED test %s: The newest entry is missing from the full cache.", t.Name())
+	}
+}
+
+func TestCacheLimitWithoutEviction(t *testing.T) {
+	t.Parallel()
+
+	maxEntries := 32
+
+	testCache := cache.NewCache(time.Minute, maxEntries, cache.WithoutEviction())
+	defer testCache.Close()
+
+	// The entries already in the cache are never evicted to make room.
+	if err := testCache.Add("authorization code", "value", time.Now().Add(1*time.Second)); err != nil {
+		t.Fatalf("FAILED test %s: Unable to add the entry to the cache: %v", t.Name(), err)
+	}
+
+	rejected := 0
+
+	for ind := range 1000 {
+		added, err := testCache.AddIfAbsent(fmt.Sprintf("entry-%d", ind), "value", time.Now().Add(10*time.Minute))
+
+		switch {
+		case errors.Is(err, cache.ErrCacheFull):
+			rejected++
+		case err != nil:
+			t.Fatalf("FAILED test %s: Unexpected error adding the entry to the cache: %v", t.Name(), err)
+		case !added:
+			t.Fatalf("FAILED test %s: The new entry was not added to the cache.", t.Name())
+		}
+	}
+
+	stats := testCache.Stats()
+
+	if stats.Size > stats.MaxEntries || stats.MaxEntries < maxEntries {
+		t.Errorf(
+			"FAILED test %s: The cache grew beyond the maximum number of entries.\nmaximum: %d\ngot: %d",
+			t.Name(),
+			stats.MaxEntries,
+			stats.Size,
+		)
+	}
+
+	if want := 1001 - stats.Size; rejected != want || stats.Rejected != uint64(want) || stats.Evictions != 0 {
+		t.Errorf(
+			"FAILED test %s: Unexpected number of rejected entries.\nwant: %d\ngot: %d (counted: %d, evicted: %d)",
+			t.Name(),
+			want,
+			rejected,
+			stats.Rejected,
+			stats.Evictions,
+		)
+	} else {
+		t.Logf("%d entries were rejected by the full cache.", rejected)
+	}
+
+	if _, exists := testCache.Get("authorization code"); !exists {
+		t.Errorf("FAILED test %s: An existing entry was evicted from the full cache.", t.Name())
+	} else {
+		t.Log("The existing entry was kept when the cache was full.")
+	}
+
+	// An existing entry can still be updated when its shard is full.
+	if err := testCache.Add("authorization code", "updated", time.Now().Add(1*time.Second)); err != nil {
+		t.Errorf("FAILED test %s: Unable to update an entry in the full cache: %v", t.Name(), err)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewCache(time.Minute, 0)
+	defer testCache.Close()
+
+	testCache.Add("present", "value", time.Now().Add(1*time.Minute))
+	testCache.Add("deleted", "value", time.Now().Add(1*time.Minute))
+
+	testCache.Get("present")
+	testCache.Get("missing")
+	testCache.GetAndDelete("deleted")
+	testCache.GetAndDelete("deleted")
+
+	want := cache.Stats{
+		Hits:       2,
+		Misses:     2,
+		Evictions:  0,
+		Rejected:   0,
+		Size:       1,
+		MaxEntries: 0,
+	}
+
+	if got := testCache.Stats(); got != want {
+		t.Errorf(
+			"FAILED test %s: Unexpected cache statistics.\nwant: %+v\ngot: %+v",
+			t.Name(),
+			want,
+			got,
+		)
+	} else {
+		t.Logf("Expected cache statistics received.\ngot: %+v", got)
+	}
+}
+
+func TestCacheClose(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewCache(10*time.Millisecond, 0)
+
+	done := make(chan struct{})
+
+	go func() {
+		testCache.Close()
+		testCache.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Log("The cache was closed.")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("FAILED test %s: Timed out waiting for the cache to close.", t.Name())
+	}
+
+	// The entries are not removed after the cache is closed.
+	testCache.Add("expired", "value", time.Now().Add(-1*time.Second))
+	time.Sleep(50 * time.Millisecond)
+
+	if _, exists := testCache.Get("expired"); !exists {
+		t.Errorf("FAILED test %s: The expired entry was removed after the cache was closed.", t.Name())
+	} else {
+		t.Log("The cache stopped removing the expired entries after it was closed.")
+	}
+}
+
+func TestCacheAddIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewCache(time.Minute, 0)
+	defer testCache.Close()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		added int
+	)
+
+	for range 20 {
+		wg.Go(func() {
+			ok, err := testCache.AddIfAbsent("proof-id", nil, time.Now().Add(1*time.Minute))
+			if err != nil {
+				t.Errorf("FAILED test %s: Unable to add the entry to the cache: %v", t.Name(), err)
+
+				return
+			}
+
+			if ok {
+				mu.Lock()
+				added++
+				mu.Unlock()
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if added != 1 {
+		t.Errorf(
+			"FAILED test %s: Unexpected number of callers added the entry.\nwant: 1\ngot: %d",
+			t.Name(),
+			added,
+		)
+	} else {
+		t.Log("Only one caller added the entry.")
+	}
+
+	// An expired entry is replaced.
+	if err := testCache.Add("expired", nil, time.Now().Add(-1*time.Second)); err != nil {
+		t.Fatalf("FAILED test %s: Unable to add the expired entry to the cache: %v", t.Name(), err)
+	}
+
+	if ok, err := testCache.AddIfAbsent("expired", nil, time.Now().Add(1*time.Minute)); err != nil || !ok {
+		t.Errorf("FAILED test %s: The expired entry was not replaced.\nerror: %v", t.Name(), err)
+	} else {
+		t.Log("The expired entry was replaced.")
+	}
+}
+
+func TestCacheCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	testCache := cache.NewCache(time.Minute, 0)
+	defer testCache.Close()
+
+	expiresAt := time.Now().Add(1 * time.Minute)
+
+	if err := testCache.Add("counter", 0, expiresAt); err != nil {
+		t.Fatalf("FAILED test %s: Unable to add the entry to the cache: %v", t.Name(), err)
+	}
+
+	stale, _ := testCache.Get("counter")
+
+	// Every increment is kept when the callers retry after a failed swap.
+	var wg sync.WaitGroup
+
+	for range 20 {
+		wg.Go(func() {
+			for {
+				entry, _ := testCache.Get("counter")
+
+				var counter int
+
+				if err := entry.Decode(&counter); err != nil {
+					t.Errorf("FAILED test %s: Unable to decode the entry: %v", t.Name(), err)
+
+					return
+				}
+
+				swapped, err := testCache.CompareAndSwap("counter", entry, counter+1, expiresAt)
+				if err != nil {
+					t.Errorf("FAILED test %s: Unable to swap the entry: %v", t.Name(), err)
+
+					return
+				}
+
+				if swapped {
+					return
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	entry, _ := testCache.Get("counter")
+
+	var counter int
+
+	if err := entry.Decode(&counter); err != nil {
+		t.Fatalf("FAILED test %s: Unable to decode the entry: %v", t.Name(), err)
+	}
+
+	if counter != 20 {
+		t.Errorf(
+			"FAILED test %s: Unexpected value after the concurrent swaps.\nwant: 20\ngot: %d",
+			t.Name(),
+			counter,
+		)
+	} else {
+		t.Logf("Expected value after the concurrent swaps.\ngot: %d", counter)
+	}
+
+	if swapped, err := testCache.CompareAndSwap("counter", stale, 100, expiresAt); err != nil || swapped {
+		t.Errorf("FAILED test %s: The entry was swapped with a stale entry.\nerror: %v", t.Name(), err)
+	} else {
+		t.Log("The entry was not swapped with a stale entry.")
+	}
+}
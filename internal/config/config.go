@@ -8,34 +8,79 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/policy"
 )
 
 const (
 	defaultCookieName              = "beacon_is_great"
 	defaultGracefulShutdownTimeout = 30
+	defaultUnknownScopePolicy      = "drop"
+	defaultMetadataMaxAge          = 3600
+	defaultMetadataStaleGrace      = 3600
+	defaultLogoMaxSize             = 256 << 10 // 256KB
+	defaultTokenLifetime           = 7 * 24 * 3600
+	defaultOutboundConnectTimeout  = 5
+	defaultOutboundTimeout         = 10
+	defaultOutboundMaxResponseSize = 1 << 20 // 1MB
+	defaultBackupRetention         = 7
+	defaultDatabaseDriver          = "bolt"
+	defaultCacheMaxEntries         = 100000
 )
 
 var (
 	ErrMissingDatabasePath = errors.New("please set the database path")
 	ErrMissingJWTSecret    = errors.New("the JWT Secret is empty")
 	ErrInvalidCookieName   = errors.New("the configured cookie name is invalid")
+	ErrInvalidScopePolicy  = errors.New("the unknown scope policy must be either 'drop' or 'reject'")
+	ErrMissingScopeName    = errors.New("a scope definition is missing its name")
+	ErrInvalidScopeRisk    = errors.New("the risk level of a scope must be 'low', 'medium' or 'high'")
+	ErrInvalidDenyRange    = errors.New("the outbound deny range is not a valid CIDR")
+	ErrInvalidLocalhostID  = errors.New("the localhost client ID must be an http(s) URL on localhost, 127.0.0.1 or [::1]")
+	ErrInvalidHostPattern  = errors.New("the client host pattern is invalid")
+	ErrMissingRuleClientID = errors.New("a client policy rule is missing its client ID")
+	ErrInvalidCookieDomain = errors.New("the cookie domain must be the domain or one of its parent domains")
+	ErrMissingBackupDir    = errors.New("please set the backup directory for the scheduled backups")
+	ErrInvalidDBDriver     = errors.New("the database driver must be 'bolt', 'sqlite' or 'memory'")
+	ErrBackupRequiresBolt  = errors.New("the scheduled backups are only supported by the bolt database driver")
 )
 
 type Config struct {
-	BindAddress             string   `json:"bindAddress"`
-	Port                    int32    `json:"port"`
-	Domain                  string   `json:"domain"`
-	GracefulShutdownTimeout int      `json:"gracefulShutdownTimeout"`
-	Database                Database `json:"database"`
-	JWT                     JWT      `json:"jwt"`
-	Log                     Log      `json:"log"`
+	BindAddress             string              `json:"bindAddress"`
+	Port                    int32               `json:"port"`
+	Domain                  string              `json:"domain"`
+	GracefulShutdownTimeout int                 `json:"gracefulShutdownTimeout"`
+	Database                Database            `json:"database"`
+	JWT                     JWT                 `json:"jwt"`
+	Log                     Log                 `json:"log"`
+	Scopes                  Scopes              `json:"scopes"`
+	ClientMetadataCache     ClientMetadataCache `json:"clientMetadataCache"`
+	Outbound                Outbound            `json:"outbound"`
+	Development             Development         `json:"development"`
+	ClientPolicy            ClientPolicy        `json:"clientPolicy"`
+	Authorization           Authorization       `json:"authorization"`
+	ForwardAuth             ForwardAuth         `json:"forwardAuth"`
+	Compatibility           Compatibility       `json:"compatibility"`
+	ProfileSync             ProfileSync         `json:"profileSync"`
+	Admin                   Admin               `json:"admin"`
+	Backup                  Backup              `json:"backup"`
+	Encryption              Encryption          `json:"encryption"`
+	Cache                   Cache               `json:"cache"`
 }
 
+// Database is the configuration for the database. The driver is either 'bolt'
+// (the default), 'sqlite' or 'memory'. The memory driver does not use the path
+// and loses everything when Beacon stops, so it should only be used for testing.
 type Database struct {
-	Path string `json:"path"`
+	Driver string `json:"driver"`
+	Path   string `json:"path"`
 }
 
 type JWT struct {
@@ -47,6 +92,131 @@ type Log struct {
 	Level string `json:"level"`
 }
 
+// ClientMetadataCache is the configuration for the client metadata cache.
+// The durations are in seconds. The client logos are cached for the default
+// max age and the maximum size of each logo is in bytes.
+type ClientMetadataCache struct {
+	DefaultMaxAge    int   `json:"defaultMaxAge"`
+	StaleGracePeriod int   `json:"staleGracePeriod"`
+	LogoMaxSize      int64 `json:"logoMaxSize"`
+}
+
+// Outbound is the configuration for the HTTP client used for all outbound requests
+// such as fetching the client's metadata. The timeouts are in seconds and the maximum
+// response size is in bytes. The default deny ranges are used if none are set.
+type Outbound struct {
+	DenyRanges      []string `json:"denyRanges"`
+	ConnectTimeout  int      `json:"connectTimeout"`
+	Timeout         int      `json:"timeout"`
+	MaxResponseSize int64    `json:"maxResponseSize"`
+}
+
+// Development is the configuration for developing IndieAuth clients locally.
+// The listed localhost client IDs are allowed to resolve to the loopback addresses.
+type Development struct {
+	LocalhostClientIDs []string `json:"localhostClientIDs"`
+}
+
+// Authorization is the configuration for the authorization endpoint.
+// If RequirePAR is true then clients must use pushed authorization requests.
+type Authorization struct {
+	RequirePAR bool `json:"requirePAR"`
+}
+
+// ForwardAuth is the configuration for the forward authentication endpoint used by
+// reverse proxies. Users are only redirected back to the hosts matching the allowed
+// host patterns after signing in. The session cookie is set for the cookie domain so
+// that the proxied applications on its subdomains receive it. The cookie domain
+// defaults to the domain.
+type ForwardAuth struct {
+	AllowedHosts []string `json:"allowedHosts"`
+	CookieDomain string   `json:"cookieDomain"`
+}
+
+// Compatibility is the configuration for the older IndieAuth behaviours that
+// existing software may still depend on. If LegacyTokenVerification is true then
+// resource servers such as Micropub servers can verify access tokens with a GET
+// request to the token endpoint.
+type Compatibility struct {
+	LegacyTokenVerification bool `json:"legacyTokenVerification"`
+}
+
+// ProfileSync is the configuration for keeping the profile information up to date
+// with the representative h-card on each profile's website. The interval is in
+// seconds and the profiles are not synced if it is not set.
+type ProfileSync struct {
+	Interval int `json:"interval"`
+}
+
+// Admin is the configuration for the admin socket. Commands such as
+// `beacon db backup` use the Unix socket to manage the running server.
+// The admin socket is disabled if the socket path is not set.
+type Admin struct {
+	SocketPath string `json:"socketPath"`
+}
+
+// Backup is the configuration for the scheduled database backups. A backup is
+// written to the directory at every interval and only the most recent backups
+// up to the retention count are kept. The interval is in seconds and the
+// database is not backed up on a schedule if it is not set.
+type Backup struct {
+	Directory string `json:"directory"`
+	Interval  int    `json:"interval"`
+	Retention int    `json:"retention"`
+}
+
+// Encryption is the configuration for encrypting the sensitive records in the
+// database. The key file holds the base64 encoded keys separated by new lines
+// and the first key is the primary key. The keys can be set in the
+// BEACON_ENCRYPTION_KEY environment variable instead of the key file. The
+// records are not encrypted if neither is set.
+type Encryption struct {
+	KeyFile string `json:"keyFile"`
+}
+
+// Cache is the configuration for the server's cache of the pending authorization
+// requests and the other short-lived entries. If
+// Persistent is true then the entries are also saved in the database so that
+// the flows in progress are not lost when Beacon restarts. MaxEntries is the
+// maximum number of entries in the cache; the entries closest to expiring are
+// evicted when the cache is full. The authorization codes and the records of the
+// used DPoP proofs and client assertions are kept in a separate cache of the
+// same size; the requests that need a new entry are refused while it is full.
+type Cache struct {
+	Persistent bool `json:"persistent"`
+	MaxEntries int  `json:"maxEntries"`
+}
+
+// ClientPolicy is the configuration for the policy that controls which clients
+// can use Beacon. The allow and deny lists contain patterns that match the host
+// of the client ID (e.g. "app.example.org" or "*.example.org"). The token
+// lifetimes are in seconds.
+type ClientPolicy struct {
+	Allow                []string     `json:"allow"`
+	Deny                 []string     `json:"deny"`
+	DefaultTokenLifetime int          `json:"defaultTokenLifetime"`
+	Clients              []ClientRule `json:"clients"`
+}
+
+type ClientRule struct {
+	ClientID      string   `json:"clientID"`
+	MaxScopes     []string `json:"maxScopes"`
+	TokenLifetime int      `json:"tokenLifetime"`
+	Trusted       bool     `json:"trusted"`
+}
+
+type Scopes struct {
+	UnknownScopePolicy string            `json:"unknownScopePolicy"`
+	Definitions        []ScopeDefinition `json:"definitions"`
+}
+
+type ScopeDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Risk        string `json:"risk"`
+	Allowed     bool   `json:"allowed"`
+}
+
 func NewConfig(path string) (Config, error) {
 	path = filepath.Clean(path)
 
@@ -68,7 +238,15 @@ func NewConfig(path string) (Config, error) {
 		)
 	}
 
-	if cfg.Database.Path == "" {
+	if cfg.Database.Driver == "" {
+		cfg.Database.Driver = defaultDatabaseDriver
+	}
+
+	if !slices.Contains([]string{"bolt", "sqlite", "memory"}, cfg.Database.Driver) {
+		return Config{}, fmt.Errorf("%w: %q", ErrInvalidDBDriver, cfg.Database.Driver)
+	}
+
+	if cfg.Database.Path == "" && cfg.Database.Driver != "memory" {
 		return Config{}, ErrMissingDatabasePath
 	}
 
@@ -88,6 +266,82 @@ func NewConfig(path string) (Config, error) {
 		cfg.GracefulShutdownTimeout = defaultGracefulShutdownTimeout
 	}
 
+	if cfg.Scopes.UnknownScopePolicy == "" {
+		cfg.Scopes.UnknownScopePolicy = defaultUnknownScopePolicy
+	}
+
+	if err := validateScopes(cfg.Scopes); err != nil {
+		return Config{}, fmt.Errorf("error validating the scopes: %w", err)
+	}
+
+	if cfg.ClientMetadataCache.DefaultMaxAge <= 0 {
+		cfg.ClientMetadataCache.DefaultMaxAge = defaultMetadataMaxAge
+	}
+
+	if cfg.ClientMetadataCache.StaleGracePeriod <= 0 {
+		cfg.ClientMetadataCache.StaleGracePeriod = defaultMetadataStaleGrace
+	}
+
+	if cfg.ClientMetadataCache.LogoMaxSize <= 0 {
+		cfg.ClientMetadataCache.LogoMaxSize = defaultLogoMaxSize
+	}
+
+	if cfg.Outbound.ConnectTimeout <= 0 {
+		cfg.Outbound.ConnectTimeout = defaultOutboundConnectTimeout
+	}
+
+	if cfg.Outbound.Timeout <= 0 {
+		cfg.Outbound.Timeout = defaultOutboundTimeout
+	}
+
+	if cfg.Outbound.MaxResponseSize <= 0 {
+		cfg.Outbound.MaxResponseSize = defaultOutboundMaxResponseSize
+	}
+
+	for _, denyRange := range cfg.Outbound.DenyRanges {
+		if _, err := netip.ParsePrefix(denyRange); err != nil {
+			return Config{}, fmt.Errorf("%w: %q", ErrInvalidDenyRange, denyRange)
+		}
+	}
+
+	if cfg.ClientPolicy.DefaultTokenLifetime <= 0 {
+		cfg.ClientPolicy.DefaultTokenLifetime = defaultTokenLifetime
+	}
+
+	if err := validateClientPolicy(cfg.ClientPolicy); err != nil {
+		return Config{}, fmt.Errorf("error validating the client policy: %w", err)
+	}
+
+	if cfg.ForwardAuth.CookieDomain == "" {
+		cfg.ForwardAuth.CookieDomain = cfg.Domain
+	}
+
+	if err := validateForwardAuth(cfg.Domain, cfg.ForwardAuth); err != nil {
+		return Config{}, fmt.Errorf("error validating the forward auth configuration: %w", err)
+	}
+
+	if cfg.Backup.Interval > 0 && cfg.Backup.Directory == "" {
+		return Config{}, ErrMissingBackupDir
+	}
+
+	if cfg.Backup.Interval > 0 && cfg.Database.Driver != "bolt" {
+		return Config{}, ErrBackupRequiresBolt
+	}
+
+	if cfg.Backup.Retention <= 0 {
+		cfg.Backup.Retention = defaultBackupRetention
+	}
+
+	if cfg.Cache.MaxEntries <= 0 {
+		cfg.Cache.MaxEntries = defaultCacheMaxEntries
+	}
+
+	for _, clientID := range cfg.Development.LocalhostClientIDs {
+		if err := validateLocalhostClientID(clientID); err != nil {
+			return Config{}, fmt.Errorf("%w: %q", err, clientID)
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -100,3 +354,77 @@ func validateCookieName(name string) error {
 
 	return nil
 }
+
+func validateScopes(scopes Scopes) error {
+	if scopes.UnknownScopePolicy != "drop" && scopes.UnknownScopePolicy != "reject" {
+		return ErrInvalidScopePolicy
+	}
+
+	for _, definition := range scopes.Definitions {
+		if definition.Name == "" {
+			return ErrMissingScopeName
+		}
+
+		switch definition.Risk {
+		case "", "low", "medium", "high":
+		default:
+			return fmt.Errorf("%w: %s", ErrInvalidScopeRisk, definition.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateClientPolicy(policy ClientPolicy) error {
+	if err := validateHostPatterns(slices.Concat(policy.Allow, policy.Deny)); err != nil {
+		return err
+	}
+
+	for _, client := range policy.Clients {
+		if client.ClientID == "" {
+			return ErrMissingRuleClientID
+		}
+	}
+
+	return nil
+}
+
+func validateForwardAuth(domain string, forwardAuth ForwardAuth) error {
+	if err := validateHostPatterns(forwardAuth.AllowedHosts); err != nil {
+		return err
+	}
+
+	if forwardAuth.CookieDomain != domain && !strings.HasSuffix(domain, "."+forwardAuth.CookieDomain) {
+		return fmt.Errorf("%w: %q", ErrInvalidCookieDomain, forwardAuth.CookieDomain)
+	}
+
+	return nil
+}
+
+func validateHostPatterns(hostPatterns []string) error {
+	for _, hostPattern := range hostPatterns {
+		if err := policy.ValidateHostPattern(hostPattern); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidHostPattern, err)
+		}
+	}
+
+	return nil
+}
+
+func validateLocalhostClientID(clientID string) error {
+	parsedClientID, err := url.Parse(clientID)
+	if err != nil {
+		return ErrInvalidLocalhostID
+	}
+
+	if parsedClientID.Scheme != "http" && parsedClientID.Scheme != "https" {
+		return ErrInvalidLocalhostID
+	}
+
+	switch parsedClientID.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return nil
+	default:
+		return ErrInvalidLocalhostID
+	}
+}
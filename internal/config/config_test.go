@@ -23,7 +23,8 @@ func TestConfig(t *testing.T) {
 			Domain:                  "auth.example.net",
 			GracefulShutdownTimeout: 10,
 			Database: config.Database{
-				Path: "/app/data/indieauth.db",
+				Driver: "bolt",
+				Path:   "/app/data/indieauth.db",
 			},
 			JWT: config.JWT{
 				Secret:     "N4N6Zpwq6tCHR3CcvHmnUynQhU6R6dk0wfi3kFV1o9I0OV6l53xRxQlvQA76aYgP",
@@ -32,6 +33,78 @@ func TestConfig(t *testing.T) {
 			Log: config.Log{
 				Level: "info",
 			},
+			Scopes: config.Scopes{
+				UnknownScopePolicy: "reject",
+				Definitions: []config.ScopeDefinition{
+					{
+						Name:        "delete",
+						Description: "",
+						Risk:        "",
+						Allowed:     false,
+					},
+					{
+						Name:        "journal",
+						Description: "Write entries in your journal",
+						Risk:        "high",
+						Allowed:     true,
+					},
+				},
+			},
+			ClientMetadataCache: config.ClientMetadataCache{
+				DefaultMaxAge:    600,
+				StaleGracePeriod: 86400,
+				LogoMaxSize:      102400,
+			},
+			Outbound: config.Outbound{
+				DenyRanges:      []string{"10.0.0.0/8", "fd00::/8"},
+				ConnectTimeout:  2,
+				Timeout:         5,
+				MaxResponseSize: 65536,
+			},
+			Development: config.Development{
+				LocalhostClientIDs: []string{"http://localhost:3000/"},
+			},
+			ClientPolicy: config.ClientPolicy{
+				Allow:                []string{"*.example.net", "app.example.org"},
+				Deny:                 []string{"untrusted.example.net"},
+				DefaultTokenLifetime: 86400,
+				Clients: []config.ClientRule{
+					{
+						ClientID:      "https://app.example.org/",
+						MaxScopes:     []string{"profile", "create"},
+						TokenLifetime: 3600,
+						Trusted:       true,
+					},
+				},
+			},
+			Authorization: config.Authorization{
+				RequirePAR: true,
+			},
+			ForwardAuth: config.ForwardAuth{
+				AllowedHosts: []string{"*.example.net"},
+				CookieDomain: "example.net",
+			},
+			Compatibility: config.Compatibility{
+				LegacyTokenVerification: true,
+			},
+			ProfileSync: config.ProfileSync{
+				Interval: 86400,
+			},
+			Admin: config.Admin{
+				SocketPath: "/run/beacon/admin.sock",
+			},
+			Backup: config.Backup{
+				Directory: "/app/backups",
+				Interval:  21600,
+				Retention: 14,
+			},
+			Encryption: config.Encryption{
+				KeyFile: "/run/secrets/beacon.keys",
+			},
+			Cache: config.Cache{
+				Persistent: true,
+				MaxEntries: 5000,
+			},
 		},
 		{
 			BindAddress:             "127.0.0.1",
@@ -39,7 +112,8 @@ func TestConfig(t *testing.T) {
 			Domain:                  "auth.example.net",
 			GracefulShutdownTimeout: 30,
 			Database: config.Database{
-				Path: "/app/data/indieauth.db",
+				Driver: "bolt",
+				Path:   "/app/data/indieauth.db",
 			},
 			JWT: config.JWT{
 				Secret:     "vrDFbzgiWEyWn21YLAo0DDVm4pO0CihJhDDZZArxKu0J8w0d-8FtKlt1tCsJFk",
@@ -48,6 +122,40 @@ func TestConfig(t *testing.T) {
 			Log: config.Log{
 				Level: "error",
 			},
+			Scopes: config.Scopes{
+				UnknownScopePolicy: "drop",
+				Definitions:        nil,
+			},
+			ClientMetadataCache: config.ClientMetadataCache{
+				DefaultMaxAge:    3600,
+				StaleGracePeriod: 3600,
+				LogoMaxSize:      262144,
+			},
+			Outbound: config.Outbound{
+				DenyRanges:      nil,
+				ConnectTimeout:  5,
+				Timeout:         10,
+				MaxResponseSize: 1048576,
+			},
+			ClientPolicy: config.ClientPolicy{
+				Allow:                nil,
+				Deny:                 nil,
+				DefaultTokenLifetime: 604800,
+				Clients:              nil,
+			},
+			ForwardAuth: config.ForwardAuth{
+				AllowedHosts: nil,
+				CookieDomain: "auth.example.net",
+			},
+			Backup: config.Backup{
+				Directory: "",
+				Interval:  0,
+				Retention: 7,
+			},
+			Cache: config.Cache{
+				Persistent: false,
+				MaxEntries: 100000,
+			},
 		},
 	}
 
@@ -75,6 +183,42 @@ func TestConfig(t *testing.T) {
 			path:    "testdata/MissingDatabasePath.golden",
 			wantErr: config.ErrMissingDatabasePath,
 		},
+		{
+			path:    "testdata/InvalidScopePolicy.golden",
+			wantErr: config.ErrInvalidScopePolicy,
+		},
+		{
+			path:    "testdata/InvalidScopeRisk.golden",
+			wantErr: config.ErrInvalidScopeRisk,
+		},
+		{
+			path:    "testdata/InvalidDenyRange.golden",
+			wantErr: config.ErrInvalidDenyRange,
+		},
+		{
+			path:    "testdata/InvalidLocalhostClientID.golden",
+			wantErr: config.ErrInvalidLocalhostID,
+		},
+		{
+			path:    "testdata/InvalidHostPattern.golden",
+			wantErr: config.ErrInvalidHostPattern,
+		},
+		{
+			path:    "testdata/InvalidCookieDomain.golden",
+			wantErr: config.ErrInvalidCookieDomain,
+		},
+		{
+			path:    "testdata/MissingBackupDirectory.golden",
+			wantErr: config.ErrMissingBackupDir,
+		},
+		{
+			path:    "testdata/InvalidDatabaseDriver.golden",
+			wantErr: config.ErrInvalidDBDriver,
+		},
+		{
+			path:    "testdata/BackupRequiresBolt.golden",
+			wantErr: config.ErrBackupRequiresBolt,
+		},
 	}
 
 	for ind, ec := range errorCases {
@@ -0,0 +1,680 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// ArchiveFormat identifies a JSON document as a Beacon archive.
+	ArchiveFormat string = "beacon-archive"
+
+	// ArchiveVersion is the version of the archive format written by Export.
+	// The version is incremented when a change to the format would stop an older
+	// version of Beacon from reading the archive correctly.
+	//
+	// Version 2 added the hash of the authorization code to the access tokens.
+	ArchiveVersion int = 2
+)
+
+// ImportMode is the way that the records in an archive are imported into the database.
+type ImportMode string
+
+const (
+	// ImportModeMerge adds the records in the archive to the database and all other
+	// records are kept. The archive must not contain a profile that already exists.
+	// A client or token that already exists is left as it is if it is the same as the
+	// record in the archive, otherwise the conflict is reported and nothing is imported.
+	ImportModeMerge ImportMode = "merge"
+
+	// ImportModeReplace deletes the profiles, clients, tokens and settings from the
+	// database before the records in the archive are added.
+	ImportModeReplace ImportMode = "replace"
+)
+
+var (
+	ErrNotAnArchive       = errors.New("the file is not a Beacon archive")
+	ErrUnknownImportMode  = errors.New("unknown import mode (the supported modes are 'merge' and 'replace')")
+	ErrNoProfilesToImport = errors.New("the archive does not contain any profiles to replace the existing profiles with")
+)
+
+// archiveBuckets are the buckets whose records are exported to the archive.
+// The cached client metadata and logos are not exported because they are
+// fetched again when they are needed.
+var archiveBuckets = []string{
+	profilesBucketName,
+	clientBucketName,
+	tokenBucketName,
+	receivedTokenBucketName,
+	settingsBucketName,
+}
+
+// Archive is the portable representation of a Beacon instance. It is written as a
+// JSON document that does not depend on how the records are stored so that an
+// instance can be moved between hosts and storage backends.
+//
+// The format and version fields are always present and are checked before the rest
+// of the archive is read. The time values are written in RFC 3339 format.
+//
+// The archive is not encrypted. The passwords, client secrets and issued access tokens
+// are only stored as hashes but the received access tokens, which are only exported
+// when requested, are stored in plain text.
+type Archive struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+
+	Profiles       []ArchiveProfile       `json:"profiles"`
+	Clients        []ArchiveClient        `json:"clients"`
+	AccessTokens   []ArchiveAccessToken   `json:"access_tokens"`
+	ReceivedTokens []ArchiveReceivedToken `json:"received_tokens"`
+	Settings       ArchiveSettings        `json:"settings"`
+}
+
+// ArchiveProfile is a profile and its bcrypt password hash.
+type ArchiveProfile struct {
+	ProfileID      string    `json:"profile_id"`
+	HashedPassword string    `json:"hashed_password"`
+	TokenVersion   int       `json:"token_version"`
+	Name           string    `json:"name"`
+	URL            string    `json:"url"`
+	PhotoURL       string    `json:"photo_url"`
+	Email          string    `json:"email"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ArchiveClient is a registered client. Only the hash of the client's secret
+// is stored.
+type ArchiveClient struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	AuthMethod   string    `json:"auth_method"`
+	SecretHash   string    `json:"secret_hash"`
+	PublicKey    string    `json:"public_key"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ArchiveAccessToken is the access granted to a client by a profile owner.
+// Only the hash of the access token is stored so the archive cannot be used
+// to recover the token.
+type ArchiveAccessToken struct {
+	TokenHash             string    `json:"token_hash"`
+	ClientID              string    `json:"client_id"`
+	Me                    string    `json:"me"`
+	Scopes                []string  `json:"scopes"`
+	JKT                   string    `json:"jkt"`
+	IssuedAt              time.Time `json:"issued_at"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	AuthorizationCodeHash string    `json:"authorization_code_hash"`
+}
+
+// ArchiveReceivedToken is an access token that a profile owner received from
+// another IndieAuth server in exchange for a ticket. The access token is stored
+// in plain text, unlike in the database, since it must still work after it is
+// imported. Anyone with the archive can use it to access the resource.
+type ArchiveReceivedToken struct {
+	Resource      string    `json:"resource"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	TokenEndpoint string    `json:"token_endpoint"`
+	AccessToken   string    `json:"access_token"`
+	TokenType     string    `json:"token_type"`
+	Scope         string    `json:"scope"`
+	ReceivedAt    time.Time `json:"received_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ArchiveSettings are the instance-wide settings.
+type ArchiveSettings struct {
+	ClientPolicy ArchiveClientPolicy `json:"client_policy"`
+}
+
+// ArchiveClientPolicy is the policy that controls which clients can be authorized.
+type ArchiveClientPolicy struct {
+	Allow []string                  `json:"allow"`
+	Deny  []string                  `json:"deny"`
+	Rules []ArchiveClientPolicyRule `json:"rules"`
+}
+
+// ArchiveClientPolicyRule is the policy rule for a single client.
+// The token lifetime is in seconds.
+type ArchiveClientPolicyRule struct {
+	ClientID      string   `json:"client_id"`
+	MaxScopes     []string `json:"max_scopes"`
+	TokenLifetime int      `json:"token_lifetime"`
+	Trusted       bool     `json:"trusted"`
+}
+
+// ExportOptions are the options for exporting the database to an archive.
+type ExportOptions struct {
+	// IncludeReceivedTokens includes the access tokens that the profile owners
+	// received from other servers. The tokens are written in plain text.
+	IncludeReceivedTokens bool
+}
+
+// Export writes the archive of the database to the writer. The records are read
+// inside a single read transaction so that the archive is consistent. The received
+// access tokens are only exported if the options include them.
+func (s *store) Export(writer io.Writer, options ExportOptions) error {
+	archive := Archive{
+		Format:         ArchiveFormat,
+		Version:        ArchiveVersion,
+		ExportedAt:     time.Now().UTC(),
+		Profiles:       make([]ArchiveProfile, 0),
+		Clients:        make([]ArchiveClient, 0),
+		AccessTokens:   make([]ArchiveAccessToken, 0),
+		ReceivedTokens: make([]ArchiveReceivedToken, 0),
+		Settings: ArchiveSettings{
+			ClientPolicy: ArchiveClientPolicy{
+				Allow: make([]string, 0),
+				Deny:  make([]string, 0),
+				Rules: make([]ArchiveClientPolicyRule, 0),
+			},
+		},
+	}
+
+	if err := s.view(func(tx tx) error {
+		if err := forEachRecord(tx, profilesBucketName, "", func(key string, profile Profile) error {
+			archive.Profiles = append(archive.Profiles, ArchiveProfile{
+				ProfileID:      key,
+				HashedPassword: profile.HashedPassword,
+				TokenVersion:   profile.TokenVersion,
+				Name:           profile.Information.Name,
+				URL:            profile.Information.URL,
+				PhotoURL:       profile.Information.PhotoURL,
+				Email:          profile.Information.Email,
+				CreatedAt:      profile.CreatedAt,
+				UpdatedAt:      profile.UpdatedAt,
+			})
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if err := forEachRecord(tx, clientBucketName, "", func(_ string, client RegisteredClient) error {
+			archive.Clients = append(archive.Clients, archiveClient(client))
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if err := forEachRecord(tx, tokenBucketName, "", func(key string, token AccessToken) error {
+			archive.AccessTokens = append(archive.AccessTokens, archiveAccessToken(key, token))
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if options.IncludeReceivedTokens {
+			if err := forEachRecord(tx, receivedTokenBucketName, "", func(_ string, token ReceivedToken) error {
+				archive.ReceivedTokens = append(archive.ReceivedTokens, ArchiveReceivedToken(token))
+
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		policy, _, err := getRecord[ClientPolicy](tx, settingsBucketName, clientPolicyKey)
+		if err != nil {
+			return err
+		}
+
+		archive.Settings.ClientPolicy.Allow = nonNil(policy.Allow)
+		archive.Settings.ClientPolicy.Deny = nonNil(policy.Deny)
+
+		for _, rule := range policy.Rules {
+			archive.Settings.ClientPolicy.Rules = append(archive.Settings.ClientPolicy.Rules, ArchiveClientPolicyRule{
+				ClientID:      rule.ClientID,
+				MaxScopes:     nonNil(rule.MaxScopes),
+				TokenLifetime: rule.TokenLifetime,
+				Trusted:       rule.Trusted,
+			})
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("error reading the records from the database: %w", err)
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(archive); err != nil {
+		return fmt.Errorf("error encoding the archive: %w", err)
+	}
+
+	return nil
+}
+
+// ExportToFile writes the archive of the store to the file at the path.
+func ExportToFile(store Store, path string, options ExportOptions) error {
+	return writeFile(path, func(writer io.Writer) error {
+		return store.Export(writer, options)
+	})
+}
+
+// SaveArchive saves the archive read from the reader to the file at the path.
+// The archive is only saved if it is valid.
+func SaveArchive(reader io.Reader, path string) error {
+	return writeFile(path, func(writer io.Writer) error {
+		if _, err := io.Copy(writer, reader); err != nil {
+			return fmt.Errorf("error reading the archive: %w", err)
+		}
+
+		return nil
+	}, func(path string) error {
+		_, err := ReadArchiveFile(path)
+
+		return err
+	})
+}
+
+// ReadArchive reads and validates the archive from the reader.
+func ReadArchive(reader io.Reader) (Archive, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading the archive: %w", err)
+	}
+
+	// The format and version are checked first so that an archive from a newer
+	// version of Beacon is reported as unsupported instead of as invalid.
+	var header struct {
+		Format  string `json:"format"`
+		Version int    `json:"version"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil || header.Format != ArchiveFormat {
+		return Archive{}, ErrNotAnArchive
+	}
+
+	if header.Version < 1 || header.Version > ArchiveVersion {
+		return Archive{}, UnsupportedArchiveVersionError{version: header.Version, latest: ArchiveVersion}
+	}
+
+	var archive Archive
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&archive); err != nil {
+		return Archive{}, fmt.Errorf("error decoding the archive: %w", err)
+	}
+
+	if problems := archive.validate(); len(problems) > 0 {
+		return Archive{}, InvalidArchiveError{problems: problems}
+	}
+
+	return archive, nil
+}
+
+// ReadArchiveFile reads and validates the archive from the file at the path.
+func ReadArchiveFile(path string) (Archive, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return Archive{}, fmt.Errorf("unable to open the archive: %w", err)
+	}
+	defer file.Close()
+
+	return ReadArchive(file)
+}
+
+// Import writes the records in the archive to the database using the import mode.
+// The archive and the existing records are checked before anything is written and
+// all of the records are written in a single transaction, so a failed import leaves
+// the database unchanged.
+func (s *store) Import(archive Archive, mode ImportMode) error {
+	if mode != ImportModeMerge && mode != ImportModeReplace {
+		return fmt.Errorf("%w: %s", ErrUnknownImportMode, mode)
+	}
+
+	if problems := archive.validate(); len(problems) > 0 {
+		return InvalidArchiveError{problems: problems}
+	}
+
+	// Replacing the profiles with none would leave Beacon waiting to be set up again.
+	if mode == ImportModeReplace && len(archive.Profiles) == 0 {
+		return ErrNoProfilesToImport
+	}
+
+	if err := s.update(func(tx tx) error {
+		if mode == ImportModeReplace {
+			for _, name := range slices.Concat(archiveBuckets, []string{tokenCodeIndexBucketName}) {
+				if err := tx.deleteBucket(name); err != nil {
+					return fmt.Errorf("error deleting the bucket %q: %w", name, err)
+				}
+			}
+		}
+
+		if mode == ImportModeMerge {
+			for _, profile := range archive.Profiles {
+				_, exists, err := getRecord[Profile](tx, profilesBucketName, profile.ProfileID)
+				if err != nil {
+					return err
+				}
+
+				if exists {
+					return ProfileAlreadyExistError{profileID: profile.ProfileID}
+				}
+			}
+
+			conflicts, err := archive.conflicts(tx)
+			if err != nil {
+				return err
+			}
+
+			if len(conflicts) > 0 {
+				return ImportConflictError{conflicts: conflicts}
+			}
+		}
+
+		for _, profile := range archive.Profiles {
+			if err := putRecord(tx, profilesBucketName, profile.ProfileID, Profile{
+				CreatedAt:      profile.CreatedAt,
+				UpdatedAt:      profile.UpdatedAt,
+				TokenVersion:   profile.TokenVersion,
+				HashedPassword: profile.HashedPassword,
+				Information: ProfileInformation{
+					Name:     profile.Name,
+					URL:      profile.URL,
+					PhotoURL: profile.PhotoURL,
+					Email:    profile.Email,
+				},
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, client := range archive.Clients {
+			if err := putRecord(tx, clientBucketName, client.ClientID, RegisteredClient(client)); err != nil {
+				return err
+			}
+		}
+
+		for _, token := range archive.AccessTokens {
+			if err := putAccessToken(tx, token.TokenHash, token.accessToken()); err != nil {
+				return err
+			}
+		}
+
+		for _, token := range archive.ReceivedTokens {
+			key := receivedTokenKey(token.Subject, token.Resource)
+
+			if err := putRecord(tx, receivedTokenBucketName, key, ReceivedToken(token)); err != nil {
+				return err
+			}
+		}
+
+		return importClientPolicy(tx, archive.Settings.ClientPolicy, mode)
+	}); err != nil {
+		return fmt.Errorf("error importing the archive: %w", err)
+	}
+
+	return nil
+}
+
+// conflicts returns the clients, access tokens and received tokens in the archive
+// that already exist in the database with different values.
+func (a Archive) conflicts(tx tx) ([]string, error) {
+	conflicts := make([]string, 0)
+
+	for _, client := range a.Clients {
+		existing, exists, err := getRecord[RegisteredClient](tx, clientBucketName, client.ClientID)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists && !sameArchiveRecord(archiveClient(existing), archiveClient(RegisteredClient(client))) {
+			conflicts = append(conflicts, fmt.Sprintf("the client %q already exists with different values", client.ClientID))
+		}
+	}
+
+	for _, token := range a.AccessTokens {
+		existing, exists, err := getRecord[AccessToken](tx, tokenBucketName, token.TokenHash)
+		if err != nil {
+			return nil, err
+		}
+
+		// The token is converted back to the archive form so that it is compared
+		// in the same way as the existing token.
+		imported := archiveAccessToken(token.TokenHash, token.accessToken())
+
+		if exists && !sameArchiveRecord(archiveAccessToken(token.TokenHash, existing), imported) {
+			conflicts = append(conflicts, fmt.Sprintf("the access token %q already exists with different values", token.TokenHash))
+		}
+	}
+
+	for _, token := range a.ReceivedTokens {
+		key := receivedTokenKey(token.Subject, token.Resource)
+
+		existing, exists, err := getRecord[ReceivedToken](tx, receivedTokenBucketName, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists && !sameArchiveRecord(ArchiveReceivedToken(existing), token) {
+			conflicts = append(
+				conflicts,
+				fmt.Sprintf("the received token for %q on %q already exists with different values", token.Subject, token.Resource),
+			)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// sameArchiveRecord reports whether the records are the same in the archive.
+func sameArchiveRecord(a, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+func archiveClient(client RegisteredClient) ArchiveClient {
+	return ArchiveClient{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: nonNil(client.RedirectURIs),
+		AuthMethod:   client.AuthMethod,
+		SecretHash:   client.SecretHash,
+		PublicKey:    client.PublicKey,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func archiveAccessToken(tokenHash string, token AccessToken) ArchiveAccessToken {
+	return ArchiveAccessToken{
+		TokenHash:             tokenHash,
+		ClientID:              token.ClientID,
+		Me:                    token.Me,
+		Scopes:                nonNil(token.Scopes),
+		JKT:                   token.JKT,
+		IssuedAt:              token.IssuedAt,
+		ExpiresAt:             token.ExpiresAt,
+		AuthorizationCodeHash: token.AuthorizationCodeHash,
+	}
+}
+
+func (t ArchiveAccessToken) accessToken() AccessToken {
+	return AccessToken{
+		ClientID:              t.ClientID,
+		Me:                    t.Me,
+		Scopes:                t.Scopes,
+		IssuedAt:              t.IssuedAt,
+		ExpiresAt:             t.ExpiresAt,
+		JKT:                   t.JKT,
+		AuthorizationCodeHash: t.AuthorizationCodeHash,
+	}
+}
+
+// importClientPolicy writes the client policy from the archive. In merge mode the
+// allow and deny lists are combined with the existing lists and the rules from the
+// archive replace the existing rules for the same clients.
+func importClientPolicy(tx tx, archivePolicy ArchiveClientPolicy, mode ImportMode) error {
+	policy := ClientPolicy{}
+
+	if mode == ImportModeMerge {
+		var err error
+
+		policy, _, err = getRecord[ClientPolicy](tx, settingsBucketName, clientPolicyKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, clientID := range archivePolicy.Allow {
+		if !slices.Contains(policy.Allow, clientID) {
+			policy.Allow = append(policy.Allow, clientID)
+		}
+	}
+
+	for _, clientID := range archivePolicy.Deny {
+		if !slices.Contains(policy.Deny, clientID) {
+			policy.Deny = append(policy.Deny, clientID)
+		}
+	}
+
+	for _, archiveRule := range archivePolicy.Rules {
+		rule := ClientPolicyRule(archiveRule)
+
+		idx := slices.IndexFunc(policy.Rules, func(existing ClientPolicyRule) bool {
+			return existing.ClientID == rule.ClientID
+		})
+
+		if idx < 0 {
+			policy.Rules = append(policy.Rules, rule)
+		} else {
+			policy.Rules[idx] = rule
+		}
+	}
+
+	return putRecord(tx, settingsBucketName, clientPolicyKey, policy)
+}
+
+// validate returns the problems found in the archive.
+func (a Archive) validate() []string {
+	problems := make([]string, 0)
+
+	addProblem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if a.Format != ArchiveFormat {
+		addProblem("the format is %q instead of %q", a.Format, ArchiveFormat)
+	}
+
+	if a.Version < 1 || a.Version > ArchiveVersion {
+		addProblem("the archive version %d is not supported", a.Version)
+	}
+
+	profileIDs := make(map[string]struct{})
+
+	for idx, profile := range a.Profiles {
+		switch {
+		case strings.TrimSpace(profile.ProfileID) == "":
+			addProblem("profile %d does not have a profile ID", idx)
+		case hasKey(profileIDs, profile.ProfileID):
+			addProblem("the profile %q appears more than once", profile.ProfileID)
+		}
+
+		if _, err := bcrypt.Cost([]byte(profile.HashedPassword)); err != nil {
+			addProblem("the password hash for the profile %q is not a valid bcrypt hash", profile.ProfileID)
+		}
+
+		if profile.TokenVersion < 0 {
+			addProblem("the token version for the profile %q is negative", profile.ProfileID)
+		}
+	}
+
+	clientIDs := make(map[string]struct{})
+
+	for idx, client := range a.Clients {
+		switch {
+		case client.ClientID == "":
+			addProblem("client %d does not have a client ID", idx)
+		case hasKey(clientIDs, client.ClientID):
+			addProblem("the client %q appears more than once", client.ClientID)
+		}
+	}
+
+	tokenHashes := make(map[string]struct{})
+
+	for idx, token := range a.AccessTokens {
+		switch {
+		case token.TokenHash == "":
+			addProblem("access token %d does not have a token hash", idx)
+		case hasKey(tokenHashes, token.TokenHash):
+			addProblem("access token %d has the same token hash as an earlier access token", idx)
+		}
+
+		if token.ClientID == "" || token.Me == "" {
+			addProblem("access token %d does not have a client ID and a profile URL", idx)
+		}
+	}
+
+	receivedTokenKeys := make(map[string]struct{})
+
+	for idx, token := range a.ReceivedTokens {
+		switch {
+		case token.Subject == "" || token.Resource == "":
+			addProblem("received token %d does not have a subject and a resource", idx)
+		case hasKey(receivedTokenKeys, receivedTokenKey(token.Subject, token.Resource)):
+			addProblem("the received token for %q on %q appears more than once", token.Subject, token.Resource)
+		}
+
+		if token.AccessToken == "" {
+			addProblem("received token %d does not have an access token", idx)
+		}
+	}
+
+	for idx, rule := range a.Settings.ClientPolicy.Rules {
+		if rule.ClientID == "" {
+			addProblem("client policy rule %d does not have a client ID", idx)
+		}
+
+		if rule.TokenLifetime < 0 {
+			addProblem("the token lifetime in the client policy rule for %q is negative", rule.ClientID)
+		}
+	}
+
+	return problems
+}
+
+// hasKey reports whether the key is already in the set and adds it if it is not.
+func hasKey(set map[string]struct{}, key string) bool {
+	if _, ok := set[key]; ok {
+		return true
+	}
+
+	set[key] = struct{}{}
+
+	return false
+}
+
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return make([]T, 0)
+	}
+
+	return values
+}
@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testExportAndImport(store database.Store, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		if err := store.SaveRegisteredClient(database.RegisteredClient{
+			ClientID:     "https://archive.example.app/",
+			Name:         "Archive App",
+			RedirectURIs: []string{"https://archive.example.app/callback"},
+			AuthMethod:   "client_secret_basic",
+			SecretHash:   "secret-hash",
+			CreatedAt:    time.Now(),
+		}); err != nil {
+			t.Fatalf("FAILED test %s: Unable to save the registered client: %v", testName, err)
+		}
+
+		if err := store.SaveReceivedToken(database.ReceivedToken{
+			Resource:      "https://archive.example.org/private/",
+			Subject:       "https://archive.example.net/",
+			Issuer:        "https://archive.example.org/",
+			TokenEndpoint: "https://archive.example.org/token",
+			AccessToken:   "archived-received-token",
+			TokenType:     "Bearer",
+			Scope:         "read",
+			ReceivedAt:    time.Now(),
+			ExpiresAt:     time.Now().Add(1 * time.Hour),
+		}); err != nil {
+			t.Fatalf("FAILED test %s: Unable to save the received token: %v", testName, err)
+		}
+
+		// The received tokens are only exported when they are included.
+		var buffer bytes.Buffer
+
+		if err := store.Export(&buffer, database.ExportOptions{}); err != nil {
+			t.Fatalf("FAILED test %s: Unable to export the database: %v", testName, err)
+		}
+
+		if strings.Contains(buffer.String(), "archived-received-token") {
+			t.Errorf("FAILED test %s: The received token was exported without being included.", testName)
+		} else {
+			t.Log("The received token was not exported without being included.")
+		}
+
+		archive := exportTestArchive(t, testName, store)
+
+		profileIDs, err := store.GetProfileIDs()
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to get the profile IDs: %v", testName, err)
+		}
+
+		if len(archive.Profiles) != len(profileIDs) ||
+			len(archive.Clients) == 0 ||
+			len(archive.AccessTokens) == 0 ||
+			len(archive.ReceivedTokens) == 0 {
+			t.Fatalf(
+				"FAILED test %s: The archive is missing records.\ngot: %d profile(s), %d client(s), %d access token(s), %d received token(s)",
+				testName,
+				len(archive.Profiles),
+				len(archive.Clients),
+				len(archive.AccessTokens),
+				len(archive.ReceivedTokens),
+			)
+		}
+
+		// The archive imported into a new store exports to the same archive.
+		newStore := database.NewMemoryStore()
+
+		if err := newStore.Import(archive, database.ImportModeReplace); err != nil {
+			t.Fatalf("FAILED test %s: Unable to import the archive: %v", testName, err)
+		}
+
+		want := encodeTestArchive(t, testName, archive)
+		got := encodeTestArchive(t, testName, exportTestArchive(t, testName, newStore))
+
+		if !bytes.Equal(want, got) {
+			t.Errorf(
+				"FAILED test %s: Unexpected archive exported from the new database.\nwant: %s\ngot: %s",
+				testName,
+				want,
+				got,
+			)
+		} else {
+			t.Log("The imported database exported to the same archive.")
+		}
+
+		// The access tokens are indexed by the authorization code after the import.
+		if count, err := newStore.DeleteAccessTokensIssuedFromCode("code-b"); err != nil || count != 1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected result deleting the imported access token issued from the code.\ngot: %d deleted\nerror: %v",
+				testName,
+				count,
+				err,
+			)
+		} else {
+			t.Log("The imported access token issued from the code was deleted.")
+		}
+
+		if err := newStore.Import(archive, database.ImportModeReplace); err != nil {
+			t.Fatalf("FAILED test %s: Unable to import the archive: %v", testName, err)
+		}
+
+		// Existing profiles are not overwritten in merge mode.
+		err = newStore.Import(archive, database.ImportModeMerge)
+
+		var alreadyExistErr database.ProfileAlreadyExistError
+
+		if !errors.As(err, &alreadyExistErr) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error merging an existing profile.\nwant: %T\ngot: %v",
+				testName,
+				alreadyExistErr,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received merging an existing profile.\ngot: %v", err)
+		}
+
+		newProfile := archive.Profiles[0]
+		newProfile.ProfileID = "https://samwise.example.me/"
+
+		merged := archive
+		merged.Profiles = []database.ArchiveProfile{newProfile}
+
+		if err := newStore.Import(merged, database.ImportModeMerge); err != nil {
+			t.Fatalf("FAILED test %s: Unable to merge the archive: %v", testName, err)
+		}
+
+		gotIDs, err := newStore.GetProfileIDs()
+		if err != nil || len(gotIDs) != len(profileIDs)+1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected profiles after the merge.\ngot: %v\nerror: %v",
+				testName,
+				gotIDs,
+				err,
+			)
+		} else {
+			t.Logf("Expected profiles after the merge.\ngot: %v", gotIDs)
+		}
+
+		// Existing clients are not overwritten with different values in merge mode.
+		conflicting := archive
+		conflicting.Profiles = []database.ArchiveProfile{}
+		conflicting.Clients = []database.ArchiveClient{archive.Clients[0]}
+		conflicting.Clients[0].Name = "Renamed App"
+
+		err = newStore.Import(conflicting, database.ImportModeMerge)
+
+		var conflictErr database.ImportConflictError
+
+		if !errors.As(err, &conflictErr) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error merging a conflicting client.\nwant: %T\ngot: %v",
+				testName,
+				conflictErr,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received merging a conflicting client.\ngot: %v", err)
+		}
+
+		if client, _, _ := newStore.GetRegisteredClient(archive.Clients[0].ClientID); client.Name != archive.Clients[0].Name {
+			t.Errorf("FAILED test %s: The existing client was overwritten.\ngot: %+v", testName, client)
+		}
+
+		// Nothing is written from an invalid archive.
+		invalid := archive
+		invalid.Profiles = []database.ArchiveProfile{newProfile, newProfile}
+		invalid.Profiles[1].HashedPassword = "password"
+
+		err = newStore.Import(invalid, database.ImportModeReplace)
+
+		var invalidErr database.InvalidArchiveError
+
+		if !errors.As(err, &invalidErr) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error importing the invalid archive.\nwant: %T\ngot: %v",
+				testName,
+				invalidErr,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received importing the invalid archive.\ngot: %v", err)
+		}
+
+		if ids, _ := newStore.GetProfileIDs(); len(ids) != len(gotIDs) {
+			t.Errorf("FAILED test %s: The database was changed by the invalid archive.\ngot: %v", testName, ids)
+		}
+	}
+}
+
+func TestReadArchive(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		archive   string
+		wantError func(err error) bool
+	}{
+		{
+			name:    "Not an archive",
+			archive: `{"profiles": []}`,
+			wantError: func(err error) bool {
+				return errors.Is(err, database.ErrNotAnArchive)
+			},
+		},
+		{
+			name:    "Unsupported archive version",
+			archive: `{"format": "beacon-archive", "version": 99}`,
+			wantError: func(err error) bool {
+				return errors.As(err, new(database.UnsupportedArchiveVersionError))
+			},
+		},
+		{
+			name:    "Unknown field",
+			archive: `{"format": "beacon-archive", "version": 1, "grants": []}`,
+			wantError: func(err error) bool {
+				return err != nil && strings.Contains(err.Error(), "unknown field")
+			},
+		},
+		{
+			name:    "Invalid records",
+			archive: `{"format": "beacon-archive", "version": 1, "clients": [{"client_id": ""}]}`,
+			wantError: func(err error) bool {
+				return errors.As(err, new(database.InvalidArchiveError))
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := database.ReadArchive(strings.NewReader(testCase.archive))
+			if !testCase.wantError(err) {
+				t.Errorf(
+					"FAILED test %s: Unexpected error reading the archive.\ngot: %v",
+					t.Name(),
+					err,
+				)
+			} else {
+				t.Logf("Expected error received.\ngot: %v", err)
+			}
+		})
+	}
+}
+
+func exportTestArchive(t *testing.T, testName string, store database.Store) database.Archive {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	if err := store.Export(&buffer, database.ExportOptions{IncludeReceivedTokens: true}); err != nil {
+		t.Fatalf("FAILED test %s: Unable to export the database: %v", testName, err)
+	}
+
+	archive, err := database.ReadArchive(&buffer)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to read the exported archive: %v", testName, err)
+	}
+
+	return archive
+}
+
+// encodeTestArchive encodes the archive without its export time so that
+// archives exported at different times can be compared.
+func encodeTestArchive(t *testing.T, testName string, archive database.Archive) []byte {
+	t.Helper()
+
+	archive.ExportedAt = time.Time{}
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to encode the archive: %v", testName, err)
+	}
+
+	return data
+}
@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	backupFilePrefix    string = "beacon-"
+	backupFileSuffix    string = ".db"
+	backupTimestampFmt  string = "20060102T150405.000Z"
+	restoreTimestampFmt string = "20060102T150405Z"
+)
+
+var ErrDatabaseInUse = errors.New("the database is in use; please stop Beacon before restoring the database")
+
+// Backup writes a consistent copy of the database to the writer. The copy is made
+// inside a read transaction so the database can be backed up while Beacon is running.
+func Backup(boltdb *bolt.DB, writer io.Writer) (int64, error) {
+	var size int64
+
+	if err := boltdb.View(func(tx *bolt.Tx) error {
+		var err error
+
+		size, err = tx.WriteTo(writer)
+
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("error writing the backup of the database: %w", err)
+	}
+
+	return size, nil
+}
+
+// BackupToFile writes a backup of the database to the file at the path.
+func BackupToFile(boltdb *bolt.DB, path string) error {
+	return writeFile(path, func(writer io.Writer) error {
+		_, err := Backup(boltdb, writer)
+
+		return err
+	})
+}
+
+// SaveBackup saves the backup read from the reader to the file at the path.
+// The backup is only saved if it passes the integrity check.
+func SaveBackup(reader io.Reader, path string) error {
+	return writeFile(path, func(writer io.Writer) error {
+		if _, err := io.Copy(writer, reader); err != nil {
+			return fmt.Errorf("error reading the backup: %w", err)
+		}
+
+		return nil
+	}, VerifyBackup)
+}
+
+// BackupToDir writes a timestamped backup of the database to the directory and
+// deletes the oldest backups so that only the retention count of backups are kept.
+// The path to the new backup is returned.
+func BackupToDir(boltdb *bolt.DB, dir string, retention int) (string, error) {
+	if err := utilities.MakeDir(dir); err != nil {
+		return "", fmt.Errorf("error creating the backup directory: %w", err)
+	}
+
+	path := filepath.Join(dir, backupFilePrefix+time.Now().UTC().Format(backupTimestampFmt)+backupFileSuffix)
+
+	if err := BackupToFile(boltdb, path); err != nil {
+		return "", err
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, backupFilePrefix+"*"+backupFileSuffix))
+	if err != nil {
+		return "", fmt.Errorf("error listing the backups in %s: %w", dir, err)
+	}
+
+	// The timestamps in the file names sort the backups from the oldest to the newest.
+	slices.Sort(backups)
+
+	for len(backups) > retention {
+		if err := os.Remove(backups[0]); err != nil {
+			return path, fmt.Errorf("error deleting the old backup %s: %w", backups[0], err)
+		}
+
+		backups = backups[1:]
+	}
+
+	return path, nil
+}
+
+// VerifyBackup checks the integrity of the backup at the path and that its schema
+// version is supported by this version of Beacon.
+func VerifyBackup(path string) error {
+	backup, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true, Timeout: 1 * time.Second})
+	if err != nil {
+		return fmt.Errorf("unable to open the backup at %q: %w", path, err)
+	}
+	defer backup.Close()
+
+	if err := backup.View(func(tx *bolt.Tx) error {
+		checkErrs := make([]error, 0)
+
+		for err := range tx.Check() {
+			checkErrs = append(checkErrs, err)
+		}
+
+		return errors.Join(checkErrs...)
+	}); err != nil {
+		return fmt.Errorf("the backup at %q failed the integrity check: %w", path, err)
+	}
+
+	if _, err := PendingMigrations(backup); err != nil {
+		return fmt.Errorf("the backup at %q cannot be restored: %w", path, err)
+	}
+
+	return nil
+}
+
+// Restore replaces the database at the database path with the backup after checking
+// the backup's integrity. The replaced database is kept next to the database and its
+// path is returned. Beacon must be stopped before the database is restored.
+func Restore(backupPath, dbPath string) (string, error) {
+	if err := VerifyBackup(backupPath); err != nil {
+		return "", err
+	}
+
+	backup, err := os.Open(filepath.Clean(backupPath))
+	if err != nil {
+		return "", fmt.Errorf("unable to open the backup: %w", err)
+	}
+	defer backup.Close()
+
+	restoredPath := dbPath + ".restore"
+
+	if err := writeFile(restoredPath, func(writer io.Writer) error {
+		_, err := io.Copy(writer, backup)
+
+		return err
+	}); err != nil {
+		return "", err
+	}
+
+	dbExists, err := utilities.FileExists(dbPath)
+	if err != nil {
+		return "", fmt.Errorf("error checking if the database exists: %w", err)
+	}
+
+	if !dbExists {
+		if err := os.Rename(restoredPath, dbPath); err != nil {
+			return "", fmt.Errorf("error moving the restored database into place: %w", err)
+		}
+
+		return "", nil
+	}
+
+	// Hold the lock on the current database while the files are swapped
+	// so that the database cannot be restored while Beacon is running.
+	current, err := bolt.Open(dbPath, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		_ = os.Remove(restoredPath)
+
+		if errors.Is(err, bolt.ErrTimeout) {
+			return "", ErrDatabaseInUse
+		}
+
+		return "", fmt.Errorf("unable to open the database at %q: %w", dbPath, err)
+	}
+	defer current.Close()
+
+	replacedPath := dbPath + ".pre-restore-" + time.Now().UTC().Format(restoreTimestampFmt) + ".bak"
+
+	if err := os.Rename(dbPath, replacedPath); err != nil {
+		return "", fmt.Errorf("error moving the current database aside: %w", err)
+	}
+
+	if err := os.Rename(restoredPath, dbPath); err != nil {
+		return "", fmt.Errorf("error moving the restored database into place: %w", err)
+	}
+
+	return replacedPath, nil
+}
+
+// writeFile writes the file through a temporary file in the same directory so that
+// an incomplete file never replaces the file at the path. The checks run on the
+// complete temporary file before it is moved into place.
+func writeFile(path string, write func(io.Writer) error, checks ...func(string) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating the temporary file: %w", err)
+	}
+
+	tmpPath := tmp.Name()
+
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("error syncing %s: %w", tmpPath, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing %s: %w", tmpPath, err)
+	}
+
+	for _, check := range checks {
+		if err := check(tmpPath); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error moving the file to %s: %w", path, err)
+	}
+
+	return nil
+}
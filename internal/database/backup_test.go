@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	bolt "go.etcd.io/bbolt"
+)
+
+func testBackupAndRestore(boltdb *bolt.DB, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		backupDir := t.TempDir()
+		retention := 2
+
+		var latestBackup string
+
+		for range retention + 1 {
+			path, err := database.BackupToDir(boltdb, backupDir, retention)
+			if err != nil {
+				t.Fatalf(
+					"FAILED test %s: Unable to back up the database: %v",
+					testName,
+					err,
+				)
+			}
+
+			latestBackup = path
+
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		backups, err := filepath.Glob(filepath.Join(backupDir, "*"))
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to list the backups: %v", testName, err)
+		}
+
+		if len(backups) != retention {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of backups kept.\nwant: %d\ngot: %d (%v)",
+				testName,
+				retention,
+				len(backups),
+				backups,
+			)
+		} else {
+			t.Logf("Expected number of backups kept.\ngot: %v", backups)
+		}
+
+		if err := database.VerifyBackup(latestBackup); err != nil {
+			t.Errorf(
+				"FAILED test %s: The backup failed the integrity check: %v",
+				testName,
+				err,
+			)
+		}
+
+		corruptBackup := filepath.Join(backupDir, "corrupt.db")
+
+		if err := os.WriteFile(corruptBackup, []byte("this is not a database"), 0o600); err != nil {
+			t.Fatalf("FAILED test %s: Unable to write the corrupt backup: %v", testName, err)
+		}
+
+		if err := database.VerifyBackup(corruptBackup); err == nil {
+			t.Errorf(
+				"FAILED test %s: The corrupt backup passed the integrity check.",
+				testName,
+			)
+		} else {
+			t.Logf("Expected error received for the corrupt backup.\ngot: %v", err)
+		}
+
+		// The database in use cannot be restored.
+		if _, err := database.Restore(latestBackup, boltdb.Path()); !errors.Is(err, database.ErrDatabaseInUse) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error restoring the database in use.\nwant: %v\ngot: %v",
+				testName,
+				database.ErrDatabaseInUse,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received restoring the database in use.\ngot: %v", err)
+		}
+
+		// The restored database replaces the stopped database which is kept aside.
+		dbPath := filepath.Join(t.TempDir(), "data", "beacon.db")
+
+		stopped, err := database.Open(dbPath)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to open the database: %v", testName, err)
+		}
+
+		_ = stopped.Close()
+
+		replacedPath, err := database.Restore(latestBackup, dbPath)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to restore the database: %v", testName, err)
+		}
+
+		if exists, err := os.Stat(replacedPath); err != nil || exists.IsDir() {
+			t.Errorf(
+				"FAILED test %s: The replaced database was not kept at %q: %v",
+				testName,
+				replacedPath,
+				err,
+			)
+		}
+
+		restored, err := database.Open(dbPath)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to open the restored database: %v", testName, err)
+		}
+
+		defer restored.Close()
+
+		want, err := database.NewBoltStore(boltdb).GetProfileIDs()
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to get the profile IDs: %v", testName, err)
+		}
+
+		got, err := database.NewBoltStore(restored).GetProfileIDs()
+		if err != nil || len(got) != len(want) {
+			t.Errorf(
+				"FAILED test %s: Unexpected profiles in the restored database.\nwant: %v\ngot: %v\nerror: %v",
+				testName,
+				want,
+				got,
+				err,
+			)
+		} else {
+			t.Logf("Expected profiles in the restored database.\ngot: %v", got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"bytes"
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBackend stores the records in a BoltDB database. Each bucket is a
+// BoltDB bucket.
+type boltBackend struct {
+	db *bolt.DB
+}
+
+// NewBoltStore returns the store for the BoltDB database.
+func NewBoltStore(boltdb *bolt.DB) Store {
+	return &store{backend: boltBackend{db: boltdb}}
+}
+
+// BoltDB returns the BoltDB database of the store. The boolean value is false
+// if the store does not use the bolt driver.
+func BoltDB(s Store) (*bolt.DB, bool) {
+	boltStore, ok := s.(*store)
+	if !ok {
+		return nil, false
+	}
+
+	backend, ok := boltStore.backend.(boltBackend)
+	if !ok {
+		return nil, false
+	}
+
+	return backend.db, true
+}
+
+func (b boltBackend) view(fn func(tx tx) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx: tx})
+	})
+}
+
+func (b boltBackend) update(fn func(tx tx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx: tx})
+	})
+}
+
+func (b boltBackend) close() error {
+	return b.db.Close()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t boltTx) bucketExists(bucket string) (bool, error) {
+	return t.tx.Bucket([]byte(bucket)) != nil, nil
+}
+
+func (t boltTx) get(bucket, key string) ([]byte, error) {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil, nil
+	}
+
+	return b.Get([]byte(key)), nil
+}
+
+func (t boltTx) forEach(bucket, prefix string, fn func(key string, value []byte) error) error {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+
+	cursor := b.Cursor()
+
+	for key, value := cursor.Seek([]byte(prefix)); key != nil && bytes.HasPrefix(key, []byte(prefix)); key, value = cursor.Next() {
+		if err := fn(string(key), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t boltTx) put(bucket, key string, value []byte) error {
+	b, err := t.tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return err
+	}
+
+	return b.Put([]byte(key), value)
+}
+
+func (t boltTx) delete(bucket, key string) error {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+
+	return b.Delete([]byte(key))
+}
+
+func (t boltTx) deleteBucket(bucket string) error {
+	if err := t.tx.DeleteBucket([]byte(bucket)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+
+	return nil
+}
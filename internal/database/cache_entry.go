@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const cacheBucketName string = "cache"
+
+// CacheEntry is an entry of the server's cache such as a pending authorization
+// request or an authorization code that has not been redeemed yet. The entries
+// are saved so that the in-flight flows survive a restart. The value is JSON.
+// The namespace separates the entries of the server's caches.
+type CacheEntry struct {
+	Namespace string          `json:"namespace,omitempty"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+func (CacheEntry) recordFormat() recordFormat {
+	return recordFormat{
+		recordType: "cache_entry",
+		upgrades: []func(json.RawMessage) (json.RawMessage, error){
+			// The values of version 1 are gob encoded and they cannot be converted
+			// to JSON so the entries are expired and removed when they are loaded.
+			func(payload json.RawMessage) (json.RawMessage, error) {
+				var v1 struct {
+					Key string `json:"key"`
+				}
+
+				if err := json.Unmarshal(payload, &v1); err != nil {
+					return nil, err
+				}
+
+				return json.Marshal(CacheEntry{Key: v1.Key})
+			},
+		},
+	}
+}
+
+// cacheEntryKey returns the key that the entry is stored under. Some of the cache
+// keys are secrets such as the authorization codes so the entry is stored under
+// the hash of its namespace and key.
+func cacheEntryKey(namespace, key string) string {
+	if namespace != "" {
+		key = namespace + "\x00" + key
+	}
+
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// GetCacheEntries returns all of the saved cache entries in the namespace including
+// the expired ones.
+func (s *store) GetCacheEntries(namespace string) ([]CacheEntry, error) {
+	entries := make([]CacheEntry, 0)
+
+	if err := s.view(func(tx tx) error {
+		return forEachRecord(tx, cacheBucketName, "", func(_ string, entry CacheEntry) error {
+			if entry.Namespace == namespace {
+				entries = append(entries, entry)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("error retrieving the cache entries from the database: %w", err)
+	}
+
+	return entries, nil
+}
+
+// SaveCacheEntries saves the cache entries in a single transaction. An existing
+// entry with the same key is replaced.
+func (s *store) SaveCacheEntries(entries ...CacheEntry) error {
+	if err := s.update(func(tx tx) error {
+		for _, entry := range entries {
+			if err := putRecord(tx, cacheBucketName, cacheEntryKey(entry.Namespace, entry.Key), entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("error saving the cache entries to the database: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCacheEntries deletes the cache entries in the namespace with the given keys.
+func (s *store) DeleteCacheEntries(namespace string, keys ...string) error {
+	if err := s.update(func(tx tx) error {
+		for _, key := range keys {
+			if err := deleteRecord(tx, cacheBucketName, cacheEntryKey(namespace, key)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("error deleting the cache entries from the database: %w", err)
+	}
+
+	return nil
+}
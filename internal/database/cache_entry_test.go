@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testCacheEntries(store database.Store, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		entries := []database.CacheEntry{
+			{
+				Key:       "fH3x9qTz0aKLmN2pR7sVwY",
+				Value:     json.RawMessage(`{"code":"authorization code"}`),
+				ExpiresAt: time.Now().Add(1 * time.Minute),
+			},
+			{
+				Key:       "state-8Yk2LmQ",
+				Value:     json.RawMessage(`{"state":"authorization request"}`),
+				ExpiresAt: time.Now().Add(10 * time.Minute),
+			},
+		}
+
+		// The entry in another namespace has the same key as the first entry.
+		replayEntry := database.CacheEntry{
+			Namespace: "replay",
+			Key:       entries[0].Key,
+			Value:     json.RawMessage(`true`),
+			ExpiresAt: time.Now().Add(1 * time.Minute),
+		}
+
+		if err := store.SaveCacheEntries(append(entries, replayEntry)...); err != nil {
+			t.Fatalf("FAILED test %s: Unable to save the cache entries: %v", testName, err)
+		}
+
+		got, err := store.GetCacheEntries("")
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to get the cache entries: %v", testName, err)
+		}
+
+		if len(got) != len(entries) {
+			t.Fatalf(
+				"FAILED test %s: Unexpected number of cache entries.\nwant: %d\ngot: %d",
+				testName,
+				len(entries),
+				len(got),
+			)
+		}
+
+		for _, entry := range got {
+			if entry.Key == entries[0].Key && !bytes.Equal(entry.Value, entries[0].Value) {
+				t.Errorf(
+					"FAILED test %s: Unexpected value of the cache entry.\nwant: %q\ngot: %q",
+					testName,
+					entries[0].Value,
+					entry.Value,
+				)
+			}
+		}
+
+		replayEntries, err := store.GetCacheEntries(replayEntry.Namespace)
+		if err != nil || len(replayEntries) != 1 || !bytes.Equal(replayEntries[0].Value, replayEntry.Value) {
+			t.Errorf(
+				"FAILED test %s: Unexpected cache entries in the %q namespace.\ngot: %+v\nerror: %v",
+				testName,
+				replayEntry.Namespace,
+				replayEntries,
+				err,
+			)
+		}
+
+		if err := store.DeleteCacheEntries("", entries[0].Key, entries[1].Key); err != nil {
+			t.Fatalf("FAILED test %s: Unable to delete the cache entries: %v", testName, err)
+		}
+
+		if err := store.DeleteCacheEntries(replayEntry.Namespace, replayEntry.Key); err != nil {
+			t.Fatalf("FAILED test %s: Unable to delete the cache entries: %v", testName, err)
+		}
+
+		got, err = store.GetCacheEntries("")
+		if err != nil || len(got) != 0 {
+			t.Errorf(
+				"FAILED test %s: Unexpected cache entries after deleting them.\ngot: %+v\nerror: %v",
+				testName,
+				got,
+				err,
+			)
+		} else {
+			t.Log("The cache entries were saved and deleted as expected.")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+const clientBucketName string = "clients"
+
+// RegisteredClient is a confidential client that is registered ahead of time.
+// Only the hash of the client secret is stored. The public key is only set for
+// clients that authenticate with private_key_jwt.
+type RegisteredClient struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	AuthMethod   string    `json:"auth_method"`
+	SecretHash   string    `json:"secret_hash"`
+	PublicKey    string    `json:"public_key"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (RegisteredClient) recordFormat() recordFormat {
+	return recordFormat{recordType: "registered_client"}
+}
+
+// GetRegisteredClient returns the registered client with the given client ID.
+// The boolean value is false if the client is not registered.
+func (s *store) GetRegisteredClient(clientID string) (RegisteredClient, bool, error) {
+	var (
+		client RegisteredClient
+		found  bool
+	)
+
+	if err := s.view(func(tx tx) error {
+		var err error
+
+		client, found, err = getRecord[RegisteredClient](tx, clientBucketName, clientID)
+
+		return err
+	}); err != nil {
+		return RegisteredClient{}, false, fmt.Errorf(
+			"error retrieving the registered client from the database: %w",
+			err,
+		)
+	}
+
+	return client, found, nil
+}
+
+// GetRegisteredClients returns all of the registered clients ordered by their client IDs.
+func (s *store) GetRegisteredClients() ([]RegisteredClient, error) {
+	clients := make([]RegisteredClient, 0)
+
+	if err := s.view(func(tx tx) error {
+		return forEachRecord(tx, clientBucketName, "", func(_ string, client RegisteredClient) error {
+			clients = append(clients, client)
+
+			return nil
+		})
+	}); err != nil {
+		return nil, fmt.Errorf(
+			"error retrieving the registered clients from the database: %w",
+			err,
+		)
+	}
+
+	return clients, nil
+}
+
+// SaveRegisteredClient saves the registered client. Any existing registration
+// for the same client ID is replaced.
+func (s *store) SaveRegisteredClient(client RegisteredClient) error {
+	if err := s.update(func(tx tx) error {
+		return putRecord(tx, clientBucketName, client.ClientID, client)
+	}); err != nil {
+		return fmt.Errorf("error saving the registered client to the database: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRegisteredClient deletes the registered client with the given client ID.
+func (s *store) DeleteRegisteredClient(clientID string) error {
+	if err := s.update(func(tx tx) error {
+		return deleteRecord(tx, clientBucketName, clientID)
+	}); err != nil {
+		return fmt.Errorf("error deleting the registered client from the database: %w", err)
+	}
+
+	return nil
+}
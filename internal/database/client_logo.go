@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+const clientLogoBucketName string = "client_logos"
+
+// CachedClientLogo is a client's logo that was fetched from the client's
+// website so that it can be served to the user by Beacon.
+type CachedClientLogo struct {
+	LogoURI     string    `json:"logo_uri"`
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"data"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (CachedClientLogo) recordFormat() recordFormat {
+	return recordFormat{recordType: "cached_client_logo"}
+}
+
+// GetCachedClientLogo returns the cached logo stored under the given ID.
+// The boolean value is false if there is no logo stored under the ID.
+func (s *store) GetCachedClientLogo(logoID string) (CachedClientLogo, bool, error) {
+	var (
+		logo  CachedClientLogo
+		found bool
+	)
+
+	if err := s.view(func(tx tx) error {
+		var err error
+
+		logo, found, err = getRecord[CachedClientLogo](tx, clientLogoBucketName, logoID)
+
+		return err
+	}); err != nil {
+		return CachedClientLogo{}, false, fmt.Errorf(
+			"error retrieving the client logo from the database: %w",
+			err,
+		)
+	}
+
+	return logo, found, nil
+}
+
+// SaveCachedClientLogo saves the client's logo to the database under the given ID.
+func (s *store) SaveCachedClientLogo(logoID string, logo CachedClientLogo) error {
+	if err := s.update(func(tx tx) error {
+		return putRecord(tx, clientLogoBucketName, logoID, logo)
+	}); err != nil {
+		return fmt.Errorf("error saving the client logo to the database: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+const clientMetadataBucketName string = "client_metadata"
+
+// CachedClientMetadata is the client's metadata along with the HTTP caching
+// information received when the metadata was last fetched.
+type CachedClientMetadata struct {
+	ClientID       string    `json:"client_id"`
+	ClientName     string    `json:"client_name"`
+	ClientURI      string    `json:"client_uri"`
+	LogoURI        string    `json:"logo_uri"`
+	RedirectURIs   []string  `json:"redirect_uris"`
+	ETag           string    `json:"etag"`
+	LastModified   string    `json:"last_modified"`
+	MustRevalidate bool      `json:"must_revalidate"`
+	FetchedAt      time.Time `json:"fetched_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+func (CachedClientMetadata) recordFormat() recordFormat {
+	return recordFormat{recordType: "cached_client_metadata"}
+}
+
+// GetCachedClientMetadata returns the cached metadata for the given client ID.
+// The boolean value is false if there is no metadata cached for the client.
+func (s *store) GetCachedClientMetadata(clientID string) (CachedClientMetadata, bool, error) {
+	var (
+		metadata CachedClientMetadata
+		found    bool
+	)
+
+	if err := s.view(func(tx tx) error {
+		var err error
+
+		metadata, found, err = getRecord[CachedClientMetadata](tx, clientMetadataBucketName, clientID)
+
+		return err
+	}); err != nil {
+		return CachedClientMetadata{}, false, fmt.Errorf(
+			"error retrieving the client metadata from the database: %w",
+			err,
+		)
+	}
+
+	return metadata, found, nil
+}
+
+// SaveCachedClientMetadata saves the client's metadata to the database.
+func (s *store) SaveCachedClientMetadata(clientID string, metadata CachedClientMetadata) error {
+	if err := s.update(func(tx tx) error {
+		return putRecord(tx, clientMetadataBucketName, clientID, metadata)
+	}); err != nil {
+		return fmt.Errorf("error saving the client metadata to the database: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStaleClientMetadata deletes the cached metadata that expired before the
+// given time. The number of deleted records is returned.
+func (s *store) DeleteStaleClientMetadata(before time.Time) (int, error) {
+	var count int
+
+	if err := s.update(func(tx tx) error {
+		stale := make([]string, 0)
+
+		if err := forEachRecord(tx, clientMetadataBucketName, "", func(clientID string, metadata CachedClientMetadata) error {
+			if metadata.ExpiresAt.Before(before) {
+				stale = append(stale, clientID)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, clientID := range stale {
+			if err := deleteRecord(tx, clientMetadataBucketName, clientID); err != nil {
+				return err
+			}
+		}
+
+		count = len(stale)
+
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("error deleting the stale client metadata: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteCachedClientMetadata removes the cached metadata for the given client ID.
+func (s *store) DeleteCachedClientMetadata(clientID string) error {
+	if err := s.update(func(tx tx) error {
+		return deleteRecord(tx, clientMetadataBucketName, clientID)
+	}); err != nil {
+		return fmt.Errorf("error deleting the client metadata from the database: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testRegisteredClients(store database.Store, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		clientID := "https://internal.example.org/"
+
+		if _, found, err := store.GetRegisteredClient(clientID); err != nil || found {
+			t.Fatalf(
+				"FAILED test %s: Unexpected result getting a client that was not registered.\nfound: %t\nerror: %v",
+				testName,
+				found,
+				err,
+			)
+		}
+
+		want := database.RegisteredClient{
+			ClientID:     clientID,
+			Name:         "Internal service",
+			RedirectURIs: []string{"https://internal.example.org/callback"},
+			AuthMethod:   "client_secret_basic",
+			SecretHash:   "$2a$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW",
+			CreatedAt:    time.Now().UTC().Truncate(time.Second),
+		}
+
+		if err := store.SaveRegisteredClient(want); err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error registering the client: %v",
+				testName,
+				err,
+			)
+		}
+
+		got, found, err := store.GetRegisteredClient(clientID)
+		if err != nil || !found {
+			t.Fatalf(
+				"FAILED test %s: Unable to get the registered client.\nfound: %t\nerror: %v",
+				testName,
+				found,
+				err,
+			)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf(
+				"FAILED test %s: Unexpected client retrieved from the database.\nwant: %+v\ngot: %+v",
+				testName,
+				want,
+				got,
+			)
+		} else {
+			t.Logf("Expected client retrieved from the database.\ngot: %+v", got)
+		}
+
+		clients, err := store.GetRegisteredClients()
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error getting the registered clients: %v",
+				testName,
+				err,
+			)
+		}
+
+		if len(clients) != 1 || clients[0].ClientID != clientID {
+			t.Errorf(
+				"FAILED test %s: Unexpected list of registered clients.\ngot: %+v",
+				testName,
+				clients,
+			)
+		}
+
+		if err := store.DeleteRegisteredClient(clientID); err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error deleting the registered client: %v",
+				testName,
+				err,
+			)
+		}
+
+		if _, found, err := store.GetRegisteredClient(clientID); err != nil || found {
+			t.Errorf(
+				"FAILED test %s: The client was still registered after it was deleted.\nfound: %t\nerror: %v",
+				testName,
+				found,
+				err,
+			)
+		} else {
+			t.Log("The client was deleted from the database.")
+		}
+	}
+}
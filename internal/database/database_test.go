@@ -35,6 +35,39 @@ func TestDatabase(t *testing.T) {
 		_ = os.RemoveAll(testdataDir)
 	}()
 
-	t.Run("Test Database Setup", testDatabaseSetup(boltdb))
-	t.Run("Test Profile Lifecycle", testProfile(boltdb, t.Name()+" (Profile)"))
+	testStore(t, database.NewBoltStore(boltdb))
+	t.Run("Test Schema Migrations", testMigrations(boltdb, t.Name()+" (Schema Migrations)"))
+	t.Run("Test Backup And Restore", testBackupAndRestore(boltdb, t.Name()+" (Backup And Restore)"))
+}
+
+func TestSQLiteStore(t *testing.T) {
+	t.Parallel()
+
+	store, err := database.NewSQLiteStore(filepath.Join(t.TempDir(), "data", "beacon.sqlite"))
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to open the database: %v", t.Name(), err)
+	}
+
+	defer store.Close()
+
+	testStore(t, store)
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	testStore(t, database.NewMemoryStore())
+}
+
+// testStore runs the tests that every store must pass.
+func testStore(t *testing.T, store database.Store) {
+	t.Run("Test Database Setup", testDatabaseSetup(store))
+	t.Run("Test Profile Lifecycle", testProfile(store, t.Name()+" (Profile)"))
+	t.Run("Test Client Policy", testClientPolicy(store, t.Name()+" (Client Policy)"))
+	t.Run("Test Access Tokens", testAccessTokens(store, t.Name()+" (Access Tokens)"))
+	t.Run("Test Access Token Cleanup", testAccessTokenCleanup(store, t.Name()+" (Access Token Cleanup)"))
+	t.Run("Test Registered Clients", testRegisteredClients(store, t.Name()+" (Registered Clients)"))
+	t.Run("Test Received Tokens", testReceivedTokens(store, t.Name()+" (Received Tokens)"))
+	t.Run("Test Cache Entries", testCacheEntries(store, t.Name()+" (Cache Entries)"))
+	t.Run("Test Export And Import", testExportAndImport(store, t.Name()+" (Export And Import)"))
 }
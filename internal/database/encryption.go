@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/encryption"
+)
+
+var ErrMissingEncryptionKey = errors.New(
+	"the encryption key is not set (set the key file or the " + encryption.KeyEnvVar + " environment variable)",
+)
+
+// sealedBuckets are the buckets whose records hold sensitive fields such as the
+// profile's email address, the access tokens received from other servers and
+// the authorization codes in the cache.
+// Each record is encrypted as a whole so that none of its fields are stored in
+// plaintext.
+var sealedBuckets = []string{
+	profilesBucketName,
+	tokenBucketName,
+	receivedTokenBucketName,
+	cacheBucketName,
+}
+
+// sealedTx encrypts the records of the sealed buckets as they are saved and
+// decrypts the encrypted records as they are read. Records saved before the
+// encryption was enabled are read as they are until they are encrypted by
+// ReencryptRecords or by the next save.
+type sealedTx struct {
+	tx
+
+	keyring *encryption.Keyring
+}
+
+func (t sealedTx) get(bucket, key string) ([]byte, error) {
+	data, err := t.tx.get(bucket, key)
+	if err != nil || data == nil {
+		return data, err
+	}
+
+	return t.open(bucket, key, data)
+}
+
+func (t sealedTx) forEach(bucket, prefix string, fn func(key string, value []byte) error) error {
+	return t.tx.forEach(bucket, prefix, func(key string, data []byte) error {
+		data, err := t.open(bucket, key, data)
+		if err != nil {
+			return err
+		}
+
+		return fn(key, data)
+	})
+}
+
+func (t sealedTx) put(bucket, key string, value []byte) error {
+	if t.keyring == nil || !slices.Contains(sealedBuckets, bucket) {
+		return t.tx.put(bucket, key, value)
+	}
+
+	sealed, err := t.keyring.Seal(value, recordContext(bucket, key))
+	if err != nil {
+		return fmt.Errorf("error encrypting the record %q in the %s bucket: %w", key, bucket, err)
+	}
+
+	return t.tx.put(bucket, key, sealed)
+}
+
+func (t sealedTx) open(bucket, key string, data []byte) ([]byte, error) {
+	if !encryption.IsSealed(data) {
+		return data, nil
+	}
+
+	if t.keyring == nil {
+		return nil, fmt.Errorf("the record %q in the %s bucket is encrypted: %w", key, bucket, ErrMissingEncryptionKey)
+	}
+
+	opened, err := t.keyring.Open(data, recordContext(bucket, key))
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting the record %q in the %s bucket: %w", key, bucket, err)
+	}
+
+	return opened, nil
+}
+
+// recordContext binds the encrypted record to its bucket and key so that it
+// cannot be copied to another record.
+func recordContext(bucket, key string) []byte {
+	return []byte(bucket + "/" + key)
+}
+
+// ReencryptRecords encrypts the records of the sealed buckets again with the
+// primary key. It is run after a new primary key is added to the keyring so
+// that the previous keys can be removed afterwards. The records that are not
+// encrypted yet are encrypted as well. The number of encrypted records is
+// returned.
+func (s *store) ReencryptRecords() (int, error) {
+	if s.keyring == nil {
+		return 0, ErrMissingEncryptionKey
+	}
+
+	type record struct {
+		key  string
+		data []byte
+	}
+
+	var count int
+
+	if err := s.backend.update(func(tx tx) error {
+		count = 0
+		sealed := sealedTx{tx: tx, keyring: s.keyring}
+
+		for _, bucket := range sealedBuckets {
+			pending := make([]record, 0)
+
+			// The records are saved after the iteration since not every backend
+			// supports changing a bucket while iterating over it.
+			if err := tx.forEach(bucket, "", func(key string, data []byte) error {
+				if s.keyring.NeedsResealing(data) {
+					pending = append(pending, record{key: key, data: bytes.Clone(data)})
+				}
+
+				return nil
+			}); err != nil {
+				return fmt.Errorf("error reading the %s bucket: %w", bucket, err)
+			}
+
+			for _, rec := range pending {
+				data, err := sealed.open(bucket, rec.key, rec.data)
+				if err != nil {
+					return err
+				}
+
+				if err := sealed.put(bucket, rec.key, data); err != nil {
+					return err
+				}
+			}
+
+			count += len(pending)
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/encryption"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "data", "beacon.db")
+
+	oldKey, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to generate the key: %v", t.Name(), err)
+	}
+
+	newKey, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to generate the key: %v", t.Name(), err)
+	}
+
+	store := openEncryptedTestStore(t, dbPath, oldKey)
+
+	testStore(t, store)
+
+	boltdb, _ := database.BoltDB(store)
+
+	if err := boltdb.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("profiles")).ForEach(func(key, value []byte) error {
+			if !encryption.IsSealed(value) || bytes.Contains(value, []byte("Pip Hubert")) {
+				t.Errorf("FAILED test %s: The profile %q is stored in plaintext.", t.Name(), key)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		t.Fatalf("FAILED test %s: Unable to read the profiles: %v", t.Name(), err)
+	}
+
+	profileIDs, err := store.GetProfileIDs()
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to get the profile IDs: %v", t.Name(), err)
+	}
+
+	_ = store.Close()
+
+	// The records are encrypted with the new primary key after the rotation.
+	store = openEncryptedTestStore(t, dbPath, newKey+"\n"+oldKey)
+
+	count, err := store.ReencryptRecords()
+	if err != nil || count < len(profileIDs) {
+		t.Errorf(
+			"FAILED test %s: Unexpected result after encrypting the records again.\ngot: %d record(s)\nerror: %v",
+			t.Name(),
+			count,
+			err,
+		)
+	} else {
+		t.Logf("Encrypted %d record(s) with the new primary key.", count)
+	}
+
+	if count, err := store.ReencryptRecords(); err != nil || count != 0 {
+		t.Errorf(
+			"FAILED test %s: Records were encrypted again a second time.\ngot: %d record(s)\nerror: %v",
+			t.Name(),
+			count,
+			err,
+		)
+	}
+
+	_ = store.Close()
+
+	store = openEncryptedTestStore(t, dbPath, newKey)
+
+	if _, err := store.GetProfile(profileIDs[0]); err != nil {
+		t.Errorf("FAILED test %s: Unable to get the profile after removing the old key: %v", t.Name(), err)
+	}
+
+	_ = store.Close()
+
+	store = openEncryptedTestStore(t, dbPath, oldKey)
+
+	if _, err := store.GetProfile(profileIDs[0]); !errors.As(err, new(encryption.UnknownKeyError)) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error getting the profile with the old key.\ngot: %v",
+			t.Name(),
+			err,
+		)
+	} else {
+		t.Logf("Expected error received getting the profile with the old key.\ngot: %v", err)
+	}
+
+	_ = store.Close()
+
+	store, err = database.OpenStore(database.DriverBolt, dbPath, nil)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to open the database: %v", t.Name(), err)
+	}
+
+	defer store.Close()
+
+	if _, err := store.Initialized(); !errors.Is(err, database.ErrMissingEncryptionKey) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error reading the database without the key.\nwant: %v\ngot: %v",
+			t.Name(),
+			database.ErrMissingEncryptionKey,
+			err,
+		)
+	} else {
+		t.Logf("Expected error received reading the database without the key.\ngot: %v", err)
+	}
+}
+
+func openEncryptedTestStore(t *testing.T, dbPath, keys string) database.Store {
+	t.Helper()
+
+	keyring, err := encryption.ParseKeyring(keys)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to parse the keyring: %v", t.Name(), err)
+	}
+
+	store, err := database.OpenStore(database.DriverBolt, dbPath, keyring)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to open the database: %v", t.Name(), err)
+	}
+
+	return store
+}
@@ -4,6 +4,11 @@
 
 package database
 
+import (
+	"fmt"
+	"strings"
+)
+
 type BucketNotExistError struct {
 	bucket string
 }
@@ -27,3 +32,58 @@ type ProfileAlreadyExistError struct {
 func (e ProfileAlreadyExistError) Error() string {
 	return "the profile for '" + e.profileID + "' is already present in the database"
 }
+
+type UnsupportedSchemaVersionError struct {
+	version int
+	latest  int
+}
+
+func (e UnsupportedSchemaVersionError) Error() string {
+	return fmt.Sprintf(
+		"the schema version of the database (%d) is newer than the latest schema version supported by this version of Beacon (%d)",
+		e.version,
+		e.latest,
+	)
+}
+
+type MigrationError struct {
+	version int
+	err     error
+}
+
+func (e MigrationError) Error() string {
+	return fmt.Sprintf("error running the migration to schema version %d: %v", e.version, e.err)
+}
+
+func (e MigrationError) Unwrap() error {
+	return e.err
+}
+
+type UnsupportedArchiveVersionError struct {
+	version int
+	latest  int
+}
+
+func (e UnsupportedArchiveVersionError) Error() string {
+	return fmt.Sprintf(
+		"the archive version (%d) is not supported by this version of Beacon (the latest supported version is %d)",
+		e.version,
+		e.latest,
+	)
+}
+
+type ImportConflictError struct {
+	conflicts []string
+}
+
+func (e ImportConflictError) Error() string {
+	return "the archive conflicts with the existing records:\n  - " + strings.Join(e.conflicts, "\n  - ")
+}
+
+type InvalidArchiveError struct {
+	problems []string
+}
+
+func (e InvalidArchiveError) Error() string {
+	return "the archive is invalid:\n  - " + strings.Join(e.problems, "\n  - ")
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var errReadOnlyTx = errors.New("unable to write in a read-only transaction")
+
+// memoryBackend stores the records in memory. The records are lost when Beacon
+// stops so it is meant for tests and for trying Beacon out.
+type memoryBackend struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryStore returns a new empty store that keeps the records in memory.
+func NewMemoryStore() Store {
+	return &store{backend: newMemoryBackend()}
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{buckets: make(map[string]map[string][]byte)}
+}
+
+func (b *memoryBackend) view(fn func(tx tx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return fn(&memoryTx{buckets: b.buckets})
+}
+
+// update runs the function on a copy of the buckets. The copy replaces the
+// buckets only if the function succeeds.
+func (b *memoryBackend) update(fn func(tx tx) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	memTx := memoryTx{
+		buckets:  maps.Clone(b.buckets),
+		writable: true,
+		copied:   make(map[string]bool),
+	}
+
+	if err := fn(&memTx); err != nil {
+		return err
+	}
+
+	b.buckets = memTx.buckets
+
+	return nil
+}
+
+func (b *memoryBackend) close() error {
+	return nil
+}
+
+type memoryTx struct {
+	buckets  map[string]map[string][]byte
+	writable bool
+
+	// copied records the buckets that were copied in this transaction
+	// so that they can be changed without changing the committed buckets.
+	copied map[string]bool
+}
+
+func (t *memoryTx) bucketExists(bucket string) (bool, error) {
+	_, ok := t.buckets[bucket]
+
+	return ok, nil
+}
+
+func (t *memoryTx) get(bucket, key string) ([]byte, error) {
+	return t.buckets[bucket][key], nil
+}
+
+func (t *memoryTx) forEach(bucket, prefix string, fn func(key string, value []byte) error) error {
+	records := t.buckets[bucket]
+
+	for _, key := range slices.Sorted(maps.Keys(records)) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if err := fn(key, records[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *memoryTx) put(bucket, key string, value []byte) error {
+	records, err := t.writableBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	records[key] = slices.Clone(value)
+
+	return nil
+}
+
+func (t *memoryTx) delete(bucket, key string) error {
+	if _, ok := t.buckets[bucket]; !ok {
+		return nil
+	}
+
+	records, err := t.writableBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	delete(records, key)
+
+	return nil
+}
+
+func (t *memoryTx) deleteBucket(bucket string) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+
+	delete(t.buckets, bucket)
+
+	return nil
+}
+
+// writableBucket returns the bucket's copy for this transaction and creates
+// the bucket if it does not exist.
+func (t *memoryTx) writableBucket(bucket string) (map[string][]byte, error) {
+	if !t.writable {
+		return nil, errReadOnlyTx
+	}
+
+	records, ok := t.buckets[bucket]
+
+	switch {
+	case !ok:
+		records = make(map[string][]byte)
+	case !t.copied[bucket]:
+		records = maps.Clone(records)
+	default:
+		return records, nil
+	}
+
+	t.buckets[bucket] = records
+	t.copied[bucket] = true
+
+	return records, nil
+}
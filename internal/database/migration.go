@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	metaBucketName   string = "meta"
+	schemaVersionKey string = "schema_version"
+)
+
+// Migration is a change to the database's schema. The migration runs inside a single
+// bolt transaction so that a failed migration leaves the database unchanged.
+type Migration struct {
+	Version     int
+	Description string
+	migrate     func(tx *bolt.Tx) error
+}
+
+// migrations are all of the migrations in the order that they run. The version of
+// each migration is the schema version of the database after it runs. New migrations
+// are appended to the end of the list and existing migrations must not be changed.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Record the schema version in the meta bucket",
+		migrate: func(*bolt.Tx) error {
+			return nil
+		},
+	},
+}
+
+// LatestSchemaVersion returns the schema version of the database after all of the
+// migrations have run.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the schema version recorded in the meta bucket.
+// The schema version of a database without a meta bucket is 0.
+func SchemaVersion(boltdb *bolt.DB) (int, error) {
+	var version int
+
+	if err := boltdb.View(func(tx *bolt.Tx) error {
+		var err error
+
+		version, err = schemaVersion(tx)
+
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("error retrieving the schema version from the database: %w", err)
+	}
+
+	return version, nil
+}
+
+// PendingMigrations returns the migrations that have not yet run on the database.
+func PendingMigrations(boltdb *bolt.DB) ([]Migration, error) {
+	version, err := SchemaVersion(boltdb)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > LatestSchemaVersion() {
+		return nil, UnsupportedSchemaVersionError{version: version, latest: LatestSchemaVersion()}
+	}
+
+	pending := make([]Migration, 0)
+
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate runs the pending migrations in order and returns the migrations that ran.
+// The database is backed up to a file next to the database before each migration runs.
+// The backups are skipped for a new database that does not contain any data.
+func Migrate(boltdb *bolt.DB) ([]Migration, error) {
+	pending, err := PendingMigrations(boltdb)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]Migration, 0, len(pending))
+
+	for _, migration := range pending {
+		empty, err := isEmpty(boltdb)
+		if err != nil {
+			return applied, err
+		}
+
+		if !empty {
+			if _, err := backupBeforeMigration(boltdb, migration.Version); err != nil {
+				return applied, err
+			}
+		}
+
+		if err := boltdb.Update(func(tx *bolt.Tx) error {
+			if err := migration.migrate(tx); err != nil {
+				return err
+			}
+
+			return setSchemaVersion(tx, migration.Version)
+		}); err != nil {
+			return applied, MigrationError{version: migration.Version, err: err}
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// backupBeforeMigration copies the database to a backup file before the migration
+// to the given version runs and returns the path to the backup file.
+func backupBeforeMigration(boltdb *bolt.DB, version int) (string, error) {
+	path := fmt.Sprintf(
+		"%s.pre-migration-%d.%s.bak",
+		boltdb.Path(),
+		version,
+		time.Now().UTC().Format("20060102T150405Z"),
+	)
+
+	if err := boltdb.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0o600)
+	}); err != nil {
+		return "", fmt.Errorf("error backing up the database to %s: %w", path, err)
+	}
+
+	return path, nil
+}
+
+func isEmpty(boltdb *bolt.DB) (bool, error) {
+	empty := true
+
+	if err := boltdb.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func([]byte, *bolt.Bucket) error {
+			empty = false
+
+			return nil
+		})
+	}); err != nil {
+		return false, fmt.Errorf("error checking if the database is empty: %w", err)
+	}
+
+	return empty, nil
+}
+
+func schemaVersion(tx *bolt.Tx) (int, error) {
+	bucket := tx.Bucket([]byte(metaBucketName))
+	if bucket == nil {
+		return 0, nil
+	}
+
+	data := bucket.Get([]byte(schemaVersionKey))
+	if data == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing the schema version %q: %w", string(data), err)
+	}
+
+	return version, nil
+}
+
+func setSchemaVersion(tx *bolt.Tx, version int) error {
+	bucket, err := tx.CreateBucketIfNotExists([]byte(metaBucketName))
+	if err != nil {
+		return fmt.Errorf(
+			"error creating the bucket %q: %w",
+			metaBucketName,
+			err,
+		)
+	}
+
+	if err := bucket.Put([]byte(schemaVersionKey), []byte(strconv.Itoa(version))); err != nil {
+		return fmt.Errorf(
+			"error saving the schema version in the %s bucket: %w",
+			metaBucketName,
+			err,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	bolt "go.etcd.io/bbolt"
+)
+
+func testMigrations(boltdb *bolt.DB, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		pending, err := database.PendingMigrations(boltdb)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to get the pending migrations: %v",
+				testName,
+				err,
+			)
+		}
+
+		if len(pending) != database.LatestSchemaVersion() {
+			t.Fatalf(
+				"FAILED test %s: Unexpected number of pending migrations for the unversioned database.\nwant: %d\ngot: %d",
+				testName,
+				database.LatestSchemaVersion(),
+				len(pending),
+			)
+		}
+
+		applied, err := database.Migrate(boltdb)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to migrate the database: %v",
+				testName,
+				err,
+			)
+		}
+
+		if len(applied) != len(pending) {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of migrations applied.\nwant: %d\ngot: %d",
+				testName,
+				len(pending),
+				len(applied),
+			)
+		}
+
+		version, err := database.SchemaVersion(boltdb)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to get the schema version: %v",
+				testName,
+				err,
+			)
+		}
+
+		if version != database.LatestSchemaVersion() {
+			t.Errorf(
+				"FAILED test %s: Unexpected schema version after the migrations.\nwant: %d\ngot: %d",
+				testName,
+				database.LatestSchemaVersion(),
+				version,
+			)
+		} else {
+			t.Logf("Expected schema version after the migrations.\ngot: %d", version)
+		}
+
+		// The database was backed up before each migration.
+		backups, err := filepath.Glob(boltdb.Path() + ".pre-migration-*.bak")
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to find the backups: %v",
+				testName,
+				err,
+			)
+		}
+
+		if len(backups) != len(applied) {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of backups created.\nwant: %d\ngot: %d (%v)",
+				testName,
+				len(applied),
+				len(backups),
+				backups,
+			)
+		} else {
+			t.Logf("Expected backups created.\ngot: %v", backups)
+		}
+
+		applied, err = database.Migrate(boltdb)
+		if err != nil || len(applied) != 0 {
+			t.Errorf(
+				"FAILED test %s: Migrations ran on the migrated database.\ngot: %d\nerror: %v",
+				testName,
+				len(applied),
+				err,
+			)
+		} else {
+			t.Log("No migrations ran on the migrated database.")
+		}
+	}
+}
+
+func TestMigrateNewerSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	boltdb, err := database.Open(filepath.Join(t.TempDir(), "data", "beacon.db"))
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to open the database: %v", t.Name(), err)
+	}
+
+	defer boltdb.Close()
+
+	newerVersion := database.LatestSchemaVersion() + 1
+
+	if err := boltdb.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("meta"))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte("schema_version"), []byte(strconv.Itoa(newerVersion)))
+	}); err != nil {
+		t.Fatalf("FAILED test %s: Unable to set the schema version: %v", t.Name(), err)
+	}
+
+	_, err = database.Migrate(boltdb)
+
+	var wantErr database.UnsupportedSchemaVersionError
+
+	if !errors.As(err, &wantErr) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error migrating a database from a newer version of Beacon.\nwant: %T\ngot: %v",
+			t.Name(),
+			wantErr,
+			err,
+		)
+	} else {
+		t.Logf("Expected error received.\ngot: %v", err)
+	}
+}
@@ -5,12 +5,8 @@
 package database
 
 import (
-	"bytes"
 	"fmt"
 	"time"
-
-	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
-	bolt "go.etcd.io/bbolt"
 )
 
 const (
@@ -18,113 +14,115 @@ const (
 	maxTokenVersion    int    = 9223372036854775807
 )
 
-func getBucketName() []byte {
-	return []byte(profilesBucketName)
+type Profile struct {
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	TokenVersion   int                `json:"token_version"`
+	HashedPassword string             `json:"hashed_password"`
+	Information    ProfileInformation `json:"information"`
 }
 
-type Profile struct {
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	TokenVersion   int
-	HashedPassword string
-	Information    ProfileInformation
+func (Profile) recordFormat() recordFormat {
+	return recordFormat{recordType: "profile"}
 }
 
 type ProfileInformation struct {
-	Name     string
-	URL      string
-	PhotoURL string
-	Email    string
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	PhotoURL string `json:"photo_url"`
+	Email    string `json:"email"`
 }
 
 // CreateProfile creates a new profile in the database.
-func CreateProfile(boltdb *bolt.DB, profileID string, profile Profile) error {
-	profileExists, err := ProfileExists(boltdb, profileID)
-	if err != nil {
-		return fmt.Errorf("error checking if the profile already exists in the database: %w", err)
-	}
+func (s *store) CreateProfile(profileID string, profile Profile) error {
+	if err := s.update(func(tx tx) error {
+		if err := checkProfilesBucket(tx); err != nil {
+			return err
+		}
 
-	if profileExists {
-		return ProfileAlreadyExistError{profileID: profileID}
-	}
+		_, profileExists, err := getRecord[Profile](tx, profilesBucketName, profileID)
+		if err != nil {
+			return err
+		}
 
-	timestamp := time.Now()
-	profile.CreatedAt = timestamp
-	profile.UpdatedAt = timestamp
+		if profileExists {
+			return ProfileAlreadyExistError{profileID: profileID}
+		}
 
-	profile.TokenVersion = 0
+		return putRecord(tx, profilesBucketName, profileID, newProfile(profile))
+	}); err != nil {
+		return fmt.Errorf("error creating the profile in the database: %w", err)
+	}
 
-	return saveProfile(boltdb, profileID, profile)
+	return nil
 }
 
 // UpdateProfileInformation updates an existing profile's information.
-func UpdateProfileInformation(boltdb *bolt.DB, profileID string, newProfileInfo ProfileInformation) error {
-	profile, err := getProfile(boltdb, profileID)
-	if err != nil {
-		return fmt.Errorf("error retrieving profile from the database: %w", err)
-	}
+func (s *store) UpdateProfileInformation(profileID string, newProfileInfo ProfileInformation) error {
+	return s.updateProfile(profileID, func(profile *Profile) {
+		profile.Information = newProfileInfo
+		profile.UpdatedAt = time.Now()
+	})
+}
 
-	profile.Information = newProfileInfo
-	profile.UpdatedAt = time.Now()
+// UpdateHashedPassword updates the profile's hashed password.
+func (s *store) UpdateHashedPassword(profileID string, newHashedPassword string) error {
+	return s.updateProfile(profileID, func(profile *Profile) {
+		profile.HashedPassword = newHashedPassword
+		profile.UpdatedAt = time.Now()
+	})
+}
 
-	if err := saveProfile(boltdb, profileID, profile); err != nil {
-		return fmt.Errorf("error saving the updated profile to the database: %w", err)
-	}
+// ProfileExists checks if a profile exists for a given website.
+func (s *store) ProfileExists(profileID string) (bool, error) {
+	profileExists := false
 
-	return nil
-}
+	if err := s.view(func(tx tx) error {
+		if err := checkProfilesBucket(tx); err != nil {
+			return err
+		}
 
-// UpdateHashedPassword updates the profile's hashed password.
-func UpdateHashedPassword(boltdb *bolt.DB, profileID string, newHashedPassword string) error {
-	profile, err := getProfile(boltdb, profileID)
-	if err != nil {
-		return fmt.Errorf("error retrieving profile from the database: %w", err)
-	}
+		var err error
 
-	profile.HashedPassword = newHashedPassword
-	profile.UpdatedAt = time.Now()
+		_, profileExists, err = getRecord[Profile](tx, profilesBucketName, profileID)
 
-	if err := saveProfile(boltdb, profileID, profile); err != nil {
-		return fmt.Errorf("error saving the updated profile to the database: %w", err)
+		return err
+	}); err != nil {
+		return false, fmt.Errorf("error checking the existence of the profile in the bucket: %w", err)
 	}
 
-	return nil
+	return profileExists, nil
 }
 
-// ProfileExists checks if a profile exists for a given website.
-func ProfileExists(boltdb *bolt.DB, profileID string) (bool, error) {
-	profileExists := false
-	bucketName := getBucketName()
-	key := []byte(profileID)
+// GetProfileIDs returns the IDs of all of the profiles.
+func (s *store) GetProfileIDs() ([]string, error) {
+	profileIDs := make([]string, 0)
 
-	if err := boltdb.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(bucketName)
-
-		if bucket == nil {
-			return BucketNotExistError{bucket: string(bucketName)}
+	if err := s.view(func(tx tx) error {
+		if err := checkProfilesBucket(tx); err != nil {
+			return err
 		}
 
-		profile := bucket.Get(key)
-		if profile != nil {
-			profileExists = true
-		}
+		return tx.forEach(profilesBucketName, "", func(key string, _ []byte) error {
+			profileIDs = append(profileIDs, key)
 
-		return nil
+			return nil
+		})
 	}); err != nil {
-		return false, fmt.Errorf("error checking the existence of the profile in the bucket: %w", err)
+		return nil, fmt.Errorf("error retrieving the profile IDs from the database: %w", err)
 	}
 
-	return profileExists, nil
+	return profileIDs, nil
 }
 
 // GetProfile returns the profile for a given profile ID.
-func GetProfile(boltdb *bolt.DB, profileID string) (Profile, error) {
-	return getProfile(boltdb, profileID)
+func (s *store) GetProfile(profileID string) (Profile, error) {
+	return s.getProfile(profileID)
 }
 
 // GetProfileInformation returns the profile information for a given profile ID.
-func GetProfileInformation(boltdb *bolt.DB, profileID string) (ProfileInformation, error) {
-	profile, err := getProfile(boltdb, profileID)
+func (s *store) GetProfileInformation(profileID string) (ProfileInformation, error) {
+	profile, err := s.getProfile(profileID)
 	if err != nil {
 		return ProfileInformation{}, fmt.Errorf("error getting profile: %w", err)
 	}
@@ -133,8 +131,8 @@ func GetProfileInformation(boltdb *bolt.DB, profileID string) (ProfileInformatio
 }
 
 // GetProfileTokenVersion returns the token version for a given profile ID.
-func GetProfileTokenVersion(boltdb *bolt.DB, profileID string) (int, error) {
-	profile, err := getProfile(boltdb, profileID)
+func (s *store) GetProfileTokenVersion(profileID string) (int, error) {
+	profile, err := s.getProfile(profileID)
 	if err != nil {
 		return 0, fmt.Errorf("error getting profile: %w", err)
 	}
@@ -142,48 +140,27 @@ func GetProfileTokenVersion(boltdb *bolt.DB, profileID string) (int, error) {
 	return profile.TokenVersion, nil
 }
 
-func IncrementTokenVersion(boltdb *bolt.DB, profileID string) error {
-	profile, err := getProfile(boltdb, profileID)
-	if err != nil {
-		return fmt.Errorf("error getting profile: %w", err)
-	}
-
-	if profile.TokenVersion >= maxTokenVersion {
-		profile.TokenVersion = 0
-	} else {
-		profile.TokenVersion += 1
-	}
-
-	if err := saveProfile(boltdb, profileID, profile); err != nil {
-		return fmt.Errorf("error saving the updated profile to the database: %w", err)
-	}
-
-	return nil
+// IncrementTokenVersion increments the profile's token version so that the
+// tokens issued with the previous version are no longer valid.
+func (s *store) IncrementTokenVersion(profileID string) error {
+	return s.updateProfile(profileID, func(profile *Profile) {
+		if profile.TokenVersion >= maxTokenVersion {
+			profile.TokenVersion = 0
+		} else {
+			profile.TokenVersion += 1
+		}
+	})
 }
 
-func getProfile(boltdb *bolt.DB, profileID string) (Profile, error) {
-	bucketName := getBucketName()
-	key := []byte(profileID)
-
+func (s *store) getProfile(profileID string) (Profile, error) {
 	var profile Profile
 
-	if err := boltdb.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(bucketName)
-
-		if bucket == nil {
-			return BucketNotExistError{bucket: string(bucketName)}
-		}
-
-		data := bucket.Get(key)
-		if data == nil {
-			return ProfileNotExistError{profileID: profileID}
-		}
+	if err := s.view(func(tx tx) error {
+		var err error
 
-		if err := utilities.GobDecode(bytes.NewBuffer(data), &profile); err != nil {
-			return fmt.Errorf("error decoding the profile: %w", err)
-		}
+		profile, err = getProfile(tx, profileID)
 
-		return nil
+		return err
 	}); err != nil {
 		return Profile{}, fmt.Errorf("error retrieving the profile from the database: %w", err)
 	}
@@ -191,39 +168,63 @@ func getProfile(boltdb *bolt.DB, profileID string) (Profile, error) {
 	return profile, nil
 }
 
-func saveProfile(boltdb *bolt.DB, profileID string, profile Profile) error {
-	bucketName := getBucketName()
+// updateProfile applies the change to the profile and saves the profile in a
+// single transaction.
+func (s *store) updateProfile(profileID string, change func(profile *Profile)) error {
+	if err := s.update(func(tx tx) error {
+		profile, err := getProfile(tx, profileID)
+		if err != nil {
+			return err
+		}
 
-	err := boltdb.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(bucketName)
+		change(&profile)
 
-		if bucket == nil {
-			return BucketNotExistError{bucket: string(bucketName)}
-		}
+		return putRecord(tx, profilesBucketName, profileID, profile)
+	}); err != nil {
+		return fmt.Errorf("error updating the profile in the database: %w", err)
+	}
+
+	return nil
+}
 
-		key := []byte(profileID)
+func getProfile(tx tx, profileID string) (Profile, error) {
+	if err := checkProfilesBucket(tx); err != nil {
+		return Profile{}, err
+	}
 
-		profileBytes, err := utilities.GobEncode(profile)
-		if err != nil {
-			return fmt.Errorf(
-				"error encoding the user data: %w",
-				err,
-			)
-		}
+	profile, found, err := getRecord[Profile](tx, profilesBucketName, profileID)
+	if err != nil {
+		return Profile{}, err
+	}
 
-		if err := bucket.Put(key, profileBytes); err != nil {
-			return fmt.Errorf(
-				"error updating the user in the %s bucket: %w",
-				string(bucketName),
-				err,
-			)
-		}
+	if !found {
+		return Profile{}, ProfileNotExistError{profileID: profileID}
+	}
 
-		return nil
-	})
+	return profile, nil
+}
+
+func checkProfilesBucket(tx tx) error {
+	exists, err := tx.bucketExists(profilesBucketName)
 	if err != nil {
-		return fmt.Errorf("error updating the user in the database: %w", err)
+		return fmt.Errorf("error checking if the %s bucket exists: %w", profilesBucketName, err)
+	}
+
+	if !exists {
+		return BucketNotExistError{bucket: profilesBucketName}
 	}
 
 	return nil
 }
+
+// newProfile returns the profile with its timestamps set to now and its token
+// version reset, ready to be created.
+func newProfile(profile Profile) Profile {
+	timestamp := time.Now()
+	profile.CreatedAt = timestamp
+	profile.UpdatedAt = timestamp
+
+	profile.TokenVersion = 0
+
+	return profile
+}
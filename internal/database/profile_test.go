@@ -6,16 +6,16 @@ package database_test
 
 import (
 	"reflect"
+	"slices"
 	"testing"
 	"time"
 
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
-	bolt "go.etcd.io/bbolt"
 )
 
-func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
+func testProfile(store database.Store, testName string) func(t *testing.T) {
 	return func(t *testing.T) {
 		t.Logf("Creating the profile in the database.")
 
@@ -53,7 +53,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 			},
 		}
 
-		if err = database.CreateProfile(boltdb, profileID, profile); err != nil {
+		if err = store.CreateProfile(profileID, profile); err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after adding the profile to the database: %v",
 				testName,
@@ -65,7 +65,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 
 		t.Log("Checking that the profile exists in the database.")
 
-		profileExists, err := database.ProfileExists(boltdb, profileID)
+		profileExists, err := store.ProfileExists(profileID)
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after checking if the profile exists or not: %v",
@@ -84,7 +84,27 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 			t.Log("The profile is present in the database.")
 		}
 
-		gotProfile, err := database.GetProfile(boltdb, profileID)
+		profileIDs, err := store.GetProfileIDs()
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error after retrieving the profile IDs: %v",
+				testName,
+				err,
+			)
+		}
+
+		if !slices.Contains(profileIDs, profileID) {
+			t.Errorf(
+				"FAILED test %s: The profile ID %q is not in the list of profile IDs.\ngot: %v",
+				testName,
+				profileID,
+				profileIDs,
+			)
+		} else {
+			t.Log("The profile ID is in the list of profile IDs.")
+		}
+
+		gotProfile, err := store.GetProfile(profileID)
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after retrieving the profile from the database: %v",
@@ -111,7 +131,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 
 		t.Log("Retrieving the profile's information from the database.")
 
-		gotProfileInfo, err := database.GetProfileInformation(boltdb, profileID)
+		gotProfileInfo, err := store.GetProfileInformation(profileID)
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Unable to get the profile information from the database: %v",
@@ -145,7 +165,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 			Email:    "hi@billjones.example.net",
 		}
 
-		if err := database.UpdateProfileInformation(boltdb, profileID, newProfileInformation); err != nil {
+		if err := store.UpdateProfileInformation(profileID, newProfileInformation); err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after updating the profile information in the database: %v",
 				testName,
@@ -153,7 +173,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 			)
 		}
 
-		gotProfile, err = database.GetProfile(boltdb, profileID)
+		gotProfile, err = store.GetProfile(profileID)
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after retrieving the profile from the database: %v",
@@ -191,7 +211,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 			)
 		}
 
-		if err := database.UpdateHashedPassword(boltdb, profileID, newHashedPassword); err != nil {
+		if err := store.UpdateHashedPassword(profileID, newHashedPassword); err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after updating the profile's hashed password: %v",
 				testName,
@@ -199,7 +219,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 			)
 		}
 
-		gotProfile, err = database.GetProfile(boltdb, profileID)
+		gotProfile, err = store.GetProfile(profileID)
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after retrieving the profile from the database: %v",
@@ -226,7 +246,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 
 		t.Log("Incrementing the token's profile version by one.")
 
-		if err := database.IncrementTokenVersion(boltdb, profileID); err != nil {
+		if err := store.IncrementTokenVersion(profileID); err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after attempting to increment the profile's token version.\ngot: %q",
 				testName,
@@ -236,7 +256,7 @@ func testProfile(boltdb *bolt.DB, testName string) func(t *testing.T) {
 			t.Log("Successfully incremented the profile's token version.")
 		}
 
-		gotTokenVersion, err := database.GetProfileTokenVersion(boltdb, profileID)
+		gotTokenVersion, err := store.GetProfileTokenVersion(profileID)
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Unable to get the profile's token version from the database: %v",
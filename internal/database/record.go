@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+var ErrUnexpectedRecordType = errors.New("unexpected record type")
+
+type UnsupportedRecordVersionError struct {
+	recordType string
+	version    int
+}
+
+func (e UnsupportedRecordVersionError) Error() string {
+	return fmt.Sprintf(
+		"version %d of the %s record is not supported by this version of Beacon",
+		e.version,
+		e.recordType,
+	)
+}
+
+// persisted is implemented by every type that is stored as a record.
+type persisted interface {
+	recordFormat() recordFormat
+}
+
+// recordFormat describes how a type is stored as a record.
+//
+// When the payload of a type changes in a way that older records cannot be decoded
+// into the new type (e.g. a field is renamed or its meaning changes), an upgrade is
+// appended to the list of upgrades. Each upgrade converts the payload of one version
+// to the next version so the current version is one more than the number of upgrades.
+// Existing upgrades must not be changed.
+type recordFormat struct {
+	recordType string
+	upgrades   []func(payload json.RawMessage) (json.RawMessage, error)
+}
+
+func (f recordFormat) version() int {
+	return len(f.upgrades) + 1
+}
+
+// recordEnvelope is how every record is stored. The payload is the JSON
+// encoded record in the format of the version.
+type recordEnvelope struct {
+	Type    string          `json:"type"`
+	Version int             `json:"version"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func encodeRecord(record persisted) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the payload: %w", err)
+	}
+
+	format := record.recordFormat()
+
+	data, err := json.Marshal(recordEnvelope{
+		Type:    format.recordType,
+		Version: format.version(),
+		Payload: payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the envelope: %w", err)
+	}
+
+	return data, nil
+}
+
+// decodeRecord decodes the record from its envelope and upgrades the payload if it
+// was saved by an older version. The records saved with gob before the envelope was
+// introduced are decoded with gob; they are saved in an envelope the next time they
+// are saved.
+func decodeRecord[T persisted](data []byte) (T, error) {
+	var record T
+
+	if !isEnvelope(data) {
+		if err := utilities.GobDecode(bytes.NewBuffer(data), &record); err != nil {
+			return record, fmt.Errorf("error decoding the gob encoded record: %w", err)
+		}
+
+		return record, nil
+	}
+
+	var envelope recordEnvelope
+
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return record, fmt.Errorf("error decoding the envelope: %w", err)
+	}
+
+	format := record.recordFormat()
+
+	if envelope.Type != format.recordType {
+		return record, fmt.Errorf("%w: want %s, got %s", ErrUnexpectedRecordType, format.recordType, envelope.Type)
+	}
+
+	if envelope.Version < 1 || envelope.Version > format.version() {
+		return record, UnsupportedRecordVersionError{recordType: envelope.Type, version: envelope.Version}
+	}
+
+	payload := envelope.Payload
+
+	for version := envelope.Version; version < format.version(); version++ {
+		var err error
+
+		payload, err = format.upgrades[version-1](payload)
+		if err != nil {
+			return record, fmt.Errorf(
+				"error upgrading the %s record from version %d to %d: %w",
+				format.recordType,
+				version,
+				version+1,
+				err,
+			)
+		}
+	}
+
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, fmt.Errorf("error decoding the payload: %w", err)
+	}
+
+	return record, nil
+}
+
+// isEnvelope returns true if the data is a JSON object. A gob stream is binary so
+// it is never valid JSON.
+func isEnvelope(data []byte) bool {
+	return len(data) > 0 && data[0] == '{' && json.Valid(data)
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+// testRecord is at version 3. Version 2 renamed the 'nick' field to 'name' and
+// version 3 made the name lower case.
+type testRecord struct {
+	Name string `json:"name"`
+}
+
+func (testRecord) recordFormat() recordFormat {
+	return recordFormat{
+		recordType: "test_record",
+		upgrades: []func(json.RawMessage) (json.RawMessage, error){
+			func(payload json.RawMessage) (json.RawMessage, error) {
+				var v1 struct {
+					Nick string `json:"nick"`
+				}
+
+				if err := json.Unmarshal(payload, &v1); err != nil {
+					return nil, err
+				}
+
+				return json.Marshal(map[string]string{"name": v1.Nick})
+			},
+			func(payload json.RawMessage) (json.RawMessage, error) {
+				var v2 testRecord
+
+				if err := json.Unmarshal(payload, &v2); err != nil {
+					return nil, err
+				}
+
+				v2.Name = strings.ToLower(v2.Name)
+
+				return json.Marshal(v2)
+			},
+		},
+	}
+}
+
+func TestRecordEncoding(t *testing.T) {
+	t.Parallel()
+
+	profile := Profile{
+		CreatedAt:      time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC),
+		TokenVersion:   2,
+		HashedPassword: "hashed-password",
+		Information: ProfileInformation{
+			Name:  "Pip Hubert",
+			Email: "pip@pippins.example.me",
+		},
+	}
+
+	t.Run("Envelope", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := encodeRecord(profile)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to encode the record: %v", t.Name(), err)
+		}
+
+		var envelope map[string]any
+
+		if err := json.Unmarshal(data, &envelope); err != nil ||
+			envelope["type"] != "profile" ||
+			envelope["version"] != float64(1) {
+			t.Errorf("FAILED test %s: Unexpected envelope.\ngot: %s", t.Name(), data)
+		} else {
+			t.Logf("Expected envelope.\ngot: %s", data)
+		}
+
+		assertDecodedProfile(t, data, profile)
+	})
+
+	t.Run("Gob", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := utilities.GobEncode(profile)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to encode the record with gob: %v", t.Name(), err)
+		}
+
+		assertDecodedProfile(t, data, profile)
+	})
+
+	t.Run("Upgrade", func(t *testing.T) {
+		t.Parallel()
+
+		record, err := decodeRecord[testRecord]([]byte(`{"type": "test_record", "version": 1, "payload": {"nick": "PIP"}}`))
+		if err != nil || record.Name != "pip" {
+			t.Errorf(
+				"FAILED test %s: Unexpected record decoded from version 1.\ngot: %+v\nerror: %v",
+				t.Name(),
+				record,
+				err,
+			)
+		} else {
+			t.Logf("Expected record decoded from version 1.\ngot: %+v", record)
+		}
+	})
+
+	t.Run("Gob cache entry", func(t *testing.T) {
+		t.Parallel()
+
+		// The value of version 1 is the base64 encoding of a gob stream.
+		data := []byte(`{"type": "cache_entry", "version": 1, "payload": {"key": "state", "value": "I/+BAwEB", "expires_at": "2099-01-01T00:00:00Z"}}`)
+
+		entry, err := decodeRecord[CacheEntry](data)
+		if err != nil || entry.Key != "state" || !entry.ExpiresAt.IsZero() {
+			t.Errorf(
+				"FAILED test %s: The cache entry of version 1 was not expired.\ngot: %+v\nerror: %v",
+				t.Name(),
+				entry,
+				err,
+			)
+		} else {
+			t.Log("The cache entry of version 1 was expired.")
+		}
+	})
+
+	errorCases := []struct {
+		name      string
+		data      string
+		wantError func(err error) bool
+	}{
+		{
+			name: "Newer version",
+			data: `{"type": "test_record", "version": 4, "payload": {}}`,
+			wantError: func(err error) bool {
+				return errors.As(err, new(UnsupportedRecordVersionError))
+			},
+		},
+		{
+			name: "Unexpected type",
+			data: `{"type": "profile", "version": 1, "payload": {}}`,
+			wantError: func(err error) bool {
+				return errors.Is(err, ErrUnexpectedRecordType)
+			},
+		},
+	}
+
+	for _, errorCase := range errorCases {
+		t.Run(errorCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := decodeRecord[testRecord]([]byte(errorCase.data)); !errorCase.wantError(err) {
+				t.Errorf("FAILED test %s: Unexpected error decoding the record.\ngot: %v", t.Name(), err)
+			} else {
+				t.Logf("Expected error received.\ngot: %v", err)
+			}
+		})
+	}
+}
+
+func assertDecodedProfile(t *testing.T, data []byte, want Profile) {
+	t.Helper()
+
+	got, err := decodeRecord[Profile](data)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to decode the record: %v", t.Name(), err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(
+			"FAILED test %s: Unexpected profile decoded.\nwant: %+v\ngot: %+v",
+			t.Name(),
+			want,
+			got,
+		)
+	} else {
+		t.Logf("Expected profile decoded.\ngot: %+v", got)
+	}
+}
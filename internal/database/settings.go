@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import "fmt"
+
+const (
+	settingsBucketName string = "settings"
+	clientPolicyKey    string = "client_policy"
+)
+
+// ClientPolicy is the client policy that is managed from the settings page.
+// It is combined with the client policy from the configuration.
+type ClientPolicy struct {
+	Allow []string           `json:"allow"`
+	Deny  []string           `json:"deny"`
+	Rules []ClientPolicyRule `json:"rules"`
+}
+
+func (ClientPolicy) recordFormat() recordFormat {
+	return recordFormat{recordType: "client_policy"}
+}
+
+// ClientPolicyRule is the policy for a single client. The token lifetime is in seconds.
+type ClientPolicyRule struct {
+	ClientID      string   `json:"client_id"`
+	MaxScopes     []string `json:"max_scopes"`
+	TokenLifetime int      `json:"token_lifetime"`
+	Trusted       bool     `json:"trusted"`
+}
+
+// GetClientPolicy returns the client policy managed from the settings page.
+// An empty policy is returned if the policy has not been saved yet.
+func (s *store) GetClientPolicy() (ClientPolicy, error) {
+	var policy ClientPolicy
+
+	if err := s.view(func(tx tx) error {
+		var err error
+
+		policy, _, err = getRecord[ClientPolicy](tx, settingsBucketName, clientPolicyKey)
+
+		return err
+	}); err != nil {
+		return ClientPolicy{}, fmt.Errorf(
+			"error retrieving the client policy from the database: %w",
+			err,
+		)
+	}
+
+	return policy, nil
+}
+
+// SaveClientPolicy saves the client policy managed from the settings page.
+func (s *store) SaveClientPolicy(policy ClientPolicy) error {
+	if err := s.update(func(tx tx) error {
+		return putRecord(tx, settingsBucketName, clientPolicyKey, policy)
+	}); err != nil {
+		return fmt.Errorf("error saving the client policy to the database: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testClientPolicy(store database.Store, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		policy, err := store.GetClientPolicy()
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error getting the client policy: %v",
+				testName,
+				err,
+			)
+		}
+
+		if !reflect.DeepEqual(policy, database.ClientPolicy{}) {
+			t.Fatalf(
+				"FAILED test %s: The client policy was not empty before it was saved.\ngot: %+v",
+				testName,
+				policy,
+			)
+		}
+
+		want := database.ClientPolicy{
+			Allow: []string{"*.example.org"},
+			Deny:  []string{"untrusted.example.org"},
+			Rules: []database.ClientPolicyRule{
+				{
+					ClientID:      "https://app.example.org/",
+					MaxScopes:     []string{"profile"},
+					TokenLifetime: 3600,
+					Trusted:       true,
+				},
+			},
+		}
+
+		if err := store.SaveClientPolicy(want); err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error saving the client policy: %v",
+				testName,
+				err,
+			)
+		}
+
+		got, err := store.GetClientPolicy()
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error getting the client policy: %v",
+				testName,
+				err,
+			)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf(
+				"FAILED test %s: Unexpected client policy retrieved from the database.\nwant: %+v\ngot: %+v",
+				testName,
+				want,
+				got,
+			)
+		} else {
+			t.Logf("Expected client policy retrieved from the database.\ngot: %+v", got)
+		}
+	}
+}
+
+func testAccessTokens(store database.Store, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		tokenHash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+		if _, found, err := store.GetAccessToken(tokenHash); err != nil || found {
+			t.Fatalf(
+				"FAILED test %s: Unexpected result getting an access token that was not saved.\nfound: %t\nerror: %v",
+				testName,
+				found,
+				err,
+			)
+		}
+
+		issuedAt := time.Now().UTC().Truncate(time.Second)
+
+		want := database.AccessToken{
+			ClientID:  "https://app.example.org/",
+			Me:        "https://billjones.example.net/",
+			Scopes:    []string{"profile", "create"},
+			IssuedAt:  issuedAt,
+			ExpiresAt: issuedAt.Add(1 * time.Hour),
+		}
+
+		if err := store.SaveAccessToken(tokenHash, want); err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error saving the access token: %v",
+				testName,
+				err,
+			)
+		}
+
+		got, found, err := store.GetAccessToken(tokenHash)
+		if err != nil || !found {
+			t.Fatalf(
+				"FAILED test %s: Unable to get the access token.\nfound: %t\nerror: %v",
+				testName,
+				found,
+				err,
+			)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf(
+				"FAILED test %s: Unexpected access token retrieved from the database.\nwant: %+v\ngot: %+v",
+				testName,
+				want,
+				got,
+			)
+		} else {
+			t.Logf("Expected access token retrieved from the database.\ngot: %+v", got)
+		}
+	}
+}
+
+func testAccessTokenCleanup(store database.Store, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Second)
+
+		tokens := map[string]database.AccessToken{
+			"code-a-token-1": {ExpiresAt: now.Add(1 * time.Hour), AuthorizationCodeHash: "code-a"},
+			"code-a-token-2": {ExpiresAt: now.Add(1 * time.Hour), AuthorizationCodeHash: "code-a"},
+			"code-b-token":   {ExpiresAt: now.Add(1 * time.Hour), AuthorizationCodeHash: "code-b"},
+			"expired-token":  {ExpiresAt: now.Add(-1 * time.Hour), AuthorizationCodeHash: "code-c"},
+		}
+
+		for tokenHash, token := range tokens {
+			token.ClientID = "https://app.example.org/"
+			token.Me = "https://billjones.example.net/"
+			token.IssuedAt = now.Add(-2 * time.Hour)
+
+			if err := store.SaveAccessToken(tokenHash, token); err != nil {
+				t.Fatalf("FAILED test %s: Received an error saving the access token: %v", testName, err)
+			}
+		}
+
+		// Only the access tokens issued from the authorization code are deleted.
+		count, err := store.DeleteAccessTokensIssuedFromCode("code-a")
+		if err != nil {
+			t.Fatalf("FAILED test %s: Received an error deleting the access tokens issued from the code: %v", testName, err)
+		}
+
+		if _, found, _ := store.GetAccessToken("code-b-token"); count != 2 || !found {
+			t.Errorf(
+				"FAILED test %s: Unexpected access tokens deleted for the authorization code.\nwant: 2 deleted and the other token kept\ngot: %d deleted (other token kept: %t)",
+				testName,
+				count,
+				found,
+			)
+		} else {
+			t.Logf("Expected access tokens deleted for the authorization code.\ngot: %d", count)
+		}
+
+		// Only the expired access tokens are deleted along with their index entries.
+		count, err = store.DeleteExpiredAccessTokens(now)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Received an error deleting the expired access tokens: %v", testName, err)
+		}
+
+		_, expiredFound, _ := store.GetAccessToken("expired-token")
+		_, validFound, _ := store.GetAccessToken("code-b-token")
+
+		if count != 1 || expiredFound || !validFound {
+			t.Errorf(
+				"FAILED test %s: Unexpected result after deleting the expired access tokens.\ngot: %d deleted (expired token found: %t, valid token found: %t)",
+				testName,
+				count,
+				expiredFound,
+				validFound,
+			)
+		} else {
+			t.Logf("Expected access tokens deleted after they expired.\ngot: %d", count)
+		}
+
+		if count, err := store.DeleteAccessTokensIssuedFromCode("code-c"); err != nil || count != 0 {
+			t.Errorf(
+				"FAILED test %s: The index of the expired access token was not deleted.\ngot: %d deleted\nerror: %v",
+				testName,
+				count,
+				err,
+			)
+		} else {
+			t.Log("The index of the expired access token was deleted.")
+		}
+	}
+}
@@ -5,32 +5,46 @@
 package database
 
 import (
+	"errors"
 	"fmt"
-
-	bolt "go.etcd.io/bbolt"
 )
 
 // Setup sets up the database by creating the 'profiles' bucket and
 // writing the first profile to that bucket.
-func Setup(boltdb *bolt.DB, profileID string, profile Profile) error {
-	if err := boltdb.Update(func(tx *bolt.Tx) error {
-		bucket := getBucketName()
-		if _, err := tx.CreateBucket(bucket); err != nil {
-			return fmt.Errorf(
-				"error creating the bucket %q: %w",
-				profilesBucketName,
-				err,
-			)
+func (s *store) Setup(profileID string, profile Profile) error {
+	if err := s.update(func(tx tx) error {
+		exists, err := tx.bucketExists(profilesBucketName)
+		if err != nil {
+			return fmt.Errorf("error checking if the %s bucket exists: %w", profilesBucketName, err)
+		}
+
+		if exists {
+			return ErrAlreadySetUp
 		}
 
-		return nil
+		return putRecord(tx, profilesBucketName, profileID, newProfile(profile))
 	}); err != nil {
-		return fmt.Errorf("error creating a BoltDB bucket: %w", err)
+		return fmt.Errorf("error setting up the database: %w", err)
 	}
 
-	if err := CreateProfile(boltdb, profileID, profile); err != nil {
-		return fmt.Errorf("error creating the profile: %w", err)
+	return nil
+}
+
+// Initialized checks to see if the database is initialized or not.
+// The database is initialized if the 'profiles' bucket exists and that
+// there is at least one profile stored in the bucket.
+func (s *store) Initialized() (bool, error) {
+	initialized := false
+
+	if err := s.view(func(tx tx) error {
+		return tx.forEach(profilesBucketName, "", func(string, []byte) error {
+			initialized = true
+
+			return errStopIteration
+		})
+	}); err != nil && !errors.Is(err, errStopIteration) {
+		return false, fmt.Errorf("error checking if the database is initialized or not: %w", err)
 	}
 
-	return nil
+	return initialized, nil
 }
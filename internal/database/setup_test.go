@@ -5,19 +5,19 @@
 package database_test
 
 import (
+	"errors"
 	"testing"
 
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
-	bolt "go.etcd.io/bbolt"
 )
 
-func testDatabaseSetup(boltdb *bolt.DB) func(t *testing.T) {
+func testDatabaseSetup(store database.Store) func(t *testing.T) {
 	return func(t *testing.T) {
 		t.Log("Ensuring that the database is not yet initialized.")
 
-		initialised, err := database.Initialized(boltdb)
+		initialised, err := store.Initialized()
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after checking whether or not the database is initialized.\ngot: %v",
@@ -64,7 +64,7 @@ func testDatabaseSetup(boltdb *bolt.DB) func(t *testing.T) {
 			},
 		}
 
-		if err := database.Setup(boltdb, profileID, profile); err != nil {
+		if err := store.Setup(profileID, profile); err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after setting up the database.\ngot: %v",
 				t.Name(),
@@ -76,7 +76,7 @@ func testDatabaseSetup(boltdb *bolt.DB) func(t *testing.T) {
 
 		t.Log("Ensuring that the database is indeed initialised.")
 
-		initialised, err = database.Initialized(boltdb)
+		initialised, err = store.Initialized()
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after checking whether or not the database is initialised.\ngot: %q",
@@ -94,7 +94,7 @@ func testDatabaseSetup(boltdb *bolt.DB) func(t *testing.T) {
 			t.Logf("The database appears to be initialized as expected.")
 		}
 
-		gotProfile, err := database.GetProfile(boltdb, profileID)
+		gotProfile, err := store.GetProfile(profileID)
 		if err != nil {
 			t.Fatalf(
 				"FAILED test %s: Received an error after getting the profile from the database:\ngot: %v",
@@ -123,5 +123,16 @@ func testDatabaseSetup(boltdb *bolt.DB) func(t *testing.T) {
 				t.Name(),
 			)
 		}
+
+		if err := store.Setup("https://samwise.example.me/", profile); !errors.Is(err, database.ErrAlreadySetUp) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error setting up the database a second time.\nwant: %v\ngot: %v",
+				t.Name(),
+				database.ErrAlreadySetUp,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received setting up the database a second time.\ngot: %v", err)
+		}
 	}
 }
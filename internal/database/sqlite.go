@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	// Registers the pure Go SQLite driver.
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema stores the records of all of the buckets in a single table.
+// The records are ordered by their keys within each bucket.
+const sqliteSchema string = `CREATE TABLE IF NOT EXISTS records (
+	bucket TEXT NOT NULL,
+	key    TEXT NOT NULL,
+	value  BLOB NOT NULL,
+	PRIMARY KEY (bucket, key)
+) WITHOUT ROWID`
+
+// sqliteBackend stores the records in a SQLite database.
+type sqliteBackend struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens the SQLite database at the given path and returns its store.
+// The database is created if it does not exist.
+func NewSQLiteStore(path string) (Store, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store{backend: sqliteBackend{db: db}}, nil
+}
+
+func openSQLite(path string) (*sql.DB, error) {
+	if err := checkPath(path); err != nil {
+		return nil, err
+	}
+
+	// Create the file first so that SQLite does not create it with
+	// permissions that are too open.
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the database at %q: %w", path, err)
+	}
+
+	_ = file.Close()
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(1000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the database at %q: %w", path, err)
+	}
+
+	// A single connection serialises the transactions so that a write never
+	// fails because the database is busy.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(context.Background(), sqliteSchema); err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("error creating the tables in the database at %q: %w", path, err)
+	}
+
+	return db, nil
+}
+
+func (b sqliteBackend) view(fn func(tx tx) error) error {
+	return b.run(&sql.TxOptions{ReadOnly: true}, fn)
+}
+
+func (b sqliteBackend) update(fn func(tx tx) error) error {
+	return b.run(nil, fn)
+}
+
+func (b sqliteBackend) run(opts *sql.TxOptions, fn func(tx tx) error) error {
+	sqlTx, err := b.db.BeginTx(context.Background(), opts)
+	if err != nil {
+		return fmt.Errorf("error starting the transaction: %w", err)
+	}
+
+	if err := fn(sqliteTx{tx: sqlTx}); err != nil {
+		_ = sqlTx.Rollback()
+
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("error committing the transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (b sqliteBackend) close() error {
+	return b.db.Close()
+}
+
+type sqliteTx struct {
+	tx *sql.Tx
+}
+
+func (t sqliteTx) bucketExists(bucket string) (bool, error) {
+	var exists bool
+
+	if err := t.tx.QueryRowContext(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM records WHERE bucket = ?)`,
+		bucket,
+	).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (t sqliteTx) get(bucket, key string) ([]byte, error) {
+	var value []byte
+
+	err := t.tx.QueryRowContext(
+		context.Background(),
+		`SELECT value FROM records WHERE bucket = ? AND key = ?`,
+		bucket,
+		key,
+	).Scan(&value)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (t sqliteTx) forEach(bucket, prefix string, fn func(key string, value []byte) error) error {
+	type record struct {
+		key   string
+		value []byte
+	}
+
+	rows, err := t.tx.QueryContext(
+		context.Background(),
+		`SELECT key, value FROM records WHERE bucket = ? AND key >= ? ORDER BY key`,
+		bucket,
+		prefix,
+	)
+	if err != nil {
+		return err
+	}
+
+	// The records are read before the function is called so that the function
+	// can run other queries in the transaction.
+	records := make([]record, 0)
+
+	for rows.Next() {
+		var rec record
+
+		if err := rows.Scan(&rec.key, &rec.value); err != nil {
+			_ = rows.Close()
+
+			return err
+		}
+
+		if !strings.HasPrefix(rec.key, prefix) {
+			break
+		}
+
+		records = append(records, rec)
+	}
+
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		if err := fn(rec.key, rec.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t sqliteTx) put(bucket, key string, value []byte) error {
+	_, err := t.tx.ExecContext(
+		context.Background(),
+		`INSERT INTO records (bucket, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value`,
+		bucket,
+		key,
+		value,
+	)
+
+	return err
+}
+
+func (t sqliteTx) delete(bucket, key string) error {
+	_, err := t.tx.ExecContext(
+		context.Background(),
+		`DELETE FROM records WHERE bucket = ? AND key = ?`,
+		bucket,
+		key,
+	)
+
+	return err
+}
+
+func (t sqliteTx) deleteBucket(bucket string) error {
+	_, err := t.tx.ExecContext(
+		context.Background(),
+		`DELETE FROM records WHERE bucket = ?`,
+		bucket,
+	)
+
+	return err
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/encryption"
+)
+
+const (
+	DriverBolt   string = "bolt"
+	DriverSQLite string = "sqlite"
+	DriverMemory string = "memory"
+)
+
+var (
+	ErrUnknownDriver  = errors.New("unknown database driver (the supported drivers are 'bolt', 'sqlite' and 'memory')")
+	ErrAlreadySetUp   = errors.New("the database is already set up")
+	ErrRequiresBoltDB = errors.New("this operation is only supported by the bolt database driver")
+	errStopIteration  = errors.New("stop iteration")
+)
+
+// Store stores the profiles and every other entity that Beacon persists.
+type Store interface {
+	Initialized() (bool, error)
+	Setup(profileID string, profile Profile) error
+
+	CreateProfile(profileID string, profile Profile) error
+	ProfileExists(profileID string) (bool, error)
+	GetProfileIDs() ([]string, error)
+	GetProfile(profileID string) (Profile, error)
+	GetProfileInformation(profileID string) (ProfileInformation, error)
+	GetProfileTokenVersion(profileID string) (int, error)
+	UpdateProfileInformation(profileID string, newProfileInfo ProfileInformation) error
+	UpdateHashedPassword(profileID string, newHashedPassword string) error
+	IncrementTokenVersion(profileID string) error
+
+	GetClientPolicy() (ClientPolicy, error)
+	SaveClientPolicy(policy ClientPolicy) error
+
+	GetAccessToken(tokenHash string) (AccessToken, bool, error)
+	SaveAccessToken(tokenHash string, token AccessToken) error
+	DeleteAccessTokensIssuedFromCode(codeHash string) (int, error)
+	DeleteExpiredAccessTokens(before time.Time) (int, error)
+
+	GetRegisteredClient(clientID string) (RegisteredClient, bool, error)
+	GetRegisteredClients() ([]RegisteredClient, error)
+	SaveRegisteredClient(client RegisteredClient) error
+	DeleteRegisteredClient(clientID string) error
+
+	GetReceivedTokens(subject string) ([]ReceivedToken, error)
+	SaveReceivedToken(token ReceivedToken) error
+	DeleteReceivedToken(subject, resource string) error
+
+	GetCachedClientMetadata(clientID string) (CachedClientMetadata, bool, error)
+	SaveCachedClientMetadata(clientID string, metadata CachedClientMetadata) error
+	DeleteCachedClientMetadata(clientID string) error
+	DeleteStaleClientMetadata(before time.Time) (int, error)
+
+	GetCachedClientLogo(logoID string) (CachedClientLogo, bool, error)
+	SaveCachedClientLogo(logoID string, logo CachedClientLogo) error
+
+	GetCacheEntries(namespace string) ([]CacheEntry, error)
+	SaveCacheEntries(entries ...CacheEntry) error
+	DeleteCacheEntries(namespace string, keys ...string) error
+
+	Export(writer io.Writer, options ExportOptions) error
+	Import(archive Archive, mode ImportMode) error
+
+	ReencryptRecords() (int, error)
+
+	Close() error
+}
+
+// backend is a key-value store that groups the records into buckets. Each
+// storage driver is a backend so that the entities are stored the same way
+// regardless of the driver.
+type backend interface {
+	view(fn func(tx tx) error) error
+	update(fn func(tx tx) error) error
+	close() error
+}
+
+// tx is a transaction on a backend. The changes made in an update transaction
+// are discarded if the transaction's function returns an error.
+type tx interface {
+	bucketExists(bucket string) (bool, error)
+	get(bucket, key string) ([]byte, error)
+
+	// forEach calls the function for each record in the bucket whose key starts
+	// with the prefix in the order of the keys.
+	forEach(bucket, prefix string, fn func(key string, value []byte) error) error
+
+	// put saves the record and creates the bucket if it does not exist.
+	put(bucket, key string, value []byte) error
+	delete(bucket, key string) error
+	deleteBucket(bucket string) error
+}
+
+// store is the Store for all of the storage drivers. The records that hold
+// sensitive fields are encrypted if the store has a keyring.
+type store struct {
+	backend backend
+	keyring *encryption.Keyring
+}
+
+// OpenStore opens the store for the database driver. The path is not used by
+// the memory driver. The sensitive records are not encrypted if the keyring
+// is nil.
+func OpenStore(driver, path string, keyring *encryption.Keyring) (Store, error) {
+	var storeBackend backend
+
+	switch driver {
+	case DriverBolt, "":
+		boltdb, err := Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		storeBackend = boltBackend{db: boltdb}
+	case DriverSQLite:
+		db, err := openSQLite(path)
+		if err != nil {
+			return nil, err
+		}
+
+		storeBackend = sqliteBackend{db: db}
+	case DriverMemory:
+		storeBackend = newMemoryBackend()
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, driver)
+	}
+
+	return &store{backend: storeBackend, keyring: keyring}, nil
+}
+
+func (s *store) view(fn func(tx tx) error) error {
+	return s.backend.view(func(tx tx) error {
+		return fn(sealedTx{tx: tx, keyring: s.keyring})
+	})
+}
+
+func (s *store) update(fn func(tx tx) error) error {
+	return s.backend.update(func(tx tx) error {
+		return fn(sealedTx{tx: tx, keyring: s.keyring})
+	})
+}
+
+func (s *store) Close() error {
+	return s.backend.close()
+}
+
+// getRecord decodes the record stored under the key. The boolean value is false
+// if the record does not exist.
+func getRecord[T persisted](tx tx, bucket, key string) (T, bool, error) {
+	var record T
+
+	data, err := tx.get(bucket, key)
+	if err != nil {
+		return record, false, fmt.Errorf("error reading the record %q in the %s bucket: %w", key, bucket, err)
+	}
+
+	if data == nil {
+		return record, false, nil
+	}
+
+	record, err = decodeRecord[T](data)
+	if err != nil {
+		return record, false, fmt.Errorf("error decoding the record %q in the %s bucket: %w", key, bucket, err)
+	}
+
+	return record, true, nil
+}
+
+// forEachRecord decodes each record in the bucket whose key starts with the
+// prefix and calls the function with the record's key.
+func forEachRecord[T persisted](tx tx, bucket, prefix string, fn func(key string, record T) error) error {
+	return tx.forEach(bucket, prefix, func(key string, data []byte) error {
+		record, err := decodeRecord[T](data)
+		if err != nil {
+			return fmt.Errorf("error decoding the record %q in the %s bucket: %w", key, bucket, err)
+		}
+
+		return fn(key, record)
+	})
+}
+
+func putRecord(tx tx, bucket, key string, record persisted) error {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return fmt.Errorf("error encoding the record %q: %w", key, err)
+	}
+
+	if err := tx.put(bucket, key, data); err != nil {
+		return fmt.Errorf(
+			"error saving the record %q in the %s bucket: %w",
+			key,
+			bucket,
+			err,
+		)
+	}
+
+	return nil
+}
+
+func deleteRecord(tx tx, bucket, key string) error {
+	if err := tx.delete(bucket, key); err != nil {
+		return fmt.Errorf(
+			"error deleting the record %q from the %s bucket: %w",
+			key,
+			bucket,
+			err,
+		)
+	}
+
+	return nil
+}
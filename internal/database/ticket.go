@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+const receivedTokenBucketName string = "received_tokens"
+
+// ReceivedToken is an access token that another website issued to one of the profiles
+// after redeeming a ticket (IndieAuth Ticket Auth). The subject is the profile that the
+// access token was issued to and the resource is the URL that the access token grants
+// access to. A profile has at most one access token for each resource.
+type ReceivedToken struct {
+	Resource      string    `json:"resource"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	TokenEndpoint string    `json:"token_endpoint"`
+	AccessToken   string    `json:"access_token"`
+	TokenType     string    `json:"token_type"`
+	Scope         string    `json:"scope"`
+	ReceivedAt    time.Time `json:"received_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (ReceivedToken) recordFormat() recordFormat {
+	return recordFormat{recordType: "received_token"}
+}
+
+func receivedTokenKey(subject, resource string) string {
+	return subject + " " + resource
+}
+
+// GetReceivedTokens returns all of the access tokens received by the subject.
+func (s *store) GetReceivedTokens(subject string) ([]ReceivedToken, error) {
+	tokens := make([]ReceivedToken, 0)
+
+	if err := s.view(func(tx tx) error {
+		return forEachRecord(tx, receivedTokenBucketName, subject+" ", func(_ string, token ReceivedToken) error {
+			tokens = append(tokens, token)
+
+			return nil
+		})
+	}); err != nil {
+		return nil, fmt.Errorf(
+			"error retrieving the received tokens from the database: %w",
+			err,
+		)
+	}
+
+	return tokens, nil
+}
+
+// SaveReceivedToken saves the received access token. Any existing access token
+// that the subject received for the same resource is replaced.
+func (s *store) SaveReceivedToken(token ReceivedToken) error {
+	if err := s.update(func(tx tx) error {
+		return putRecord(tx, receivedTokenBucketName, receivedTokenKey(token.Subject, token.Resource), token)
+	}); err != nil {
+		return fmt.Errorf("error saving the received token to the database: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReceivedToken deletes the access token that the subject received for the resource.
+func (s *store) DeleteReceivedToken(subject, resource string) error {
+	if err := s.update(func(tx tx) error {
+		return deleteRecord(tx, receivedTokenBucketName, receivedTokenKey(subject, resource))
+	}); err != nil {
+		return fmt.Errorf("error deleting the received token from the database: %w", err)
+	}
+
+	return nil
+}
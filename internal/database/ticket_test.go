@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testReceivedTokens(store database.Store, testName string) func(t *testing.T) {
+	return func(t *testing.T) {
+		subject := "https://billjones.example.net/"
+		receivedAt := time.Now().UTC().Truncate(time.Second)
+
+		tokens := []database.ReceivedToken{
+			{
+				Resource:      "https://alice.example.org/private/",
+				Subject:       subject,
+				Issuer:        "https://auth.alice.example.org/",
+				TokenEndpoint: "https://auth.alice.example.org/token",
+				AccessToken:   "first-access-token",
+				TokenType:     "Bearer",
+				Scope:         "read",
+				ReceivedAt:    receivedAt,
+				ExpiresAt:     receivedAt.Add(24 * time.Hour),
+			},
+			{
+				Resource:    "https://alice.example.org/private/",
+				Subject:     "https://someoneelse.example.net/",
+				AccessToken: "another-subject",
+				ReceivedAt:  receivedAt,
+			},
+		}
+
+		for _, token := range tokens {
+			if err := store.SaveReceivedToken(token); err != nil {
+				t.Fatalf(
+					"FAILED test %s: Received an error saving the received token: %v",
+					testName,
+					err,
+				)
+			}
+		}
+
+		// The new access token for the same resource replaces the old one.
+		want := tokens[0]
+		want.AccessToken = "second-access-token"
+
+		if err := store.SaveReceivedToken(want); err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error replacing the received token: %v",
+				testName,
+				err,
+			)
+		}
+
+		got, err := store.GetReceivedTokens(subject)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to get the received tokens: %v",
+				testName,
+				err,
+			)
+		}
+
+		if !reflect.DeepEqual([]database.ReceivedToken{want}, got) {
+			t.Errorf(
+				"FAILED test %s: Unexpected received tokens retrieved from the database.\nwant: %+v\ngot: %+v",
+				testName,
+				[]database.ReceivedToken{want},
+				got,
+			)
+		} else {
+			t.Logf("Expected received tokens retrieved from the database.\ngot: %+v", got)
+		}
+
+		if err := store.DeleteReceivedToken(subject, want.Resource); err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to delete the received token: %v",
+				testName,
+				err,
+			)
+		}
+
+		if got, err := store.GetReceivedTokens(subject); err != nil || len(got) != 0 {
+			t.Errorf(
+				"FAILED test %s: The received token was not deleted.\ngot: %+v\nerror: %v",
+				testName,
+				got,
+				err,
+			)
+		} else {
+			t.Log("The received token was deleted.")
+		}
+	}
+}
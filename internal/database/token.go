@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	tokenBucketName string = "tokens"
+
+	// tokenCodeIndexBucketName is the bucket of the index of the access tokens by
+	// the hash of the authorization code that they were issued from. The keys are
+	// the code hash and the token hash separated by a slash.
+	tokenCodeIndexBucketName string = "token_code_index"
+)
+
+// AccessToken is the record of an access token issued to a client.
+// The token itself is not stored; the record is stored under the token's hash.
+type AccessToken struct {
+	ClientID  string    `json:"client_id"`
+	Me        string    `json:"me"`
+	Scopes    []string  `json:"scopes"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// JKT is the JWK thumbprint of the client's key if the access
+	// token is bound to the key with DPoP.
+	JKT string `json:"jkt"`
+
+	// AuthorizationCodeHash is the hash of the authorization code that the access
+	// token was issued from so that the access token can be revoked if the code is
+	// used again.
+	AuthorizationCodeHash string `json:"authorization_code_hash"`
+}
+
+func (AccessToken) recordFormat() recordFormat {
+	return recordFormat{recordType: "access_token"}
+}
+
+// GetAccessToken returns the record of the access token stored under the given hash.
+// The boolean value is false if there is no record of the access token.
+func (s *store) GetAccessToken(tokenHash string) (AccessToken, bool, error) {
+	var (
+		token AccessToken
+		found bool
+	)
+
+	if err := s.view(func(tx tx) error {
+		var err error
+
+		token, found, err = getRecord[AccessToken](tx, tokenBucketName, tokenHash)
+
+		return err
+	}); err != nil {
+		return AccessToken{}, false, fmt.Errorf(
+			"error retrieving the access token from the database: %w",
+			err,
+		)
+	}
+
+	return token, found, nil
+}
+
+// SaveAccessToken saves the record of the access token under the given hash.
+func (s *store) SaveAccessToken(tokenHash string, token AccessToken) error {
+	if err := s.update(func(tx tx) error {
+		return putAccessToken(tx, tokenHash, token)
+	}); err != nil {
+		return fmt.Errorf("error saving the access token to the database: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAccessTokensIssuedFromCode deletes the records of every access token that
+// was issued from the authorization code with the given hash so that the access
+// tokens are no longer valid. The number of deleted records is returned.
+func (s *store) DeleteAccessTokensIssuedFromCode(codeHash string) (int, error) {
+	var count int
+
+	if err := s.update(func(tx tx) error {
+		tokenHashes := make([]string, 0)
+
+		if err := tx.forEach(tokenCodeIndexBucketName, codeHash+"/", func(_ string, tokenHash []byte) error {
+			tokenHashes = append(tokenHashes, string(tokenHash))
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("error reading the %s bucket: %w", tokenCodeIndexBucketName, err)
+		}
+
+		for _, tokenHash := range tokenHashes {
+			if err := deleteAccessToken(tx, tokenHash, codeHash); err != nil {
+				return err
+			}
+		}
+
+		count = len(tokenHashes)
+
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("error deleting the access tokens issued from the authorization code: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteExpiredAccessTokens deletes the records of the access tokens that expired
+// before the given time. The number of deleted records is returned.
+func (s *store) DeleteExpiredAccessTokens(before time.Time) (int, error) {
+	var count int
+
+	if err := s.update(func(tx tx) error {
+		expired := make(map[string]string)
+
+		if err := forEachRecord(tx, tokenBucketName, "", func(tokenHash string, token AccessToken) error {
+			if token.ExpiresAt.Before(before) {
+				expired[tokenHash] = token.AuthorizationCodeHash
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for tokenHash, codeHash := range expired {
+			if err := deleteAccessToken(tx, tokenHash, codeHash); err != nil {
+				return err
+			}
+		}
+
+		count = len(expired)
+
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("error deleting the expired access tokens: %w", err)
+	}
+
+	return count, nil
+}
+
+// putAccessToken saves the record of the access token and, if the access token was
+// issued from an authorization code, adds it to the index by the code hash.
+func putAccessToken(tx tx, tokenHash string, token AccessToken) error {
+	if err := putRecord(tx, tokenBucketName, tokenHash, token); err != nil {
+		return err
+	}
+
+	if token.AuthorizationCodeHash == "" {
+		return nil
+	}
+
+	key := tokenCodeIndexKey(token.AuthorizationCodeHash, tokenHash)
+
+	if err := tx.put(tokenCodeIndexBucketName, key, []byte(tokenHash)); err != nil {
+		return fmt.Errorf("error saving the record %q in the %s bucket: %w", key, tokenCodeIndexBucketName, err)
+	}
+
+	return nil
+}
+
+// deleteAccessToken deletes the record of the access token and its entry in the
+// index by the code hash.
+func deleteAccessToken(tx tx, tokenHash, codeHash string) error {
+	if err := deleteRecord(tx, tokenBucketName, tokenHash); err != nil {
+		return err
+	}
+
+	if codeHash == "" {
+		return nil
+	}
+
+	return deleteRecord(tx, tokenCodeIndexBucketName, tokenCodeIndexKey(codeHash, tokenHash))
+}
+
+func tokenCodeIndexKey(codeHash, tokenHash string) string {
+	return codeHash + "/" + tokenHash
+}
@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+var ErrUnexpectedNotModified = errors.New("the client responded with 304 Not Modified but the metadata is not cached")
+
+// MetadataCache is a cache for the client ID metadata which is persisted to the database.
+// The cache respects the Cache-Control, ETag and Last-Modified headers sent by the client
+// and uses conditional requests to revalidate stale entries. A stale entry can be served
+// within the grace period if the client's website cannot be reached.
+type MetadataCache struct {
+	store            database.Store
+	client           *http.Client
+	issuer           string
+	defaultMaxAge    time.Duration
+	staleGracePeriod time.Duration
+}
+
+func NewMetadataCache(
+	store database.Store,
+	client *http.Client,
+	issuer string,
+	defaultMaxAge, staleGracePeriod time.Duration,
+) *MetadataCache {
+	return &MetadataCache{
+		store:            store,
+		client:           client,
+		issuer:           issuer,
+		defaultMaxAge:    defaultMaxAge,
+		staleGracePeriod: staleGracePeriod,
+	}
+}
+
+// Get returns the metadata for the given client ID. The metadata is returned from the cache
+// if it is still fresh, otherwise it is fetched (or revalidated) from the client.
+func (c *MetadataCache) Get(ctx context.Context, clientID string) (ClientIDMetadata, error) {
+	cached, found, err := c.store.GetCachedClientMetadata(clientID)
+	if err != nil {
+		return ClientIDMetadata{}, fmt.Errorf("error getting the client metadata from the cache: %w", err)
+	}
+
+	now := time.Now()
+
+	if found && now.Before(cached.ExpiresAt) {
+		return metadataFromCache(cached), nil
+	}
+
+	etag, lastModified := "", ""
+
+	if found {
+		etag, lastModified = cached.ETag, cached.LastModified
+	}
+
+	response, err := fetchClientMetadata(ctx, c.client, clientID, c.issuer, etag, lastModified)
+	if err != nil {
+		if found && c.canServeStale(cached, now, err) {
+			slog.LogAttrs(
+				context.Background(),
+				slog.LevelWarn,
+				"Serving stale client metadata from the cache",
+				slog.String("client_id", clientID),
+				slog.Time("expired_at", cached.ExpiresAt),
+				slog.Any("error", err),
+			)
+
+			return metadataFromCache(cached), nil
+		}
+
+		return ClientIDMetadata{}, err
+	}
+
+	metadata := response.metadata
+
+	if response.notModified {
+		if !found {
+			return ClientIDMetadata{}, ErrUnexpectedNotModified
+		}
+
+		metadata = metadataFromCache(cached)
+
+		// Keep the validators from the cached entry if the client did not resend them.
+		if response.etag == "" {
+			response.etag = cached.ETag
+		}
+
+		if response.lastModified == "" {
+			response.lastModified = cached.LastModified
+		}
+	}
+
+	directives := parseCacheControl(response.cacheControl)
+
+	if directives.noStore {
+		if found {
+			if err := c.store.DeleteCachedClientMetadata(clientID); err != nil {
+				return ClientIDMetadata{}, fmt.Errorf("error removing the client metadata from the cache: %w", err)
+			}
+		}
+
+		return metadata, nil
+	}
+
+	maxAge := c.defaultMaxAge
+
+	switch {
+	case directives.noCache:
+		maxAge = 0
+	case directives.hasMaxAge:
+		maxAge = directives.maxAge
+
+		if age, err := strconv.Atoi(response.age); err == nil && age > 0 {
+			maxAge -= time.Duration(age) * time.Second
+		}
+	}
+
+	entry := database.CachedClientMetadata{
+		ClientID:       metadata.ClientID,
+		ClientName:     metadata.ClientName,
+		ClientURI:      metadata.ClientURI,
+		LogoURI:        metadata.LogoURI,
+		RedirectURIs:   metadata.RedirectURIs,
+		ETag:           response.etag,
+		LastModified:   response.lastModified,
+		MustRevalidate: directives.mustRevalidate,
+		FetchedAt:      now,
+		ExpiresAt:      now.Add(max(maxAge, 0)),
+	}
+
+	if err := c.store.SaveCachedClientMetadata(clientID, entry); err != nil {
+		return ClientIDMetadata{}, fmt.Errorf("error saving the client metadata to the cache: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// PurgeStale deletes the cached metadata that can no longer be served because it
+// expired before the grace period. Anyone can make Beacon fetch the metadata of a
+// client so the entries are not kept once they are stale. The number of deleted
+// entries is returned.
+func (c *MetadataCache) PurgeStale() (int, error) {
+	count, err := c.store.DeleteStaleClientMetadata(time.Now().Add(-c.staleGracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("error purging the stale client metadata: %w", err)
+	}
+
+	return count, nil
+}
+
+// canServeStale returns true if the stale cache entry can be served after
+// the failed attempt to fetch the client's metadata.
+func (c *MetadataCache) canServeStale(cached database.CachedClientMetadata, now time.Time, fetchErr error) bool {
+	if cached.MustRevalidate {
+		return false
+	}
+
+	if now.After(cached.ExpiresAt.Add(c.staleGracePeriod)) {
+		return false
+	}
+
+	// The metadata is only served if the client's website is unavailable.
+	// Responses such as 404 Not Found suggest that the client no longer exists.
+	badStatusErr := BadStatusResponseError{}
+	if errors.As(fetchErr, &badStatusErr) {
+		return badStatusErr.code >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+func metadataFromCache(cached database.CachedClientMetadata) ClientIDMetadata {
+	return ClientIDMetadata{
+		ClientID:     cached.ClientID,
+		ClientName:   cached.ClientName,
+		ClientURI:    cached.ClientURI,
+		LogoURI:      cached.LogoURI,
+		RedirectURIs: cached.RedirectURIs,
+	}
+}
+
+type cacheControlDirectives struct {
+	noStore        bool
+	noCache        bool
+	mustRevalidate bool
+	hasMaxAge      bool
+	maxAge         time.Duration
+}
+
+func parseCacheControl(header string) cacheControlDirectives {
+	var directives cacheControlDirectives
+
+	for directive := range strings.SplitSeq(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store":
+			directives.noStore = true
+		case "no-cache":
+			directives.noCache = true
+		case "must-revalidate":
+			directives.mustRevalidate = true
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				continue
+			}
+
+			directives.hasMaxAge = true
+			directives.maxAge = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return directives
+}
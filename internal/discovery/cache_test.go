@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+)
+
+func TestMetadataCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Fresh entries are served from the cache", testMetadataCacheFresh)
+	t.Run("Stale entries are revalidated", testMetadataCacheRevalidation)
+	t.Run("Stale entries are served within the grace period", testMetadataCacheStaleGrace)
+	t.Run("Responses with no-store are not cached", testMetadataCacheNoStore)
+	t.Run("Stale entries are purged after the grace period", testMetadataCachePurgeStale)
+}
+
+type testMetadataClient struct {
+	server         *httptest.Server
+	requests       atomic.Int32
+	conditionals   atomic.Int32
+	cacheControl   atomic.Value
+	statusOverride atomic.Int32
+}
+
+func testMetadataCachePurgeStale(t *testing.T) {
+	t.Parallel()
+
+	staleClient := newTestMetadataClient("max-age=0")
+	defer staleClient.server.Close()
+
+	freshClient := newTestMetadataClient("max-age=300")
+	defer freshClient.server.Close()
+
+	cache := newTestMetadataCache(t, 0)
+
+	_ = getCachedMetadata(t, cache, staleClient.server.URL)
+	_ = getCachedMetadata(t, cache, freshClient.server.URL)
+
+	time.Sleep(10 * time.Millisecond)
+
+	count, err := cache.PurgeStale()
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Received an error after purging the stale metadata.\ngot: %q",
+			t.Name(),
+			err.Error(),
+		)
+	}
+
+	checkRequestCount(t, "purged entries", 1, int32(count))
+
+	// The purged entry is fetched again while the fresh entry is still served from the cache.
+	_ = getCachedMetadata(t, cache, staleClient.server.URL)
+	_ = getCachedMetadata(t, cache, freshClient.server.URL)
+
+	checkRequestCount(t, "conditional requests sent to the stale client", 0, staleClient.conditionals.Load())
+	checkRequestCount(t, "requests sent to the fresh client", 1, freshClient.requests.Load())
+}
+
+func newTestMetadataClient(cacheControl string) *testMetadataClient {
+	client := testMetadataClient{}
+	client.cacheControl.Store(cacheControl)
+
+	client.server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		client.requests.Add(1)
+
+		if status := client.statusOverride.Load(); status != 0 {
+			writer.WriteHeader(int(status))
+
+			return
+		}
+
+		writer.Header().Set("ETag", `"v1"`)
+		writer.Header().Set("Cache-Control", client.cacheControl.Load().(string))
+
+		if request.Header.Get("If-None-Match") == `"v1"` {
+			client.conditionals.Add(1)
+			writer.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(`{"client_id":"` + client.server.URL + `","client_name":"Cached Client","client_uri":"` + client.server.URL + `/"}`))
+	}))
+
+	return &client
+}
+
+func newTestMetadataCache(t *testing.T, gracePeriod time.Duration) *discovery.MetadataCache {
+	t.Helper()
+
+	return discovery.NewMetadataCache(
+		database.NewMemoryStore(),
+		newTestClient(t),
+		"http://auth.testserver.example/",
+		1*time.Hour,
+		gracePeriod,
+	)
+}
+
+func getCachedMetadata(t *testing.T, cache *discovery.MetadataCache, clientID string) discovery.ClientIDMetadata {
+	t.Helper()
+
+	metadata, err := cache.Get(context.Background(), clientID)
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Received an error after getting the client's metadata.\ngot: %q",
+			t.Name(),
+			err.Error(),
+		)
+	}
+
+	if metadata.ClientName != "Cached Client" {
+		t.Fatalf(
+			"FAILED test %s: Unexpected client name received.\nwant: %q\ngot: %q",
+			t.Name(),
+			"Cached Client",
+			metadata.ClientName,
+		)
+	}
+
+	return metadata
+}
+
+func checkRequestCount(t *testing.T, name string, want, got int32) {
+	t.Helper()
+
+	if want != got {
+		t.Errorf(
+			"FAILED test %s: Unexpected number of %s.\nwant: %d\ngot: %d",
+			t.Name(),
+			name,
+			want,
+			got,
+		)
+	} else {
+		t.Logf(
+			"Expected number of %s.\ngot: %d",
+			name,
+			got,
+		)
+	}
+}
+
+func testMetadataCacheFresh(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMetadataClient("max-age=300")
+	defer client.server.Close()
+
+	cache := newTestMetadataCache(t, 1*time.Hour)
+
+	_ = getCachedMetadata(t, cache, client.server.URL)
+	_ = getCachedMetadata(t, cache, client.server.URL)
+
+	checkRequestCount(t, "requests sent to the client", 1, client.requests.Load())
+}
+
+func testMetadataCacheRevalidation(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMetadataClient("no-cache")
+	defer client.server.Close()
+
+	cache := newTestMetadataCache(t, 1*time.Hour)
+
+	_ = getCachedMetadata(t, cache, client.server.URL)
+	_ = getCachedMetadata(t, cache, client.server.URL)
+
+	checkRequestCount(t, "requests sent to the client", 2, client.requests.Load())
+	checkRequestCount(t, "conditional requests sent to the client", 1, client.conditionals.Load())
+}
+
+func testMetadataCacheStaleGrace(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMetadataClient("max-age=0")
+	defer client.server.Close()
+
+	cache := newTestMetadataCache(t, 1*time.Hour)
+
+	_ = getCachedMetadata(t, cache, client.server.URL)
+
+	client.statusOverride.Store(http.StatusServiceUnavailable)
+
+	_ = getCachedMetadata(t, cache, client.server.URL)
+
+	client.statusOverride.Store(http.StatusNotFound)
+
+	if _, err := cache.Get(context.Background(), client.server.URL); err == nil {
+		t.Errorf(
+			"FAILED test %s: Did not receive an error after the client responded with 404 Not Found",
+			t.Name(),
+		)
+	} else {
+		t.Logf(
+			"Expected error received after the client responded with 404 Not Found.\ngot: %q",
+			err.Error(),
+		)
+	}
+}
+
+func testMetadataCacheNoStore(t *testing.T) {
+	t.Parallel()
+
+	client := newTestMetadataClient("no-store")
+	defer client.server.Close()
+
+	cache := newTestMetadataCache(t, 1*time.Hour)
+
+	_ = getCachedMetadata(t, cache, client.server.URL)
+	_ = getCachedMetadata(t, cache, client.server.URL)
+
+	checkRequestCount(t, "requests sent to the client", 2, client.requests.Load())
+	checkRequestCount(t, "conditional requests sent to the client", 0, client.conditionals.Load())
+}
@@ -53,10 +53,37 @@ type ClientIDMetadata struct {
 	RedirectURIs []string `json:"redirect_uris"`
 }
 
-func FetchClientMetadata(ctx context.Context, clientID, issuer string) (ClientIDMetadata, error) {
+// FetchClientMetadata fetches the client's metadata from the URL of the client ID
+// using the given HTTP client.
+func FetchClientMetadata(ctx context.Context, client *http.Client, clientID, issuer string) (ClientIDMetadata, error) {
+	response, err := fetchClientMetadata(ctx, client, clientID, issuer, "", "")
+	if err != nil {
+		return ClientIDMetadata{}, err
+	}
+
+	return response.metadata, nil
+}
+
+type metadataResponse struct {
+	metadata     ClientIDMetadata
+	notModified  bool
+	etag         string
+	lastModified string
+	cacheControl string
+	age          string
+}
+
+// fetchClientMetadata fetches the client's metadata from the URL of the client ID.
+// If either of the ETag or Last-Modified validators are set then a conditional
+// request is made and the client may respond with 304 Not Modified.
+func fetchClientMetadata(
+	ctx context.Context,
+	client *http.Client,
+	clientID, issuer, etag, lastModified string,
+) (metadataResponse, error) {
 	request, err := http.NewRequestWithContext(ctx, http.MethodGet, clientID, nil)
 	if err != nil {
-		return ClientIDMetadata{}, fmt.Errorf("error received after creating the HTTP request: %w", err)
+		return metadataResponse{}, fmt.Errorf("error received after creating the HTTP request: %w", err)
 	}
 
 	request.Header.Set(
@@ -64,20 +91,29 @@ func FetchClientMetadata(ctx context.Context, clientID, issuer string) (ClientID
 		fmt.Sprintf("%s/%s (+%s)", info.ApplicationTitledName, info.BinaryVersion, issuer),
 	)
 
-	client := http.Client{
-		// Ensure that the HTTP Client does not follow redirects
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
 	}
 
-	response, err := client.Do(request) //#nosec G704 - The URL (a.k.a the Client ID) is validated before fetching the client's metadata.
+	if lastModified != "" {
+		request.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	response, err := client.Do(request) //#nosec G704 - The outbound client checks every address that it connects to.
 	if err != nil {
-		return ClientIDMetadata{}, fmt.Errorf("error getting the response from the client: %w", err)
+		return metadataResponse{}, fmt.Errorf("error getting the response from the client: %w", err)
 	}
 	defer response.Body.Close()
 
-	metadata := ClientIDMetadata{}
+	result := metadataResponse{
+		metadata:     ClientIDMetadata{},
+		notModified:  false,
+		etag:         response.Header.Get("ETag"),
+		lastModified: response.Header.Get("Last-Modified"),
+		cacheControl: response.Header.Get("Cache-Control"),
+		age:          response.Header.Get("Age"),
+	}
+
 	gotContentType := response.Header.Get("Content-Type")
 
 	logRequest(
@@ -89,15 +125,21 @@ func FetchClientMetadata(ctx context.Context, clientID, issuer string) (ClientID
 		response.ContentLength,
 	)
 
+	if response.StatusCode == http.StatusNotModified && (etag != "" || lastModified != "") {
+		result.notModified = true
+
+		return result, nil
+	}
+
 	if response.StatusCode != http.StatusOK {
 		if response.StatusCode >= http.StatusMultipleChoices && response.StatusCode < http.StatusBadRequest {
-			return ClientIDMetadata{}, AttemptedRedirectionError{
+			return metadataResponse{}, AttemptedRedirectionError{
 				code:   response.StatusCode,
 				status: response.Status,
 			}
 		}
 
-		return ClientIDMetadata{}, BadStatusResponseError{
+		return metadataResponse{}, BadStatusResponseError{
 			code:   response.StatusCode,
 			status: response.Status,
 		}
@@ -105,21 +147,21 @@ func FetchClientMetadata(ctx context.Context, clientID, issuer string) (ClientID
 
 	switch strings.ToLower(gotContentType) {
 	case "application/json":
-		if err := json.NewDecoder(response.Body).Decode(&metadata); err != nil {
-			return ClientIDMetadata{}, fmt.Errorf("unable to decode the JSON data: %w", err)
+		if err := json.NewDecoder(response.Body).Decode(&result.metadata); err != nil {
+			return metadataResponse{}, fmt.Errorf("unable to decode the JSON data: %w", err)
 		}
 	case "text/html", "text/html; charset=utf-8":
 		parsedClientID, err := url.Parse(clientID)
 		if err != nil {
-			return ClientIDMetadata{}, fmt.Errorf("unable to parse the client ID: %w", err)
+			return metadataResponse{}, fmt.Errorf("unable to parse the client ID: %w", err)
 		}
 
-		metadata = GetMetadataFromHTML(response.Body, clientID, parsedClientID)
+		result.metadata = GetMetadataFromHTML(response.Body, clientID, parsedClientID)
 	default:
-		return ClientIDMetadata{}, UnsupportedContentTypeError{contentType: gotContentType}
+		return metadataResponse{}, UnsupportedContentTypeError{contentType: gotContentType}
 	}
 
-	return metadata, nil
+	return result, nil
 }
 
 func GetMetadataFromHTML(reader io.Reader, clientID string, parsedClientID *url.URL) ClientIDMetadata {
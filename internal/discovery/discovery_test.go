@@ -11,8 +11,10 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/outbound"
 )
 
 func TestGetMetadataFromHTML(t *testing.T) {
@@ -56,7 +58,7 @@ func testGetMetadataFromHTML(t *testing.T) {
 	}))
 	defer testClient.Close()
 
-	gotMetadata, err := discovery.FetchClientMetadata(context.Background(), testClient.URL, "http://auth.testserver.example/")
+	gotMetadata, err := discovery.FetchClientMetadata(context.Background(), newTestClient(t), testClient.URL, "http://auth.testserver.example/")
 	if err != nil {
 		t.Fatalf(
 			"FAILED test %s: Received an error after attempting to get the client's metadata.\ngot: %q",
@@ -115,7 +117,7 @@ func testGetMetadataFromJSON(t *testing.T) {
 	}))
 	defer testClient.Close()
 
-	gotMetadata, err := discovery.FetchClientMetadata(context.Background(), testClient.URL, "http://auth.testserver.example/")
+	gotMetadata, err := discovery.FetchClientMetadata(context.Background(), newTestClient(t), testClient.URL, "http://auth.testserver.example/")
 	if err != nil {
 		t.Fatalf(
 			"FAILED test %s: Received an error after attempting to get the client's metadata.\ngot: %q",
@@ -160,7 +162,7 @@ func testBadStatusCode(t *testing.T) {
 	}))
 	defer testClient.Close()
 
-	_, err := discovery.FetchClientMetadata(context.Background(), testClient.URL, "http://auth.testserver.example/")
+	_, err := discovery.FetchClientMetadata(context.Background(), newTestClient(t), testClient.URL, "http://auth.testserver.example/")
 	if err == nil {
 		t.Fatalf(
 			"FAILED test %s: Did not receive an error for bad status code.",
@@ -193,7 +195,7 @@ func testClientAttemptedRedirection(t *testing.T) {
 	}))
 	defer testClient.Close()
 
-	_, err := discovery.FetchClientMetadata(context.Background(), testClient.URL, "http://auth.testserver.example/")
+	_, err := discovery.FetchClientMetadata(context.Background(), newTestClient(t), testClient.URL, "http://auth.testserver.example/")
 	if err == nil {
 		t.Fatalf(
 			"FAILED test %s: Did not receive an error for the client's attempt at a redirection.",
@@ -217,3 +219,27 @@ func testClientAttemptedRedirection(t *testing.T) {
 		)
 	}
 }
+
+// newTestClient returns an outbound HTTP client which allows connections
+// to the test servers on the loopback address.
+func newTestClient(t *testing.T) *http.Client {
+	t.Helper()
+
+	guard, err := outbound.NewGuard([]string{})
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Unable to create the outbound guard: %v",
+			t.Name(),
+			err,
+		)
+	}
+
+	return outbound.NewClient(
+		guard,
+		outbound.Settings{
+			ConnectTimeout:  1 * time.Second,
+			Timeout:         5 * time.Second,
+			MaxResponseSize: 1 << 20,
+		},
+	)
+}
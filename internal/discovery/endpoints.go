@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+	"willnorris.com/go/microformats"
+)
+
+const (
+	relIndieAuthMetadata     = "indieauth-metadata"
+	relAuthorizationEndpoint = "authorization_endpoint"
+	relTokenEndpoint         = "token_endpoint"
+	relTicketEndpoint        = "ticket_endpoint"
+)
+
+// Endpoints are the IndieAuth endpoints of a user's website.
+type Endpoints struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	TicketEndpoint        string `json:"ticket_endpoint"`
+}
+
+// DiscoverEndpoints discovers the IndieAuth endpoints of the website at the profile URL.
+// The endpoints are taken from the authorization server's metadata if the website links
+// to it with the indieauth-metadata rel. Otherwise the endpoints are taken from the
+// individual rels. Links in the HTTP Link header take precedence over the HTML links.
+func DiscoverEndpoints(ctx context.Context, client *http.Client, profileURL, issuer string) (Endpoints, error) {
+	headerRels, htmlRels, err := fetchRels(ctx, client, profileURL, issuer, "discovering endpoints")
+	if err != nil {
+		return Endpoints{}, err
+	}
+
+	rels := headerRels
+
+	for rel, values := range htmlRels {
+		if _, ok := rels[rel]; !ok {
+			rels[rel] = values
+		}
+	}
+
+	if metadataURL := firstRel(rels, relIndieAuthMetadata); metadataURL != "" {
+		return FetchServerMetadata(ctx, client, metadataURL, issuer)
+	}
+
+	return Endpoints{
+		Issuer:                "",
+		AuthorizationEndpoint: firstRel(rels, relAuthorizationEndpoint),
+		TokenEndpoint:         firstRel(rels, relTokenEndpoint),
+		TicketEndpoint:        firstRel(rels, relTicketEndpoint),
+	}, nil
+}
+
+// ProfileLinks are the IndieAuth links published by the user's website. The links
+// from the HTTP Link headers are listed before the links from the HTML.
+type ProfileLinks struct {
+	IndieAuthMetadata     []string
+	AuthorizationEndpoint []string
+	TokenEndpoint         []string
+}
+
+// FetchProfileLinks fetches the website at the profile URL and returns all of the
+// IndieAuth links that it publishes, without following them.
+func FetchProfileLinks(ctx context.Context, client *http.Client, profileURL, issuer string) (ProfileLinks, error) {
+	headerRels, htmlRels, err := fetchRels(ctx, client, profileURL, issuer, "fetching profile links")
+	if err != nil {
+		return ProfileLinks{}, err
+	}
+
+	allRels := func(rel string) []string {
+		values := make([]string, 0)
+
+		for _, value := range append(headerRels[rel], htmlRels[rel]...) {
+			if !slices.Contains(values, value) {
+				values = append(values, value)
+			}
+		}
+
+		return values
+	}
+
+	return ProfileLinks{
+		IndieAuthMetadata:     allRels(relIndieAuthMetadata),
+		AuthorizationEndpoint: allRels(relAuthorizationEndpoint),
+		TokenEndpoint:         allRels(relTokenEndpoint),
+	}, nil
+}
+
+// fetchRels fetches the website at the profile URL and returns the rels from the
+// HTTP Link headers and the rels from the HTML separately.
+func fetchRels(
+	ctx context.Context,
+	client *http.Client,
+	profileURL string,
+	issuer string,
+	action string,
+) (map[string][]string, map[string][]string, error) {
+	response, err := get(ctx, client, profileURL, issuer, "text/html")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	logRequest(
+		action,
+		response.Request.Method,
+		response.Request.URL.String(),
+		response.StatusCode,
+		response.Header.Get("Content-Type"),
+		response.ContentLength,
+	)
+
+	if err := checkStatus(response); err != nil {
+		return nil, nil, err
+	}
+
+	headerRels := parseLinkHeaders(response.Header.Values("Link"), response.Request.URL)
+	htmlRels := make(map[string][]string)
+
+	if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType == "text/html" {
+		htmlRels = microformats.Parse(response.Body, response.Request.URL).Rels
+	}
+
+	return headerRels, htmlRels, nil
+}
+
+// FetchServerMetadata fetches the endpoints from the authorization server's metadata document.
+func FetchServerMetadata(ctx context.Context, client *http.Client, metadataURL, issuer string) (Endpoints, error) {
+	response, err := get(ctx, client, metadataURL, issuer, "application/json")
+	if err != nil {
+		return Endpoints{}, err
+	}
+	defer response.Body.Close()
+
+	gotContentType := response.Header.Get("Content-Type")
+
+	logRequest(
+		"fetching server metadata",
+		response.Request.Method,
+		response.Request.URL.String(),
+		response.StatusCode,
+		gotContentType,
+		response.ContentLength,
+	)
+
+	if err := checkStatus(response); err != nil {
+		return Endpoints{}, err
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(gotContentType); mediaType != "application/json" {
+		return Endpoints{}, UnsupportedContentTypeError{contentType: gotContentType}
+	}
+
+	var endpoints Endpoints
+
+	if err := json.NewDecoder(response.Body).Decode(&endpoints); err != nil {
+		return Endpoints{}, fmt.Errorf("unable to decode the JSON data: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func get(ctx context.Context, client *http.Client, target, issuer, accept string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error received after creating the HTTP request: %w", err)
+	}
+
+	request.Header.Set(
+		"User-Agent",
+		fmt.Sprintf("%s/%s (+%s)", info.ApplicationTitledName, info.BinaryVersion, issuer),
+	)
+	request.Header.Set("Accept", accept)
+
+	response, err := client.Do(request) //#nosec G704 - The outbound client checks every address that it connects to.
+	if err != nil {
+		return nil, fmt.Errorf("error getting the response from %s: %w", target, err)
+	}
+
+	return response, nil
+}
+
+func checkStatus(response *http.Response) error {
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	if response.StatusCode >= http.StatusMultipleChoices && response.StatusCode < http.StatusBadRequest {
+		return AttemptedRedirectionError{
+			code:   response.StatusCode,
+			status: response.Status,
+		}
+	}
+
+	return BadStatusResponseError{
+		code:   response.StatusCode,
+		status: response.Status,
+	}
+}
+
+// parseLinkHeaders parses the HTTP Link headers (RFC 8288) into a map of rels to the
+// resolved link targets, e.g. `<https://auth.example.org/token>; rel="token_endpoint"`.
+func parseLinkHeaders(headers []string, base *url.URL) map[string][]string {
+	rels := make(map[string][]string)
+
+	for _, header := range headers {
+		for link := range strings.SplitSeq(header, ",") {
+			target, params, found := strings.Cut(link, ";")
+			if !found {
+				continue
+			}
+
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			resolved, err := base.Parse(strings.Trim(target, "<>"))
+			if err != nil {
+				continue
+			}
+
+			for param := range strings.SplitSeq(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+
+				for rel := range strings.FieldsSeq(strings.Trim(value, `"`)) {
+					rels[rel] = append(rels[rel], resolved.String())
+				}
+			}
+		}
+	}
+
+	return rels
+}
+
+func firstRel(rels map[string][]string, rel string) string {
+	if values := rels[rel]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+)
+
+func TestDiscoverEndpoints(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	// The website with the endpoints in the Link header and in the HTML links.
+	// The Link header takes precedence.
+	mux.HandleFunc("/links", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Link", `</token>; rel="token_endpoint", <https://auth.example.org/authorize>; rel="authorization_endpoint"`)
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(`
+<!DOCTYPE html>
+<html>
+    <head>
+        <link rel="token_endpoint" href="/ignored-token">
+        <link rel="ticket_endpoint" href="/ticket">
+    </head>
+    <body></body>
+</html>
+`))
+	})
+
+	// The website linking to the authorization server's metadata.
+	mux.HandleFunc("/metadata-link", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Link", `</metadata>; rel="indieauth-metadata"`)
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	mux.HandleFunc("/metadata", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(`{
+	"issuer": "https://auth.example.org/",
+	"authorization_endpoint": "https://auth.example.org/authorize",
+	"token_endpoint": "https://auth.example.org/token",
+	"ticket_endpoint": "https://auth.example.org/ticket"
+}`))
+	})
+
+	testWebsite := httptest.NewServer(mux)
+	defer testWebsite.Close()
+
+	testCases := []struct {
+		path string
+		want discovery.Endpoints
+	}{
+		{
+			path: "/links",
+			want: discovery.Endpoints{
+				AuthorizationEndpoint: "https://auth.example.org/authorize",
+				TokenEndpoint:         testWebsite.URL + "/token",
+				TicketEndpoint:        testWebsite.URL + "/ticket",
+			},
+		},
+		{
+			path: "/metadata-link",
+			want: discovery.Endpoints{
+				Issuer:                "https://auth.example.org/",
+				AuthorizationEndpoint: "https://auth.example.org/authorize",
+				TokenEndpoint:         "https://auth.example.org/token",
+				TicketEndpoint:        "https://auth.example.org/ticket",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := discovery.DiscoverEndpoints(
+			context.Background(),
+			newTestClient(t),
+			testWebsite.URL+tc.path,
+			"http://auth.testserver.example/",
+		)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error discovering the endpoints from %s.\ngot: %q",
+				t.Name(),
+				tc.path,
+				err.Error(),
+			)
+		}
+
+		if got != tc.want {
+			t.Errorf(
+				"FAILED test %s: Unexpected endpoints discovered from %s.\nwant: %+v\ngot: %+v",
+				t.Name(),
+				tc.path,
+				tc.want,
+				got,
+			)
+		} else {
+			t.Logf("Expected endpoints discovered from %s.\ngot: %+v", tc.path, got)
+		}
+	}
+}
+
+func TestFetchProfileLinks(t *testing.T) {
+	t.Parallel()
+
+	testWebsite := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Link", `<https://auth.example.org/.well-known/oauth-authorization-server>; rel="indieauth-metadata"`)
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(`
+<!DOCTYPE html>
+<html>
+    <head>
+        <link rel="indieauth-metadata" href="https://auth.example.org/.well-known/oauth-authorization-server">
+        <link rel="authorization_endpoint" href="https://auth.example.org/authorize">
+        <link rel="authorization_endpoint" href="/authorize">
+    </head>
+    <body></body>
+</html>
+`))
+	}))
+	defer testWebsite.Close()
+
+	got, err := discovery.FetchProfileLinks(
+		context.Background(),
+		newTestClient(t),
+		testWebsite.URL,
+		"http://auth.testserver.example/",
+	)
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Received an error fetching the profile links.\ngot: %q",
+			t.Name(),
+			err.Error(),
+		)
+	}
+
+	want := discovery.ProfileLinks{
+		IndieAuthMetadata:     []string{"https://auth.example.org/.well-known/oauth-authorization-server"},
+		AuthorizationEndpoint: []string{"https://auth.example.org/authorize", testWebsite.URL + "/authorize"},
+		TokenEndpoint:         []string{},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf(
+			"FAILED test %s: Unexpected profile links returned.\nwant: %+v\ngot: %+v",
+			t.Name(),
+			want,
+			got,
+		)
+	} else {
+		t.Logf("Expected profile links returned.\ngot: %+v", got)
+	}
+}
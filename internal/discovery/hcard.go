@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"willnorris.com/go/microformats"
+)
+
+var ErrNoRepresentativeHCard = errors.New("the website does not have a representative h-card")
+
+// HCard is the profile information taken from the representative h-card of a website.
+type HCard struct {
+	Name  string
+	URL   string
+	Email string
+	Photo string
+}
+
+// FetchRepresentativeHCard fetches the website at the profile URL and returns the
+// information from its representative h-card.
+// See https://microformats.org/wiki/representative-h-card-parsing.
+func FetchRepresentativeHCard(ctx context.Context, client *http.Client, profileURL, issuer string) (HCard, error) {
+	response, err := get(ctx, client, profileURL, issuer, "text/html")
+	if err != nil {
+		return HCard{}, err
+	}
+	defer response.Body.Close()
+
+	gotContentType := response.Header.Get("Content-Type")
+
+	logRequest(
+		"fetching the representative h-card",
+		response.Request.Method,
+		response.Request.URL.String(),
+		response.StatusCode,
+		gotContentType,
+		response.ContentLength,
+	)
+
+	if err := checkStatus(response); err != nil {
+		return HCard{}, err
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(gotContentType); mediaType != "text/html" {
+		return HCard{}, UnsupportedContentTypeError{contentType: gotContentType}
+	}
+
+	data := microformats.Parse(response.Body, response.Request.URL)
+
+	item := representativeHCard(data, response.Request.URL.String())
+	if item == nil {
+		return HCard{}, ErrNoRepresentativeHCard
+	}
+
+	return HCard{
+		Name:  firstProperty(item, "name"),
+		URL:   firstProperty(item, "url"),
+		Email: strings.TrimPrefix(firstProperty(item, "email"), "mailto:"),
+		Photo: firstProperty(item, "photo"),
+	}, nil
+}
+
+func representativeHCard(data *microformats.Data, pageURL string) *microformats.Microformat {
+	hCards := make([]*microformats.Microformat, 0)
+
+	for _, item := range data.Items {
+		if slices.Contains(item.Type, "h-card") {
+			hCards = append(hCards, item)
+		}
+	}
+
+	// The h-card with both a uid and a url that match the page's URL.
+	for _, item := range hCards {
+		if slices.ContainsFunc(properties(item, "uid"), matchesURL(pageURL)) &&
+			slices.ContainsFunc(properties(item, "url"), matchesURL(pageURL)) {
+			return item
+		}
+	}
+
+	// The h-card with a url that matches one of the page's rel=me links.
+	for _, item := range hCards {
+		for _, relMe := range data.Rels["me"] {
+			if slices.ContainsFunc(properties(item, "url"), matchesURL(relMe)) {
+				return item
+			}
+		}
+	}
+
+	// The only h-card on the page with a url that matches the page's URL.
+	if len(hCards) == 1 && slices.ContainsFunc(properties(hCards[0], "url"), matchesURL(pageURL)) {
+		return hCards[0]
+	}
+
+	return nil
+}
+
+// properties returns the string values of the microformat's property. The values of
+// the properties with alternative text (e.g. the photo) are returned.
+func properties(item *microformats.Microformat, name string) []string {
+	values := make([]string, 0)
+
+	for _, value := range item.Properties[name] {
+		switch value := value.(type) {
+		case string:
+			values = append(values, value)
+		case map[string]string:
+			values = append(values, value["value"])
+		case map[string]any:
+			if text, ok := value["value"].(string); ok {
+				values = append(values, text)
+			}
+		}
+	}
+
+	return values
+}
+
+func firstProperty(item *microformats.Microformat, name string) string {
+	if values := properties(item, name); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+
+	return ""
+}
+
+func matchesURL(want string) func(string) bool {
+	return func(got string) bool {
+		return strings.TrimSuffix(got, "/") == strings.TrimSuffix(want, "/")
+	}
+}
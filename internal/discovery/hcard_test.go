@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+)
+
+func TestFetchRepresentativeHCard(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	// The representative h-card is the one with the uid and url of the page.
+	mux.HandleFunc("/uid/", func(writer http.ResponseWriter, request *http.Request) {
+		pageURL := "http://" + request.Host + "/uid/"
+
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = writer.Write([]byte(`
+<!DOCTYPE html>
+<html>
+    <body>
+        <div class="h-card">
+            <a class="p-name u-url" href="https://someoneelse.example.org/">Someone Else</a>
+        </div>
+        <div class="h-card">
+            <a class="p-name u-url u-uid" href="` + pageURL + `">Bill Jones</a>
+            <a class="u-email" href="mailto:hi@billjones.example.net">Email</a>
+            <img class="u-photo" src="/photo.png" alt="Bill Jones">
+        </div>
+    </body>
+</html>
+`))
+	})
+
+	// The representative h-card is the one with the url of a rel=me link.
+	mux.HandleFunc("/rel-me/", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = writer.Write([]byte(`
+<!DOCTYPE html>
+<html>
+    <head>
+        <link rel="me" href="https://social.example.org/@bill">
+    </head>
+    <body>
+        <div class="h-card">
+            <span class="p-name">Bill Jones</span>
+            <a class="u-url" href="https://social.example.org/@bill">Social</a>
+        </div>
+    </body>
+</html>
+`))
+	})
+
+	// There is no representative h-card when the only h-card is for another website.
+	mux.HandleFunc("/none/", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = writer.Write([]byte(`
+<!DOCTYPE html>
+<html>
+    <body>
+        <div class="h-card">
+            <a class="p-name u-url" href="https://someoneelse.example.org/">Someone Else</a>
+        </div>
+    </body>
+</html>
+`))
+	})
+
+	testWebsite := httptest.NewServer(mux)
+	defer testWebsite.Close()
+
+	testCases := []struct {
+		path    string
+		want    discovery.HCard
+		wantErr error
+	}{
+		{
+			path: "/uid/",
+			want: discovery.HCard{
+				Name:  "Bill Jones",
+				URL:   testWebsite.URL + "/uid/",
+				Email: "hi@billjones.example.net",
+				Photo: testWebsite.URL + "/photo.png",
+			},
+			wantErr: nil,
+		},
+		{
+			path: "/rel-me/",
+			want: discovery.HCard{
+				Name:  "Bill Jones",
+				URL:   "https://social.example.org/@bill",
+				Email: "",
+				Photo: "",
+			},
+			wantErr: nil,
+		},
+		{
+			path:    "/none/",
+			want:    discovery.HCard{},
+			wantErr: discovery.ErrNoRepresentativeHCard,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := discovery.FetchRepresentativeHCard(
+			context.Background(),
+			newTestClient(t),
+			testWebsite.URL+tc.path,
+			"http://auth.testserver.example/",
+		)
+		if !errors.Is(err, tc.wantErr) {
+			t.Fatalf(
+				"FAILED test %s: Unexpected error received fetching the h-card from %s.\nwant: %v\ngot: %v",
+				t.Name(),
+				tc.path,
+				tc.wantErr,
+				err,
+			)
+		}
+
+		if got != tc.want {
+			t.Errorf(
+				"FAILED test %s: Unexpected h-card fetched from %s.\nwant: %+v\ngot: %+v",
+				t.Name(),
+				tc.path,
+				tc.want,
+				got,
+			)
+		} else {
+			t.Logf("Expected h-card fetched from %s.\ngot: %+v", tc.path, got)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+)
+
+var (
+	ErrInvalidLogoURI      = errors.New("the logo URI is not an absolute http-based URL")
+	ErrMismatchedLogoType  = errors.New("the content of the logo does not match its content type")
+	ErrClientLogoNotCached = errors.New("the client logo is not cached")
+)
+
+type LogoTooLargeError struct {
+	limit int64
+}
+
+func (e LogoTooLargeError) Error() string {
+	return "the logo is larger than the limit of " + strconv.FormatInt(e.limit, 10) + " bytes"
+}
+
+// supportedLogoContentTypes are the image types that can be served to the user.
+// SVG images are not supported as they can contain scripts.
+func supportedLogoContentTypes() []string {
+	return []string{
+		"image/png",
+		"image/jpeg",
+		"image/gif",
+		"image/webp",
+	}
+}
+
+// LogoID returns the ID used to store and serve the logo at the given URI.
+func LogoID(logoURI string) string {
+	hash := sha256.Sum256([]byte(logoURI))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// LogoCache is a cache for the client logos which is persisted to the database.
+// Logos are fetched with the outbound HTTP client so that they can be served to
+// the user from Beacon without revealing the user's IP address to the client.
+type LogoCache struct {
+	store   database.Store
+	client  *http.Client
+	issuer  string
+	maxAge  time.Duration
+	maxSize int64
+}
+
+func NewLogoCache(
+	store database.Store,
+	client *http.Client,
+	issuer string,
+	maxAge time.Duration,
+	maxSize int64,
+) *LogoCache {
+	return &LogoCache{
+		store:   store,
+		client:  client,
+		issuer:  issuer,
+		maxAge:  maxAge,
+		maxSize: maxSize,
+	}
+}
+
+// Fetch ensures that the logo at the given URI is in the cache and returns its ID.
+// The logo is only fetched from the client if it is not cached or has expired.
+// The stale logo is kept if it cannot be fetched again.
+func (c *LogoCache) Fetch(ctx context.Context, logoURI string) (string, error) {
+	logoID := LogoID(logoURI)
+
+	cached, found, err := c.store.GetCachedClientLogo(logoID)
+	if err != nil {
+		return "", fmt.Errorf("error getting the client logo from the cache: %w", err)
+	}
+
+	now := time.Now()
+
+	if found && now.Before(cached.ExpiresAt) {
+		return logoID, nil
+	}
+
+	contentType, data, err := fetchClientLogo(ctx, c.client, logoURI, c.issuer, c.maxSize)
+	if err != nil {
+		if found {
+			slog.LogAttrs(
+				context.Background(),
+				slog.LevelWarn,
+				"Serving stale client logo from the cache",
+				slog.String("logo_uri", logoURI),
+				slog.Time("expired_at", cached.ExpiresAt),
+				slog.Any("error", err),
+			)
+
+			return logoID, nil
+		}
+
+		return "", err
+	}
+
+	logo := database.CachedClientLogo{
+		LogoURI:     logoURI,
+		ContentType: contentType,
+		Data:        data,
+		FetchedAt:   now,
+		ExpiresAt:   now.Add(c.maxAge),
+	}
+
+	if err := c.store.SaveCachedClientLogo(logoID, logo); err != nil {
+		return "", fmt.Errorf("error saving the client logo to the cache: %w", err)
+	}
+
+	return logoID, nil
+}
+
+// Get returns the cached logo stored under the given ID.
+func (c *LogoCache) Get(logoID string) (database.CachedClientLogo, error) {
+	logo, found, err := c.store.GetCachedClientLogo(logoID)
+	if err != nil {
+		return database.CachedClientLogo{}, fmt.Errorf("error getting the client logo from the cache: %w", err)
+	}
+
+	if !found {
+		return database.CachedClientLogo{}, ErrClientLogoNotCached
+	}
+
+	return logo, nil
+}
+
+// fetchClientLogo fetches the logo from the client and validates the content type
+// and size of the image. The content type is checked against the content of the
+// image to prevent content that browsers would treat differently.
+func fetchClientLogo(
+	ctx context.Context,
+	client *http.Client,
+	logoURI, issuer string,
+	maxSize int64,
+) (string, []byte, error) {
+	parsedLogoURI, err := url.Parse(logoURI)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing the logo URI: %w", err)
+	}
+
+	if (parsedLogoURI.Scheme != "http" && parsedLogoURI.Scheme != "https") || parsedLogoURI.Host == "" {
+		return "", nil, ErrInvalidLogoURI
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, logoURI, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("error received after creating the HTTP request: %w", err)
+	}
+
+	request.Header.Set(
+		"User-Agent",
+		fmt.Sprintf("%s/%s (+%s)", info.ApplicationTitledName, info.BinaryVersion, issuer),
+	)
+	request.Header.Set("Accept", "image/png, image/jpeg, image/gif, image/webp")
+
+	response, err := client.Do(request) //#nosec G704 - The outbound client checks every address that it connects to.
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting the response from the client: %w", err)
+	}
+	defer response.Body.Close()
+
+	gotContentType := response.Header.Get("Content-Type")
+
+	logRequest(
+		"fetching client logo",
+		response.Request.Method,
+		response.Request.URL.String(),
+		response.StatusCode,
+		gotContentType,
+		response.ContentLength,
+	)
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode >= http.StatusMultipleChoices && response.StatusCode < http.StatusBadRequest {
+			return "", nil, AttemptedRedirectionError{
+				code:   response.StatusCode,
+				status: response.Status,
+			}
+		}
+
+		return "", nil, BadStatusResponseError{
+			code:   response.StatusCode,
+			status: response.Status,
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(gotContentType)
+	if err != nil || !slices.Contains(supportedLogoContentTypes(), mediaType) {
+		return "", nil, UnsupportedContentTypeError{contentType: gotContentType}
+	}
+
+	if response.ContentLength > maxSize {
+		return "", nil, LogoTooLargeError{limit: maxSize}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading the logo: %w", err)
+	}
+
+	if int64(len(data)) > maxSize {
+		return "", nil, LogoTooLargeError{limit: maxSize}
+	}
+
+	if http.DetectContentType(data) != mediaType {
+		return "", nil, ErrMismatchedLogoType
+	}
+
+	return mediaType, data, nil
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package discovery_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+)
+
+const testPNG = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00"
+
+func TestLogoCache(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+
+	logos := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"/logo.png":      {contentType: "image/png", body: testPNG},
+		"/logo.svg":      {contentType: "image/svg+xml", body: `<svg xmlns="http://www.w3.org/2000/svg"></svg>`},
+		"/large.png":     {contentType: "image/png", body: testPNG + strings.Repeat("\x00", 2048)},
+		"/disguised.png": {contentType: "image/png", body: "<html><script>alert(1)</script></html>"},
+	}
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests.Add(1)
+
+		logo, ok := logos[request.URL.Path]
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		writer.Header().Set("Content-Type", logo.contentType)
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(logo.body))
+	}))
+	t.Cleanup(testServer.Close)
+
+	cache := discovery.NewLogoCache(database.NewMemoryStore(), newTestClient(t), "http://auth.testserver.example/", 1*time.Hour, 1024)
+
+	t.Run("Valid logos are cached", func(t *testing.T) {
+		logoURI := testServer.URL + "/logo.png"
+
+		for range 2 {
+			logoID, err := cache.Fetch(context.Background(), logoURI)
+			if err != nil {
+				t.Fatalf(
+					"FAILED test %s: Received an error after fetching the logo.\ngot: %q",
+					t.Name(),
+					err.Error(),
+				)
+			}
+
+			if logoID != discovery.LogoID(logoURI) {
+				t.Fatalf(
+					"FAILED test %s: Unexpected logo ID received.\nwant: %q\ngot: %q",
+					t.Name(),
+					discovery.LogoID(logoURI),
+					logoID,
+				)
+			}
+		}
+
+		checkRequestCount(t, "requests sent to the client", 1, requests.Load())
+
+		logo, err := cache.Get(discovery.LogoID(logoURI))
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error after getting the logo from the cache.\ngot: %q",
+				t.Name(),
+				err.Error(),
+			)
+		}
+
+		if logo.ContentType != "image/png" || string(logo.Data) != testPNG {
+			t.Errorf(
+				"FAILED test %s: Unexpected logo received from the cache.\ngot: %s (%d bytes)",
+				t.Name(),
+				logo.ContentType,
+				len(logo.Data),
+			)
+		} else {
+			t.Logf("Expected logo received from the cache.\ngot: %s (%d bytes)", logo.ContentType, len(logo.Data))
+		}
+	})
+
+	errorCases := []struct {
+		name      string
+		path      string
+		wantError error
+	}{
+		{
+			name:      "SVG logos are rejected",
+			path:      "/logo.svg",
+			wantError: discovery.UnsupportedContentTypeError{},
+		},
+		{
+			name:      "Logos larger than the size limit are rejected",
+			path:      "/large.png",
+			wantError: discovery.LogoTooLargeError{},
+		},
+		{
+			name:      "Logos that do not match their content type are rejected",
+			path:      "/disguised.png",
+			wantError: discovery.ErrMismatchedLogoType,
+		},
+	}
+
+	for _, ec := range errorCases {
+		t.Run(ec.name, func(t *testing.T) {
+			_, err := cache.Fetch(context.Background(), testServer.URL+ec.path)
+			if err == nil {
+				t.Fatalf(
+					"FAILED test %s: No error was received after fetching an invalid logo",
+					t.Name(),
+				)
+			}
+
+			if !errors.Is(err, ec.wantError) && !sameErrorType(err, ec.wantError) {
+				t.Errorf(
+					"FAILED test %s: Unexpected error received after fetching an invalid logo.\nwant: %T\ngot: %v",
+					t.Name(),
+					ec.wantError,
+					err,
+				)
+			} else {
+				t.Logf("Expected error received.\ngot: %q", err.Error())
+			}
+		})
+	}
+}
+
+func sameErrorType(err, target error) bool {
+	switch target.(type) {
+	case discovery.UnsupportedContentTypeError:
+		return errors.As(err, &discovery.UnsupportedContentTypeError{})
+	case discovery.LogoTooLargeError:
+		return errors.As(err, &discovery.LogoTooLargeError{})
+	default:
+		return false
+	}
+}
@@ -9,16 +9,21 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"net/url"
 	"slices"
 	"strings"
 	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/outbound"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
 )
 
 var (
-	ErrMismatchedClientID = errors.New("the client ID in the authorization request does not match the client ID in the client ID's metadata")
-	ErrInvalidClientURL   = errors.New("the client URL in the metadata is not a prefix of the client ID")
-	ErrInvalidRedirectURI = errors.New("the redirect URI in the authorization request is invalid")
+	ErrMismatchedClientID       = errors.New("the client ID in the authorization request does not match the client ID in the client ID's metadata")
+	ErrInvalidClientURL         = errors.New("the client URL in the metadata is not a prefix of the client ID")
+	ErrInvalidRedirectURI       = errors.New("the redirect URI in the authorization request is invalid")
+	ErrInvalidRedirectURIScheme = errors.New("the redirect URI uses a scheme that is neither http-based nor a private-use URI scheme")
 )
 
 // ValidateClientMetadata validates the received client metadata against the criteria specified in the
@@ -47,7 +52,32 @@ func ValidateClientMetadata(metadata ClientIDMetadata, requestedClientID, reques
 
 	// The scheme, host and port of the requested redirect URI should match that of the client ID.
 	// Otherwise the redirect URI MUST match one of the redirect URIs in the client ID metadata.
-	if parsedClientID.Scheme != parsedRequestedRedirectURI.Scheme || parsedClientID.Hostname() != parsedRequestedRedirectURI.Hostname() || parsedClientID.Port() != parsedRequestedRedirectURI.Port() {
+	sameOrigin := parsedClientID.Scheme == parsedRequestedRedirectURI.Scheme &&
+		parsedClientID.Hostname() == parsedRequestedRedirectURI.Hostname() &&
+		parsedClientID.Port() == parsedRequestedRedirectURI.Port()
+
+	switch {
+	case parsedRequestedRedirectURI.Scheme != "http" && parsedRequestedRedirectURI.Scheme != "https":
+		// Native clients may use a private-use URI scheme (RFC 8252, section 7.1) which must
+		// be based on a domain name that the client controls (e.g. com.example.app) and must be
+		// listed in the client ID metadata.
+		if !strings.Contains(parsedRequestedRedirectURI.Scheme, ".") {
+			return ErrInvalidRedirectURIScheme
+		}
+
+		if !slices.Contains(metadata.RedirectURIs, parsedRequestedRedirectURI.String()) {
+			return ErrInvalidRedirectURI
+		}
+	case sameOrigin:
+		// The redirect URI does not need to be listed in the metadata.
+	case isLoopbackRedirectURI(parsedRequestedRedirectURI):
+		// Native clients that use the loopback interface (RFC 8252, section 7.3) listen on
+		// an ephemeral port so the port is ignored when comparing the redirect URI against
+		// the loopback redirect URIs listed in the client ID metadata.
+		if !containsLoopbackRedirectURI(metadata.RedirectURIs, parsedRequestedRedirectURI) {
+			return ErrInvalidRedirectURI
+		}
+	default:
 		if !slices.Contains(metadata.RedirectURIs, parsedRequestedRedirectURI.String()) {
 			return ErrInvalidRedirectURI
 		}
@@ -56,14 +86,50 @@ func ValidateClientMetadata(metadata ClientIDMetadata, requestedClientID, reques
 	return nil
 }
 
+// isLoopbackRedirectURI returns true if the redirect URI uses the http scheme and the
+// host is either the IPv4 loopback address 127.0.0.1 or the IPv6 loopback address [::1].
+func isLoopbackRedirectURI(redirectURI *url.URL) bool {
+	if redirectURI.Scheme != "http" {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(redirectURI.Hostname())
+	if err != nil {
+		return false
+	}
+
+	return utilities.IsLoopbackIPLiteral(addr)
+}
+
+// containsLoopbackRedirectURI returns true if the list of redirect URIs contains a loopback
+// redirect URI that matches the requested redirect URI on any port.
+func containsLoopbackRedirectURI(redirectURIs []string, requested *url.URL) bool {
+	for _, redirectURI := range redirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !isLoopbackRedirectURI(parsed) {
+			continue
+		}
+
+		if parsed.Hostname() == requested.Hostname() &&
+			parsed.EscapedPath() == requested.EscapedPath() &&
+			parsed.RawQuery == requested.RawQuery {
+			return true
+		}
+	}
+
+	return false
+}
+
 var (
-	ErrClientIDLoopBackAddr  = errors.New("the hostname of client ID resolves to a loopback address")
+	ErrClientIDDeniedAddr    = errors.New("the hostname of the client ID resolves to a denied address")
 	ErrClientIDInvalidScheme = errors.New("the client ID contains a non-http-based URI scheme")
 )
 
 // ValidateClientID inspects the client's ID to ensure that it doesn't resolve to
-// an IP address in the internal network.
-func ValidateClientID(clientID string) error {
+// an IP address in any of the ranges denied by the guard.
+// Note that this is only an early check; the outbound HTTP client checks the
+// address again when connecting to the client.
+func ValidateClientID(ctx context.Context, guard *outbound.Guard, clientID string) error {
 	// Parse the client ID for inspection.
 	parsedClientID, err := url.Parse(clientID)
 	if err != nil {
@@ -74,28 +140,26 @@ func ValidateClientID(clientID string) error {
 		return ErrClientIDInvalidScheme
 	}
 
-	var addrs []net.IP
+	var addrs []netip.Addr
 
-	addr := net.ParseIP(parsedClientID.Hostname())
-	if addr != nil {
-		addrs = []net.IP{addr}
+	addr, err := netip.ParseAddr(parsedClientID.Hostname())
+	if err == nil {
+		addrs = []netip.Addr{addr}
 	} else {
 		// Resolve the client ID's hostname
 		resolver := net.Resolver{}
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 		defer cancel()
 
-		addrs, err = resolver.LookupIP(ctx, "ip", parsedClientID.Hostname())
+		addrs, err = resolver.LookupNetIP(ctx, "ip", parsedClientID.Hostname())
 		if err != nil {
 			return fmt.Errorf("error resolving the client ID's hostname: %w", err)
 		}
-
 	}
 
 	for _, addr := range addrs {
-		// The IP address must not resolve to a loopback address.
-		if addr.IsLoopback() {
-			return ErrClientIDLoopBackAddr
+		if err := guard.Check(ctx, addr); err != nil {
+			return fmt.Errorf("%w: %w", ErrClientIDDeniedAddr, err)
 		}
 	}
 
@@ -5,10 +5,12 @@
 package discovery_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/outbound"
 )
 
 func TestValidateClientMetadata(t *testing.T) {
@@ -44,6 +46,45 @@ func TestValidateClientMetadata(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "Test Case 3: Loopback redirect URI on an ephemeral port",
+			clientID:    "https://app.website.net/",
+			redirectURI: "http://127.0.0.1:49152/callback",
+			metadata: discovery.ClientIDMetadata{
+				ClientID:   "https://app.website.net/",
+				ClientName: "Desktop App",
+				ClientURI:  "https://app.website.net/",
+				RedirectURIs: []string{
+					"http://127.0.0.1/callback",
+				},
+			},
+		},
+		{
+			name:        "Test Case 4: IPv6 loopback redirect URI on an ephemeral port",
+			clientID:    "https://app.website.net/",
+			redirectURI: "http://[::1]:61000/callback",
+			metadata: discovery.ClientIDMetadata{
+				ClientID:   "https://app.website.net/",
+				ClientName: "Desktop App",
+				ClientURI:  "https://app.website.net/",
+				RedirectURIs: []string{
+					"http://[::1]:8080/callback",
+				},
+			},
+		},
+		{
+			name:        "Test Case 5: Private-use URI scheme redirect URI",
+			clientID:    "https://app.website.net/",
+			redirectURI: "net.website.app:/oauth/callback",
+			metadata: discovery.ClientIDMetadata{
+				ClientID:   "https://app.website.net/",
+				ClientName: "Mobile App",
+				ClientURI:  "https://app.website.net/",
+				RedirectURIs: []string{
+					"net.website.app:/oauth/callback",
+				},
+			},
+		},
 	}
 
 	for _, vc := range validCases {
@@ -98,6 +139,60 @@ func TestValidateClientMetadata(t *testing.T) {
 			},
 			wantError: discovery.ErrInvalidClientURL,
 		},
+		{
+			name:        "Unlisted loopback redirect URI",
+			clientID:    "https://app.website.net/",
+			redirectURI: "http://127.0.0.1:49152/callback",
+			metadata: discovery.ClientIDMetadata{
+				ClientID:   "https://app.website.net/",
+				ClientName: "Desktop App",
+				ClientURI:  "https://app.website.net/",
+				RedirectURIs: []string{
+					"http://127.0.0.1/other",
+				},
+			},
+			wantError: discovery.ErrInvalidRedirectURI,
+		},
+		{
+			name:        "Loopback redirect URI using localhost",
+			clientID:    "https://app.website.net/",
+			redirectURI: "http://localhost:49152/callback",
+			metadata: discovery.ClientIDMetadata{
+				ClientID:   "https://app.website.net/",
+				ClientName: "Desktop App",
+				ClientURI:  "https://app.website.net/",
+				RedirectURIs: []string{
+					"http://localhost/callback",
+				},
+			},
+			wantError: discovery.ErrInvalidRedirectURI,
+		},
+		{
+			name:        "Unlisted private-use URI scheme redirect URI",
+			clientID:    "https://app.website.net/",
+			redirectURI: "net.website.app:/oauth/callback",
+			metadata: discovery.ClientIDMetadata{
+				ClientID:     "https://app.website.net/",
+				ClientName:   "Mobile App",
+				ClientURI:    "https://app.website.net/",
+				RedirectURIs: make([]string, 0),
+			},
+			wantError: discovery.ErrInvalidRedirectURI,
+		},
+		{
+			name:        "Redirect URI using a scheme that is not a private-use URI scheme",
+			clientID:    "https://app.website.net/",
+			redirectURI: "javascript:alert(1)",
+			metadata: discovery.ClientIDMetadata{
+				ClientID:   "https://app.website.net/",
+				ClientName: "Mobile App",
+				ClientURI:  "https://app.website.net/",
+				RedirectURIs: []string{
+					"javascript:alert(1)",
+				},
+			},
+			wantError: discovery.ErrInvalidRedirectURIScheme,
+		},
 	}
 
 	for _, ic := range invalidCases {
@@ -182,38 +277,88 @@ func TestValidateClientID(t *testing.T) {
 		{
 			name:      "Test Case 1: The client ID contains the IPv4 address of 127.0.0.1",
 			clientID:  "http://127.0.0.1/.site/id",
-			wantError: discovery.ErrClientIDLoopBackAddr,
+			wantError: discovery.ErrClientIDDeniedAddr,
 		},
 		{
 			name:      "Test Case 2: The client ID contains the IPv6 address [::1]",
 			clientID:  "http://[::1]/client_id",
-			wantError: discovery.ErrClientIDLoopBackAddr,
+			wantError: discovery.ErrClientIDDeniedAddr,
 		},
 		{
 			name:      "Test Case 3: The client ID's hostname resolves to 127.0.0.1 and/or [::1]",
 			clientID:  "http://localhost:8080/.site/metadata",
-			wantError: discovery.ErrClientIDLoopBackAddr,
+			wantError: discovery.ErrClientIDDeniedAddr,
 		},
 		{
 			name:      "Test Case 4: The client ID contains a loopback IPv4 address",
 			clientID:  "http://127.12.34.56/id",
-			wantError: discovery.ErrClientIDLoopBackAddr,
+			wantError: discovery.ErrClientIDDeniedAddr,
 		},
 		{
 			name:      "Test Case 5: The client ID contains a non-http-based URI scheme",
 			clientID:  "ssh://example.com/id",
 			wantError: discovery.ErrClientIDInvalidScheme,
 		},
+		{
+			name:      "Test Case 6: The client ID contains the address of the cloud metadata service",
+			clientID:  "http://169.254.169.254/latest/meta-data",
+			wantError: discovery.ErrClientIDDeniedAddr,
+		},
+		{
+			name:      "Test Case 7: The client ID contains a private IPv4 address",
+			clientID:  "https://192.168.1.20/id",
+			wantError: discovery.ErrClientIDDeniedAddr,
+		},
+		{
+			name:      "Test Case 8: The client ID contains an IPv4-mapped IPv6 loopback address",
+			clientID:  "http://[::ffff:127.0.0.1]/id",
+			wantError: discovery.ErrClientIDDeniedAddr,
+		},
 	}
 
 	for _, ic := range invalidCases {
 		t.Run(ic.name, testInvalidClientID(ic.name, ic.clientID, ic.wantError))
 	}
+
+	t.Run("Loopback addresses are allowed for localhost client IDs", testLoopbackAllowedClientID)
+}
+
+func testLoopbackAllowedClientID(t *testing.T) {
+	ctx := outbound.WithLoopbackAllowed(context.Background())
+	guard := newTestGuard(t)
+
+	for _, clientID := range []string{"http://127.0.0.1:3000/", "http://[::1]:3000/"} {
+		if err := discovery.ValidateClientID(ctx, guard, clientID); err != nil {
+			t.Errorf(
+				"FAILED test %s: Received an unexpected error validating the localhost client ID %q: %v",
+				t.Name(),
+				clientID,
+				err,
+			)
+		} else {
+			t.Logf("%s passed validation", clientID)
+		}
+	}
+
+	// Only the loopback addresses are allowed.
+	if err := discovery.ValidateClientID(ctx, guard, "http://192.168.1.20/id"); !errors.Is(err, discovery.ErrClientIDDeniedAddr) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error received validating a client ID with a private address.\nwant: %v\n got: %v",
+			t.Name(),
+			discovery.ErrClientIDDeniedAddr,
+			err,
+		)
+	} else {
+		t.Logf(
+			"Expected error received validating a client ID with a private address.\ngot: %v",
+			err,
+		)
+	}
 }
 
 func testValidClientID(testName, clientID string) func(t *testing.T) {
 	return func(t *testing.T) {
-		if err := discovery.ValidateClientID(clientID); err != nil {
+		if err := discovery.ValidateClientID(context.Background(), newTestGuard(t), clientID); err != nil {
 			t.Errorf(
 				"FAILED test %q: Received an unexpected error validating the client's ID: %v",
 				testName,
@@ -227,7 +372,7 @@ func testValidClientID(testName, clientID string) func(t *testing.T) {
 
 func testInvalidClientID(testName, clientID string, wantErr error) func(t *testing.T) {
 	return func(t *testing.T) {
-		err := discovery.ValidateClientID(clientID)
+		err := discovery.ValidateClientID(context.Background(), newTestGuard(t), clientID)
 		if err == nil {
 			t.Errorf(
 				"FAILED test %q: No error was received using invalid client ID",
@@ -252,3 +397,18 @@ func testInvalidClientID(testName, clientID string, wantErr error) func(t *testi
 		}
 	}
 }
+
+func newTestGuard(t *testing.T) *outbound.Guard {
+	t.Helper()
+
+	guard, err := outbound.NewGuard(outbound.DefaultDenyRanges())
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Unable to create the outbound guard: %v",
+			t.Name(),
+			err,
+		)
+	}
+
+	return guard
+}
@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// KeyEnvVar is the environment variable that can hold the encryption keys
+// instead of the key file.
+const KeyEnvVar string = "BEACON_ENCRYPTION_KEY"
+
+const (
+	keySize     = 32
+	keyIDSize   = 8
+	envVersion  = 1
+	wrappedSize = 12 + keySize + 16
+)
+
+// magic starts every envelope. A gob stream never starts with a zero byte and
+// a JSON document never starts with one either, so sealed data is never mistaken
+// for a plaintext record.
+var magic = []byte{0x00, 'B', 'K', 'E'}
+
+var (
+	ErrInvalidKey        = errors.New("each encryption key must be 32 random bytes encoded in base64")
+	ErrNoKeys            = errors.New("no encryption keys were found")
+	ErrKeySourceConflict = errors.New("the encryption keys are set in both the key file and the " + KeyEnvVar + " environment variable")
+	ErrMalformedEnvelope = errors.New("the sealed data is malformed")
+	ErrUnableToOpen      = errors.New("unable to decrypt the sealed data (the data or its context was changed)")
+)
+
+type UnknownKeyError struct {
+	keyID string
+}
+
+func (e UnknownKeyError) Error() string {
+	return "the data was sealed with the unknown encryption key " + e.keyID
+}
+
+// Keyring holds the keys that encrypt the data at rest. The first key is the
+// primary key that seals new data. The other keys are previous keys that are
+// kept so that the data sealed before a key rotation can still be opened.
+//
+// The data is sealed with envelope encryption. Each piece of data is encrypted
+// with its own random data key and the data key is encrypted with the primary key.
+type Keyring struct {
+	primary key
+	keys    map[string]key
+}
+
+type key struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// GenerateKey returns a new random key encoded in base64.
+func GenerateKey() (string, error) {
+	secret := make([]byte, keySize)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("error generating the key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// LoadKeyring loads the keyring from the key file or from the environment
+// variable. A nil keyring is returned if the keys are set in neither.
+func LoadKeyring(keyFile string) (*Keyring, error) {
+	envKeys := os.Getenv(KeyEnvVar)
+
+	switch {
+	case keyFile != "" && envKeys != "":
+		return nil, ErrKeySourceConflict
+	case envKeys != "":
+		return ParseKeyring(envKeys)
+	case keyFile != "":
+		data, err := os.ReadFile(filepath.Clean(keyFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the encryption keys from %q: %w", keyFile, err)
+		}
+
+		return ParseKeyring(string(data))
+	default:
+		return nil, nil
+	}
+}
+
+// ParseKeyring parses the keys separated by new lines, spaces or commas. The first
+// key is the primary key.
+func ParseKeyring(text string) (*Keyring, error) {
+	encodedKeys := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	if len(encodedKeys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	keyring := Keyring{
+		keys: make(map[string]key),
+	}
+
+	for ind, encodedKey := range encodedKeys {
+		secret, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil || len(secret) != keySize {
+			return nil, fmt.Errorf("%w: key %d", ErrInvalidKey, ind+1)
+		}
+
+		aead, err := newAEAD(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(secret)
+
+		parsed := key{
+			id:   sum[:keyIDSize],
+			aead: aead,
+		}
+
+		if ind == 0 {
+			keyring.primary = parsed
+		}
+
+		keyring.keys[string(parsed.id)] = parsed
+	}
+
+	return &keyring, nil
+}
+
+// PrimaryKeyID returns the ID of the primary key. The ID is derived from the key
+// so it is safe to log.
+func (k *Keyring) PrimaryKeyID() string {
+	return hex.EncodeToString(k.primary.id)
+}
+
+// IsSealed returns true if the data is an envelope.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// NeedsResealing returns true if the data is not sealed or if it was sealed with
+// a key other than the primary key.
+func (k *Keyring) NeedsResealing(data []byte) bool {
+	header, _, err := splitEnvelope(data)
+	if err != nil {
+		return true
+	}
+
+	return !bytes.Equal(header[len(magic)+1:], k.primary.id)
+}
+
+// Seal encrypts the data with the primary key. The context is authenticated but
+// not encrypted and the same context must be given to open the data. It binds the
+// sealed data to where it is stored so that it cannot be moved elsewhere.
+//
+// The envelope is laid out as the magic bytes, the version, the primary key's ID,
+// the encrypted data key and the encrypted data.
+func (k *Keyring) Seal(data, context []byte) ([]byte, error) {
+	dataKey := make([]byte, keySize)
+
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("error generating the data key: %w", err)
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(magic)+1+keyIDSize)
+	header = append(header, magic...)
+	header = append(header, envVersion)
+	header = append(header, k.primary.id...)
+
+	envelope := bytes.Clone(header)
+
+	envelope, err = seal(k.primary.aead, envelope, dataKey, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// The data is bound to the header, the encrypted data key and the context.
+	additionalData := append(bytes.Clone(envelope), context...)
+
+	return seal(dataAEAD, envelope, data, additionalData)
+}
+
+// Open decrypts the data sealed by Seal.
+func (k *Keyring) Open(envelope, context []byte) ([]byte, error) {
+	header, body, err := splitEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := header[len(magic)+1:]
+
+	kek, ok := k.keys[string(keyID)]
+	if !ok {
+		return nil, UnknownKeyError{keyID: hex.EncodeToString(keyID)}
+	}
+
+	wrappedKey, sealedData := body[:wrappedSize], body[wrappedSize:]
+
+	dataKey, err := open(kek.aead, wrappedKey, header)
+	if err != nil {
+		return nil, err
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	additionalData := append(append(bytes.Clone(header), wrappedKey...), context...)
+
+	return open(dataAEAD, sealedData, additionalData)
+}
+
+// splitEnvelope returns the header and the body of the envelope.
+func splitEnvelope(envelope []byte) ([]byte, []byte, error) {
+	headerSize := len(magic) + 1 + keyIDSize
+
+	if !IsSealed(envelope) || len(envelope) < headerSize+wrappedSize {
+		return nil, nil, ErrMalformedEnvelope
+	}
+
+	if envelope[len(magic)] != envVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrMalformedEnvelope, envelope[len(magic)])
+	}
+
+	return envelope[:headerSize], envelope[headerSize:], nil
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the cipher: %w", err)
+	}
+
+	return aead, nil
+}
+
+// seal appends the random nonce and the encrypted plaintext to dst.
+func seal(aead cipher.AEAD, dst, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating the nonce: %w", err)
+	}
+
+	dst = append(dst, nonce...)
+
+	return aead.Seal(dst, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrMalformedEnvelope
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrUnableToOpen
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package encryption_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/encryption"
+)
+
+func TestKeyring(t *testing.T) {
+	t.Parallel()
+
+	oldKey := newTestKey(t)
+	newKey := newTestKey(t)
+
+	oldKeyring := newTestKeyring(t, oldKey)
+	rotatedKeyring := newTestKeyring(t, newKey+"\n"+oldKey)
+	newKeyring := newTestKeyring(t, newKey)
+
+	data := []byte("pip@pippins.example.me")
+	context := []byte("profiles/https://pippins.example.me/")
+
+	sealed, err := oldKeyring.Seal(data, context)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to seal the data: %v", t.Name(), err)
+	}
+
+	if !encryption.IsSealed(sealed) || bytes.Contains(sealed, data) {
+		t.Fatalf("FAILED test %s: The data was not sealed.\ngot: %q", t.Name(), sealed)
+	}
+
+	// The data sealed with a previous key is opened after the rotation.
+	opened, err := rotatedKeyring.Open(sealed, context)
+	if err != nil || !bytes.Equal(opened, data) {
+		t.Errorf(
+			"FAILED test %s: Unexpected data opened with the rotated keyring.\nwant: %q\ngot: %q\nerror: %v",
+			t.Name(),
+			data,
+			opened,
+			err,
+		)
+	} else {
+		t.Logf("Expected data opened with the rotated keyring.\ngot: %q", opened)
+	}
+
+	if !rotatedKeyring.NeedsResealing(sealed) || !rotatedKeyring.NeedsResealing(data) {
+		t.Errorf("FAILED test %s: The data sealed with the previous key does not need resealing.", t.Name())
+	}
+
+	resealed, err := rotatedKeyring.Seal(opened, context)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to reseal the data: %v", t.Name(), err)
+	}
+
+	if rotatedKeyring.NeedsResealing(resealed) {
+		t.Errorf("FAILED test %s: The data sealed with the primary key needs resealing.", t.Name())
+	}
+
+	// The previous key can be removed once the data is resealed.
+	if opened, err := newKeyring.Open(resealed, context); err != nil || !bytes.Equal(opened, data) {
+		t.Errorf("FAILED test %s: Unable to open the resealed data.\ngot: %q\nerror: %v", t.Name(), opened, err)
+	}
+
+	if _, err := newKeyring.Open(sealed, context); !errors.As(err, new(encryption.UnknownKeyError)) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error opening the data sealed with a removed key.\ngot: %v",
+			t.Name(),
+			err,
+		)
+	} else {
+		t.Logf("Expected error opening the data sealed with a removed key.\ngot: %v", err)
+	}
+
+	if _, err := oldKeyring.Open(sealed, []byte("profiles/https://samwise.example.me/")); !errors.Is(err, encryption.ErrUnableToOpen) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error opening the data with a different context.\nwant: %v\ngot: %v",
+			t.Name(),
+			encryption.ErrUnableToOpen,
+			err,
+		)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 0xff
+
+	if _, err := oldKeyring.Open(tampered, context); !errors.Is(err, encryption.ErrUnableToOpen) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error opening the tampered data.\nwant: %v\ngot: %v",
+			t.Name(),
+			encryption.ErrUnableToOpen,
+			err,
+		)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		text    string
+		wantErr error
+	}{
+		{
+			name:    "No keys",
+			text:    " \n",
+			wantErr: encryption.ErrNoKeys,
+		},
+		{
+			name:    "Key is not base64",
+			text:    "not a key",
+			wantErr: encryption.ErrInvalidKey,
+		},
+		{
+			name:    "Key is too short",
+			text:    "c2hvcnQ=",
+			wantErr: encryption.ErrInvalidKey,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := encryption.ParseKeyring(testCase.text); !errors.Is(err, testCase.wantErr) {
+				t.Errorf(
+					"FAILED test %s: Unexpected error parsing the keyring.\nwant: %v\ngot: %v",
+					t.Name(),
+					testCase.wantErr,
+					err,
+				)
+			} else {
+				t.Logf("Expected error received.\ngot: %v", err)
+			}
+		})
+	}
+}
+
+func newTestKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to generate the key: %v", t.Name(), err)
+	}
+
+	return key
+}
+
+func newTestKeyring(t *testing.T, keys string) *encryption.Keyring {
+	t.Helper()
+
+	keyring, err := encryption.ParseKeyring(keys)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to parse the keyring: %v", t.Name(), err)
+	}
+
+	return keyring
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+// adminSocketExists returns true if the admin socket is configured and the
+// running server is listening on it.
+func adminSocketExists(socketPath string) (bool, error) {
+	if socketPath == "" {
+		return false, nil
+	}
+
+	socketExists, err := utilities.FileExists(socketPath)
+	if err != nil {
+		return false, fmt.Errorf("error checking if the admin socket exists: %w", err)
+	}
+
+	return socketExists, nil
+}
+
+// fetchFromAdminSocket requests the path from the admin socket of the running server
+// and passes the response body to the save function.
+func fetchFromAdminSocket(socketPath, path string, save func(io.Reader) error) error {
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	request, err := http.NewRequestWithContext(
+		context.Background(),
+		http.MethodGet,
+		"http://beacon"+path,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating the request to the admin socket: %w", err)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error sending the request to the admin socket: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from the admin socket: %s", response.Status)
+	}
+
+	return save(response.Body)
+}
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package actions
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/config"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/encryption"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/server"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+var ErrMissingBackupFile = errors.New("please set the path to the backup file with --file")
+
+// DB manages the database. The resources are:
+//
+//   - migrate: runs the pending schema migrations, or lists them with --dry-run.
+//   - backup: backs up the database to the file. The live database is backed up
+//     through the admin socket when Beacon is running.
+//   - restore: restores the database from the file after checking its integrity.
+//     Beacon must be stopped first.
+//   - generate-key: prints a new random encryption key.
+//   - rotate-key: encrypts the sensitive records again with the primary encryption
+//     key so that the previous keys can be removed. Beacon must be stopped first.
+type DB struct {
+	*flag.FlagSet
+
+	configPath string
+	dryRun     bool
+	file       string
+}
+
+func NewDB() *DB {
+	db := DB{
+		FlagSet: flag.NewFlagSet("db", flag.ExitOnError),
+	}
+
+	db.StringVar(&db.configPath, "config", "", "The path to the config file")
+	db.BoolVar(&db.dryRun, "dry-run", false, "Print the pending migrations without running them")
+	db.StringVar(&db.file, "file", "", "The path to the backup file")
+
+	return &db
+}
+
+func (a *DB) Execute(args []string) error {
+	resourceArgs, err := utilities.ParseArgs(args)
+	if err != nil {
+		return fmt.Errorf("(%s) args parsing error: %w", a.Name(), err)
+	}
+
+	if err := a.Parse(resourceArgs.Args); err != nil {
+		return fmt.Errorf("(%s) flag parsing error: %w", a.Name(), err)
+	}
+
+	switch resourceArgs.Name {
+	case "migrate":
+		return a.migrate()
+	case "backup":
+		return a.backup()
+	case "restore":
+		return a.restore()
+	case "generate-key":
+		return a.generateKey()
+	case "rotate-key":
+		return a.rotateKey()
+	default:
+		return UnrecognisedResouceError{resource: resourceArgs.Name}
+	}
+}
+
+func (a *DB) migrate() error {
+	cfg, err := a.config()
+	if err != nil {
+		return err
+	}
+
+	boltdb, err := database.Open(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("error opening the database: %w", err)
+	}
+	defer boltdb.Close()
+
+	version, err := database.SchemaVersion(boltdb)
+	if err != nil {
+		return fmt.Errorf("error getting the schema version: %w", err)
+	}
+
+	if a.dryRun {
+		pending, err := database.PendingMigrations(boltdb)
+		if err != nil {
+			return fmt.Errorf("error getting the pending migrations: %w", err)
+		}
+
+		printMigrations(
+			fmt.Sprintf("Schema version: %d\nPending migrations:", version),
+			"There are no pending migrations.",
+			pending,
+		)
+
+		return nil
+	}
+
+	applied, err := database.Migrate(boltdb)
+	if err != nil {
+		printMigrations("Migrations that ran before the error:", "", applied)
+
+		return fmt.Errorf("error migrating the database: %w", err)
+	}
+
+	printMigrations(
+		fmt.Sprintf("Migrated the database from schema version %d to %d:", version, database.LatestSchemaVersion()),
+		"The database is already up to date.",
+		applied,
+	)
+
+	return nil
+}
+
+func (a *DB) backup() error {
+	if a.file == "" {
+		return ErrMissingBackupFile
+	}
+
+	cfg, err := a.config()
+	if err != nil {
+		return err
+	}
+
+	socketExists, err := adminSocketExists(cfg.Admin.SocketPath)
+	if err != nil {
+		return err
+	}
+
+	if socketExists {
+		if err := fetchFromAdminSocket(cfg.Admin.SocketPath, server.AdminPathBackup, func(reader io.Reader) error {
+			return database.SaveBackup(reader, a.file)
+		}); err != nil {
+			return fmt.Errorf("error saving the backup: %w", err)
+		}
+
+		_, _ = os.Stdout.WriteString("Backed up the live database to " + a.file + "\n")
+
+		return nil
+	}
+
+	boltdb, err := database.Open(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf(
+			"error opening the database (set the admin socket path to back up the database while Beacon is running): %w",
+			err,
+		)
+	}
+	defer boltdb.Close()
+
+	if err := database.BackupToFile(boltdb, a.file); err != nil {
+		return fmt.Errorf("error backing up the database: %w", err)
+	}
+
+	_, _ = os.Stdout.WriteString("Backed up the database to " + a.file + "\n")
+
+	return nil
+}
+
+func (a *DB) restore() error {
+	if a.file == "" {
+		return ErrMissingBackupFile
+	}
+
+	cfg, err := a.config()
+	if err != nil {
+		return err
+	}
+
+	replacedPath, err := database.Restore(a.file, cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("error restoring the database: %w", err)
+	}
+
+	_, _ = os.Stdout.WriteString("Restored the database from " + a.file + "\n")
+
+	if replacedPath != "" {
+		_, _ = os.Stdout.WriteString("The previous database was moved to " + replacedPath + "\n")
+	}
+
+	return nil
+}
+
+func (a *DB) generateKey() error {
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	_, _ = os.Stdout.WriteString(key + "\n")
+
+	return nil
+}
+
+func (a *DB) rotateKey() error {
+	cfg, err := config.NewConfig(a.configPath)
+	if err != nil {
+		return fmt.Errorf("error loading the configuration: %w", err)
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return fmt.Errorf("error opening the database (please stop Beacon before rotating the key): %w", err)
+	}
+	defer store.Close()
+
+	count, err := store.ReencryptRecords()
+	if err != nil {
+		return fmt.Errorf("error encrypting the records: %w", err)
+	}
+
+	_, _ = os.Stdout.WriteString(
+		"Encrypted " + strconv.Itoa(count) + " record(s) with the primary key. " +
+			"The previous keys can now be removed.\n",
+	)
+
+	return nil
+}
+
+// openStore opens the configured database with the configured encryption keys.
+func openStore(cfg config.Config) (database.Store, error) {
+	keyring, err := encryption.LoadKeyring(cfg.Encryption.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the encryption keys: %w", err)
+	}
+
+	return database.OpenStore(cfg.Database.Driver, cfg.Database.Path, keyring)
+}
+
+// config loads the configuration. The database must use the bolt driver
+// because migrations, backups and restores are only supported by bolt.
+func (a *DB) config() (config.Config, error) {
+	cfg, err := config.NewConfig(a.configPath)
+	if err != nil {
+		return config.Config{}, fmt.Errorf("error loading the configuration: %w", err)
+	}
+
+	if cfg.Database.Driver != database.DriverBolt {
+		return config.Config{}, database.ErrRequiresBoltDB
+	}
+
+	return cfg, nil
+}
+
+func printMigrations(heading, none string, migrations []database.Migration) {
+	if len(migrations) == 0 {
+		if none != "" {
+			_, _ = os.Stdout.WriteString(none + "\n")
+		}
+
+		return
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString(heading + "\n")
+
+	tableWriter := tabwriter.NewWriter(&builder, 0, 4, 2, ' ', 0)
+
+	for _, migration := range migrations {
+		_, _ = tableWriter.Write([]byte("  " + strconv.Itoa(migration.Version) + "\t" + migration.Description + "\n"))
+	}
+
+	_ = tableWriter.Flush()
+
+	_, _ = os.Stdout.WriteString(builder.String())
+}
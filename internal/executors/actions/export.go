@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package actions
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/config"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/server"
+)
+
+var ErrMissingArchiveFile = errors.New("please set the path to the archive file with --file")
+
+// Export exports the profiles, clients, tokens and settings to a portable JSON
+// archive. The live database is exported through the admin socket when Beacon
+// is running. The archive is not encrypted so the access tokens received from
+// other servers are only exported when they are explicitly included.
+type Export struct {
+	*flag.FlagSet
+
+	configPath            string
+	file                  string
+	includeReceivedTokens bool
+}
+
+func NewExport() *Export {
+	export := Export{
+		FlagSet: flag.NewFlagSet("export", flag.ExitOnError),
+	}
+
+	export.StringVar(&export.configPath, "config", "", "The path to the config file")
+	export.StringVar(&export.file, "file", "", "The path to the archive file")
+	export.BoolVar(
+		&export.includeReceivedTokens,
+		"include-received-tokens",
+		false,
+		"Include the access tokens received from other servers. The archive is not encrypted so "+
+			"these tokens are written in plain text and anyone with the archive can use them",
+	)
+
+	return &export
+}
+
+func (a *Export) Execute(args []string) error {
+	if err := a.Parse(args); err != nil {
+		return fmt.Errorf("(%s) flag parsing error: %w", a.Name(), err)
+	}
+
+	if a.file == "" {
+		return ErrMissingArchiveFile
+	}
+
+	cfg, err := config.NewConfig(a.configPath)
+	if err != nil {
+		return fmt.Errorf("error loading the configuration: %w", err)
+	}
+
+	socketExists, err := adminSocketExists(cfg.Admin.SocketPath)
+	if err != nil {
+		return err
+	}
+
+	if socketExists {
+		path := server.AdminPathExport
+
+		if a.includeReceivedTokens {
+			path += "?" + url.Values{server.AdminQueryIncludeReceivedTokens: {"true"}}.Encode()
+		}
+
+		if err := fetchFromAdminSocket(cfg.Admin.SocketPath, path, func(reader io.Reader) error {
+			return database.SaveArchive(reader, a.file)
+		}); err != nil {
+			return fmt.Errorf("error saving the archive: %w", err)
+		}
+
+		_, _ = os.Stdout.WriteString("Exported the live database to " + a.file + "\n")
+
+		return nil
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return fmt.Errorf(
+			"error opening the database (set the admin socket path to export the database while Beacon is running): %w",
+			err,
+		)
+	}
+	defer store.Close()
+
+	if err := database.ExportToFile(store, a.file, database.ExportOptions{
+		IncludeReceivedTokens: a.includeReceivedTokens,
+	}); err != nil {
+		return fmt.Errorf("error exporting the database: %w", err)
+	}
+
+	_, _ = os.Stdout.WriteString("Exported the database to " + a.file + "\n")
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package actions
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/config"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+// Import imports the records from a JSON archive created with the export action.
+// The archive is validated before anything is written to the database. Beacon
+// must be stopped first.
+type Import struct {
+	*flag.FlagSet
+
+	configPath string
+	file       string
+	mode       string
+	dryRun     bool
+}
+
+func NewImport() *Import {
+	imp := Import{
+		FlagSet: flag.NewFlagSet("import", flag.ExitOnError),
+	}
+
+	imp.StringVar(&imp.configPath, "config", "", "The path to the config file")
+	imp.StringVar(&imp.file, "file", "", "The path to the archive file")
+	imp.StringVar(
+		&imp.mode,
+		"mode",
+		string(database.ImportModeMerge),
+		"The import mode: 'merge' adds the records to the database and 'replace' replaces the records in the database",
+	)
+	imp.BoolVar(&imp.dryRun, "dry-run", false, "Validate the archive without importing it")
+
+	return &imp
+}
+
+func (a *Import) Execute(args []string) error {
+	if err := a.Parse(args); err != nil {
+		return fmt.Errorf("(%s) flag parsing error: %w", a.Name(), err)
+	}
+
+	if a.file == "" {
+		return ErrMissingArchiveFile
+	}
+
+	mode := database.ImportMode(a.mode)
+
+	if mode != database.ImportModeMerge && mode != database.ImportModeReplace {
+		return fmt.Errorf("%w: %s", database.ErrUnknownImportMode, a.mode)
+	}
+
+	archive, err := database.ReadArchiveFile(a.file)
+	if err != nil {
+		return fmt.Errorf("error reading the archive: %w", err)
+	}
+
+	summary := fmt.Sprintf(
+		"%d profile(s), %d client(s), %d access token(s) and %d received token(s)",
+		len(archive.Profiles),
+		len(archive.Clients),
+		len(archive.AccessTokens),
+		len(archive.ReceivedTokens),
+	)
+
+	if a.dryRun {
+		_, _ = os.Stdout.WriteString("The archive is valid and contains " + summary + ".\n")
+
+		return nil
+	}
+
+	cfg, err := config.NewConfig(a.configPath)
+	if err != nil {
+		return fmt.Errorf("error loading the configuration: %w", err)
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return fmt.Errorf("error opening the database (please stop Beacon before importing): %w", err)
+	}
+	defer store.Close()
+
+	// The records are written in the format of the latest schema version.
+	if boltdb, ok := database.BoltDB(store); ok {
+		if _, err := database.Migrate(boltdb); err != nil {
+			return fmt.Errorf("error migrating the database: %w", err)
+		}
+	}
+
+	if err := store.Import(archive, mode); err != nil {
+		return fmt.Errorf("error importing the archive: %w", err)
+	}
+
+	_, _ = os.Stdout.WriteString("Imported " + summary + " from " + a.file + " (" + a.mode + " mode).\n")
+
+	return nil
+}
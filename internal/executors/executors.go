@@ -27,6 +27,9 @@ func (e UnrecognisedActionError) Error() string {
 
 func Execute(args []string) error {
 	actionMap := map[string]actions.Executor{
+		"db":      actions.NewDB(),
+		"export":  actions.NewExport(),
+		"import":  actions.NewImport(),
 		"serve":   actions.NewServe(),
 		"version": actions.NewVersion(),
 	}
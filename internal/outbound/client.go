@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package outbound
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ResponseTooLargeError struct {
+	limit int64
+}
+
+func (e ResponseTooLargeError) Error() string {
+	return "the response body is larger than the limit of " + strconv.FormatInt(e.limit, 10) + " bytes"
+}
+
+type Settings struct {
+	ConnectTimeout  time.Duration
+	Timeout         time.Duration
+	MaxResponseSize int64
+}
+
+// NewClient creates the HTTP client used for all outbound requests. Every connection is
+// checked against the guard's denied IP ranges, redirects are not followed, proxies
+// from the environment are ignored and the size of the response body is limited.
+func NewClient(guard *Guard, settings Settings) *http.Client {
+	dialer := net.Dialer{
+		Timeout:        settings.ConnectTimeout,
+		ControlContext: guard.control,
+	}
+
+	transport := http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   settings.ConnectTimeout,
+		ResponseHeaderTimeout: settings.Timeout,
+	}
+
+	return &http.Client{
+		Transport: &limitedTransport{
+			next:  &transport,
+			limit: settings.MaxResponseSize,
+		},
+		// Ensure that the HTTP Client does not follow redirects
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: settings.Timeout,
+	}
+}
+
+// limitedTransport limits the size of the body of every response.
+type limitedTransport struct {
+	next  http.RoundTripper
+	limit int64
+}
+
+func (t *limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.limit <= 0 {
+		return response, nil
+	}
+
+	if response.ContentLength > t.limit {
+		_ = response.Body.Close()
+
+		return nil, ResponseTooLargeError{limit: t.limit}
+	}
+
+	response.Body = &limitedBody{
+		ReadCloser: response.Body,
+		remaining:  t.limit,
+		limit:      t.limit,
+	}
+
+	return response, nil
+}
+
+// limitedBody returns an error once more than the limit has been read from the body.
+type limitedBody struct {
+	io.ReadCloser
+
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(data []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ResponseTooLargeError{limit: b.limit}
+	}
+
+	// Read one more byte than the remaining limit to detect bodies
+	// that are larger than the limit.
+	if int64(len(data)) > b.remaining+1 {
+		data = data[:b.remaining+1]
+	}
+
+	read, err := b.ReadCloser.Read(data)
+	b.remaining -= int64(read)
+
+	if b.remaining < 0 {
+		return read + int(b.remaining), ResponseTooLargeError{limit: b.limit}
+	}
+
+	return read, err
+}
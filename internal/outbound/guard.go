@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+type DeniedAddressError struct {
+	addr   netip.Addr
+	prefix netip.Prefix
+}
+
+func (e DeniedAddressError) Error() string {
+	return "the address " + e.addr.String() + " is in the denied range " + e.prefix.String()
+}
+
+// The IPv6 ranges of the addresses that embed an IPv4 address. The embedded IPv4
+// address is checked as well so that the denied IPv4 ranges cannot be reached
+// through a NAT64 gateway or a 6to4 relay.
+var (
+	nat64Prefix     = netip.MustParsePrefix("64:ff9b::/96") // RFC 6052
+	sixToFourPrefix = netip.MustParsePrefix("2002::/16")    // RFC 3056
+)
+
+type loopbackContextKey struct{}
+
+// WithLoopbackAllowed returns a copy of the context which allows connections to the
+// loopback addresses even when they are in the denied ranges. This is used for the
+// localhost client IDs that are explicitly allowed for local development.
+func WithLoopbackAllowed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loopbackContextKey{}, true)
+}
+
+func loopbackAllowed(ctx context.Context) bool {
+	allowed, ok := ctx.Value(loopbackContextKey{}).(bool)
+
+	return ok && allowed
+}
+
+// Guard checks the IP addresses that Beacon connects to against a list of denied ranges.
+type Guard struct {
+	deny []netip.Prefix
+}
+
+// NewGuard creates a new guard which denies the given IP ranges. Each range must be written
+// in CIDR notation.
+func NewGuard(denyRanges []string) (*Guard, error) {
+	guard := Guard{
+		deny: make([]netip.Prefix, len(denyRanges)),
+	}
+
+	for ind := range denyRanges {
+		prefix, err := netip.ParsePrefix(denyRanges[ind])
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the IP range %q: %w", denyRanges[ind], err)
+		}
+
+		guard.deny[ind] = prefix.Masked()
+	}
+
+	return &guard, nil
+}
+
+// DefaultDenyRanges returns the IP ranges that are denied by default. These include the
+// loopback, private, link-local (including the cloud metadata services), shared, multicast,
+// documentation and reserved ranges.
+func DefaultDenyRanges() []string {
+	return []string{
+		// IPv4
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+
+		// IPv6
+		"::/128",
+		"::1/128",
+		"64:ff9b:1::/48",
+		"100::/64",
+		"2001:db8::/32",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+}
+
+// Check returns an error if the IP address is in one of the denied ranges. The IPv4
+// address embedded in a NAT64 or 6to4 address must not be in a denied range either.
+// Loopback addresses are permitted if they are allowed in the context.
+func (g *Guard) Check(ctx context.Context, addr netip.Addr) error {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() && loopbackAllowed(ctx) {
+		return nil
+	}
+
+	if err := g.checkRanges(addr); err != nil {
+		return err
+	}
+
+	if embedded, ok := embeddedIPv4(addr); ok {
+		return g.checkRanges(embedded)
+	}
+
+	return nil
+}
+
+func (g *Guard) checkRanges(addr netip.Addr) error {
+	for _, prefix := range g.deny {
+		if prefix.Contains(addr) {
+			return DeniedAddressError{addr: addr, prefix: prefix}
+		}
+	}
+
+	return nil
+}
+
+// embeddedIPv4 returns the IPv4 address embedded in a NAT64 or 6to4 address.
+// The boolean value is false if the address does not embed an IPv4 address.
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	bytes := addr.As16()
+
+	switch {
+	case nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[12:16])), true
+	case sixToFourPrefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[2:6])), true
+	default:
+		return netip.Addr{}, false
+	}
+}
+
+// control checks the address of every connection just before it is made so that
+// the check is performed against the IP address that is actually dialled.
+func (g *Guard) control(ctx context.Context, _, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("unable to split the host and port of %q: %w", address, err)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("unable to parse the IP address %q: %w", host, err)
+	}
+
+	return g.Check(ctx, addr)
+}
@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package outbound_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/outbound"
+)
+
+func TestGuard(t *testing.T) {
+	t.Parallel()
+
+	guard, err := outbound.NewGuard(outbound.DefaultDenyRanges())
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Unable to create the guard: %v",
+			t.Name(),
+			err,
+		)
+	}
+
+	deniedAddrs := []string{
+		"127.0.0.1",
+		"10.1.2.3",
+		"172.20.0.5",
+		"192.168.0.1",
+		"169.254.169.254",
+		"100.64.0.1",
+		"0.0.0.0",
+		"::1",
+		"::ffff:127.0.0.1",
+		"fe80::1",
+		"fd12:3456::1",
+		"64:ff9b::a9fe:a9fe",
+		"64:ff9b::7f00:1",
+		"2002:a00:1::1",
+		"2002:c0a8:1::1",
+	}
+
+	for _, addr := range deniedAddrs {
+		err := guard.Check(context.Background(), netip.MustParseAddr(addr))
+
+		deniedErr := outbound.DeniedAddressError{}
+		if !errors.As(err, &deniedErr) {
+			t.Errorf(
+				"FAILED test %s: The address %s was not denied.\ngot: %v",
+				t.Name(),
+				addr,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received for %s.\ngot: %q", addr, err.Error())
+		}
+	}
+
+	allowedAddrs := []string{
+		"34.154.39.123",
+		"93.184.215.14",
+		"2606:4700::6810:84e5",
+		"64:ff9b::5db8:d70e",
+		"2002:5db8:d70e::1",
+	}
+
+	for _, addr := range allowedAddrs {
+		if err := guard.Check(context.Background(), netip.MustParseAddr(addr)); err != nil {
+			t.Errorf(
+				"FAILED test %s: The address %s was denied.\ngot: %v",
+				t.Name(),
+				addr,
+				err,
+			)
+		} else {
+			t.Logf("The address %s was allowed.", addr)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("beacon", 100)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(body))
+	}))
+	t.Cleanup(testServer.Close)
+
+	t.Run("Connections to denied addresses are refused", testClientDeniedAddress(testServer.URL))
+	t.Run("Responses within the size limit are read", testClientSizeLimit(testServer.URL, int64(len(body)), false))
+	t.Run("Responses larger than the size limit are rejected", testClientSizeLimit(testServer.URL, 100, true))
+}
+
+func testClientDeniedAddress(url string) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		guard, err := outbound.NewGuard([]string{"127.0.0.0/8", "::1/128"})
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to create the guard: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		client := outbound.NewClient(guard, testSettings(1<<20))
+
+		_, err = get(client, url)
+
+		deniedErr := outbound.DeniedAddressError{}
+		if !errors.As(err, &deniedErr) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error received after connecting to a denied address.\nwant: %T\ngot: %v",
+				t.Name(),
+				deniedErr,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received.\ngot: %q", err.Error())
+		}
+	}
+}
+
+func testClientSizeLimit(url string, limit int64, wantErr bool) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		guard, err := outbound.NewGuard([]string{})
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to create the guard: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		client := outbound.NewClient(guard, testSettings(limit))
+
+		data, err := get(client, url)
+
+		if !wantErr {
+			if err != nil {
+				t.Fatalf(
+					"FAILED test %s: Received an error reading the response.\ngot: %v",
+					t.Name(),
+					err,
+				)
+			}
+
+			if int64(len(data)) != limit {
+				t.Errorf(
+					"FAILED test %s: Unexpected number of bytes read.\nwant: %d\ngot: %d",
+					t.Name(),
+					limit,
+					len(data),
+				)
+			} else {
+				t.Logf("Expected number of bytes read.\ngot: %d", len(data))
+			}
+
+			return
+		}
+
+		tooLargeErr := outbound.ResponseTooLargeError{}
+		if !errors.As(err, &tooLargeErr) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error received after reading a large response.\nwant: %T\ngot: %v",
+				t.Name(),
+				tooLargeErr,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received.\ngot: %q", err.Error())
+		}
+	}
+}
+
+func testSettings(limit int64) outbound.Settings {
+	return outbound.Settings{
+		ConnectTimeout:  1 * time.Second,
+		Timeout:         5 * time.Second,
+		MaxResponseSize: limit,
+	}
+}
+
+func get(client *http.Client, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	return io.ReadAll(response.Body)
+}
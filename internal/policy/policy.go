@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingClientID = errors.New("the client rule is missing the client ID")
+	ErrInvalidClientID = errors.New("unable to get the host from the client ID")
+)
+
+type InvalidHostPatternError struct {
+	pattern string
+}
+
+func (e InvalidHostPatternError) Error() string {
+	return "invalid host pattern '" + e.pattern + "'"
+}
+
+type ClientDeniedError struct {
+	host    string
+	pattern string
+}
+
+func (e ClientDeniedError) Error() string {
+	return "the client host '" + e.host + "' matches the denied pattern '" + e.pattern + "'"
+}
+
+type ClientNotAllowedError struct {
+	host string
+}
+
+func (e ClientNotAllowedError) Error() string {
+	return "the client host '" + e.host + "' does not match any of the allowed patterns"
+}
+
+// Rule is the policy that applies to a single client.
+type Rule struct {
+	// ClientID is the canonicalized client ID that the rule applies to.
+	ClientID string
+
+	// MaxScopes is the set of scopes that the client can be granted.
+	// The client can request any scope if this is empty.
+	MaxScopes []string
+
+	// TokenLifetime is the lifetime of the access tokens issued to the client.
+	// The default lifetime is used if this is zero.
+	TokenLifetime time.Duration
+
+	// Trusted clients are first-party clients that skip the consent page.
+	Trusted bool
+}
+
+// Policy controls which clients can use Beacon and on what terms.
+// Patterns match either the exact host of the client ID or, if the pattern
+// starts with '*.', any subdomain of the host that follows.
+type Policy struct {
+	allow                []string
+	deny                 []string
+	rules                map[string]Rule
+	defaultTokenLifetime time.Duration
+}
+
+// NewPolicy creates a new client policy. If there are multiple rules for the
+// same client then the last rule is used.
+func NewPolicy(allow, deny []string, rules []Rule, defaultTokenLifetime time.Duration) (Policy, error) {
+	for _, pattern := range slices.Concat(allow, deny) {
+		if err := ValidateHostPattern(pattern); err != nil {
+			return Policy{}, err
+		}
+	}
+
+	policy := Policy{
+		allow:                normalizePatterns(allow),
+		deny:                 normalizePatterns(deny),
+		rules:                make(map[string]Rule),
+		defaultTokenLifetime: defaultTokenLifetime,
+	}
+
+	for _, rule := range rules {
+		if rule.ClientID == "" {
+			return Policy{}, ErrMissingClientID
+		}
+
+		policy.rules[rule.ClientID] = rule
+	}
+
+	return policy, nil
+}
+
+// CheckClient returns an error if the client is not permitted to use Beacon.
+// Deny patterns take precedence over allow patterns. All clients are allowed
+// if there are no allow patterns.
+func (p Policy) CheckClient(clientID string) error {
+	parsedClientID, err := url.Parse(clientID)
+	if err != nil || parsedClientID.Hostname() == "" {
+		return ErrInvalidClientID
+	}
+
+	host := strings.ToLower(parsedClientID.Hostname())
+
+	for _, pattern := range p.deny {
+		if matchHost(pattern, host) {
+			return ClientDeniedError{host: host, pattern: pattern}
+		}
+	}
+
+	if len(p.allow) == 0 {
+		return nil
+	}
+
+	for _, pattern := range p.allow {
+		if matchHost(pattern, host) {
+			return nil
+		}
+	}
+
+	return ClientNotAllowedError{host: host}
+}
+
+// Rule returns the rule for the client. The boolean value is false if there
+// is no rule for the client.
+func (p Policy) Rule(clientID string) (Rule, bool) {
+	rule, ok := p.rules[clientID]
+
+	return rule, ok
+}
+
+// LimitScopes returns the scopes that the client can be granted from the
+// given list of scopes.
+func (p Policy) LimitScopes(clientID string, scopes []string) []string {
+	rule, ok := p.rules[clientID]
+	if !ok || len(rule.MaxScopes) == 0 {
+		return scopes
+	}
+
+	limited := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		if slices.Contains(rule.MaxScopes, scope) {
+			limited = append(limited, scope)
+		}
+	}
+
+	return limited
+}
+
+// TokenLifetime returns the lifetime of the access tokens issued to the client.
+func (p Policy) TokenLifetime(clientID string) time.Duration {
+	rule, ok := p.rules[clientID]
+	if !ok || rule.TokenLifetime <= 0 {
+		return p.defaultTokenLifetime
+	}
+
+	return rule.TokenLifetime
+}
+
+// Trusted returns true if the client is trusted to skip the consent page.
+func (p Policy) Trusted(clientID string) bool {
+	rule, ok := p.rules[clientID]
+
+	return ok && rule.Trusted
+}
+
+// ValidateHostPattern validates the host pattern used in the allow and deny lists.
+func ValidateHostPattern(pattern string) error {
+	hostPattern := regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+	if !hostPattern.MatchString(strings.ToLower(pattern)) {
+		return InvalidHostPatternError{pattern: pattern}
+	}
+
+	return nil
+}
+
+func normalizePatterns(patterns []string) []string {
+	normalized := make([]string, len(patterns))
+
+	for ind := range patterns {
+		normalized[ind] = strings.ToLower(patterns[ind])
+	}
+
+	return normalized
+}
+
+// MatchesAnyHost returns true if the host matches any of the host
+// patterns (e.g. "app.example.org" or "*.example.org").
+func MatchesAnyHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+
+	for _, pattern := range normalizePatterns(patterns) {
+		if matchHost(pattern, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchHost(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+
+	return pattern == host
+}
+
+// ParseList parses a list of values separated by whitespace or commas
+// such as the host patterns and scopes entered in the settings page.
+func ParseList(input string) []string {
+	return strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+}
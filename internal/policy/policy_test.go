@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/policy"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	clientPolicy, err := policy.NewPolicy(
+		[]string{"*.example.org", "app.example.net"},
+		[]string{"untrusted.example.org"},
+		[]policy.Rule{
+			{
+				ClientID:      "https://app.example.net/",
+				MaxScopes:     []string{"profile", "create"},
+				TokenLifetime: 1 * time.Hour,
+				Trusted:       true,
+			},
+		},
+		24*time.Hour,
+	)
+	if err != nil {
+		t.Fatalf(
+			"FAILED test %s: Unable to create the client policy: %v",
+			t.Name(),
+			err,
+		)
+	}
+
+	t.Run("Allowed clients", testAllowedClients(clientPolicy))
+	t.Run("Denied clients", testDeniedClients(clientPolicy))
+	t.Run("Client rules", testClientRules(clientPolicy))
+}
+
+func testAllowedClients(clientPolicy policy.Policy) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		for _, clientID := range []string{
+			"https://app.example.net/",
+			"https://notes.example.org/",
+			"https://Notes.Example.org:8443/app",
+		} {
+			if err := clientPolicy.CheckClient(clientID); err != nil {
+				t.Errorf(
+					"FAILED test %s: The client %q was not allowed.\ngot: %v",
+					t.Name(),
+					clientID,
+					err,
+				)
+			} else {
+				t.Logf("The client %q was allowed.", clientID)
+			}
+		}
+	}
+}
+
+func testDeniedClients(clientPolicy policy.Policy) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		deniedClients := []struct {
+			clientID string
+			check    func(error) bool
+		}{
+			{
+				clientID: "https://untrusted.example.org/",
+				check: func(err error) bool {
+					return errors.As(err, &policy.ClientDeniedError{})
+				},
+			},
+			{
+				clientID: "https://example.org/",
+				check: func(err error) bool {
+					return errors.As(err, &policy.ClientNotAllowedError{})
+				},
+			},
+			{
+				clientID: "https://other.example.com/",
+				check: func(err error) bool {
+					return errors.As(err, &policy.ClientNotAllowedError{})
+				},
+			},
+		}
+
+		for _, client := range deniedClients {
+			if err := clientPolicy.CheckClient(client.clientID); !client.check(err) {
+				t.Errorf(
+					"FAILED test %s: Unexpected error received for the client %q.\ngot: %v",
+					t.Name(),
+					client.clientID,
+					err,
+				)
+			} else {
+				t.Logf("Expected error received for the client %q.\ngot: %q", client.clientID, err.Error())
+			}
+		}
+	}
+}
+
+func testClientRules(clientPolicy policy.Policy) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		trustedClient := "https://app.example.net/"
+		otherClient := "https://notes.example.org/"
+		requested := []string{"profile", "email", "create", "delete"}
+
+		wantScopes := []string{"profile", "create"}
+		if got := clientPolicy.LimitScopes(trustedClient, requested); !slices.Equal(got, wantScopes) {
+			t.Errorf(
+				"FAILED test %s: Unexpected scopes returned for %q.\nwant: %v\ngot: %v",
+				t.Name(),
+				trustedClient,
+				wantScopes,
+				got,
+			)
+		} else {
+			t.Logf("Expected scopes returned for %q.\ngot: %v", trustedClient, got)
+		}
+
+		if got := clientPolicy.LimitScopes(otherClient, requested); !slices.Equal(got, requested) {
+			t.Errorf(
+				"FAILED test %s: Unexpected scopes returned for %q.\nwant: %v\ngot: %v",
+				t.Name(),
+				otherClient,
+				requested,
+				got,
+			)
+		} else {
+			t.Logf("Expected scopes returned for %q.\ngot: %v", otherClient, got)
+		}
+
+		lifetimes := map[string]time.Duration{
+			trustedClient: 1 * time.Hour,
+			otherClient:   24 * time.Hour,
+		}
+
+		for clientID, want := range lifetimes {
+			if got := clientPolicy.TokenLifetime(clientID); got != want {
+				t.Errorf(
+					"FAILED test %s: Unexpected token lifetime returned for %q.\nwant: %s\ngot: %s",
+					t.Name(),
+					clientID,
+					want,
+					got,
+				)
+			} else {
+				t.Logf("Expected token lifetime returned for %q.\ngot: %s", clientID, got)
+			}
+		}
+
+		if !clientPolicy.Trusted(trustedClient) || clientPolicy.Trusted(otherClient) {
+			t.Errorf(
+				"FAILED test %s: Unexpected trusted clients.\nwant: only %q",
+				t.Name(),
+				trustedClient,
+			)
+		} else {
+			t.Logf("Only %q is trusted.", trustedClient)
+		}
+	}
+}
+
+func TestValidateHostPattern(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{"example.org", "*.example.org", "app-1.example.org", "localhost"} {
+		if err := policy.ValidateHostPattern(pattern); err != nil {
+			t.Errorf(
+				"FAILED test %s: The pattern %q was not valid.\ngot: %v",
+				t.Name(),
+				pattern,
+				err,
+			)
+		}
+	}
+
+	for _, pattern := range []string{"", "*", "https://example.org/", "app.*.example.org", "-app.example.org"} {
+		if err := policy.ValidateHostPattern(pattern); !errors.As(err, &policy.InvalidHostPatternError{}) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error received for the pattern %q.\ngot: %v",
+				t.Name(),
+				pattern,
+				err,
+			)
+		} else {
+			t.Logf("Expected error received for the pattern %q.\ngot: %q", pattern, err.Error())
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scopes
+
+import (
+	"slices"
+	"strings"
+)
+
+type Risk string
+
+const (
+	RiskLow    Risk = "low"
+	RiskMedium Risk = "medium"
+	RiskHigh   Risk = "high"
+)
+
+// Policy determines what happens to the scopes in an authorization request that are
+// either unknown to the registry or are not allowed.
+type Policy string
+
+const (
+	// PolicyDrop silently removes the offending scopes from the request.
+	PolicyDrop Policy = "drop"
+
+	// PolicyReject rejects the whole request with the invalid_scope error.
+	PolicyReject Policy = "reject"
+)
+
+type InvalidRiskError struct {
+	scope string
+	risk  string
+}
+
+func (e InvalidRiskError) Error() string {
+	return "invalid risk level '" + e.risk + "' for the scope '" + e.scope + "'"
+}
+
+type InvalidPolicyError struct {
+	policy string
+}
+
+func (e InvalidPolicyError) Error() string {
+	return "invalid unknown scope policy: " + e.policy
+}
+
+type InvalidScopeError struct {
+	scopes []string
+}
+
+func (e InvalidScopeError) Error() string {
+	return "the following scopes are unknown or not allowed: " + strings.Join(e.scopes, ", ")
+}
+
+// Scopes returns the list of scopes that were found to be unknown or not allowed.
+func (e InvalidScopeError) Scopes() []string {
+	return e.scopes
+}
+
+type Scope struct {
+	Name        string
+	Description string
+	Risk        Risk
+	Allowed     bool
+}
+
+// Registry is the collection of scopes known to Beacon.
+type Registry struct {
+	scopes map[string]Scope
+	order  []string
+	policy Policy
+}
+
+// NewRegistry creates a new registry pre-populated with the IndieAuth, Micropub and
+// Microsub scopes. The definitions are applied on top of the default scopes; a definition
+// that shares its name with a default scope replaces it, otherwise it is added to the
+// registry. The default description and risk level are kept if the definition does not
+// set them.
+func NewRegistry(policy Policy, definitions []Scope) (*Registry, error) {
+	if !ValidPolicy(string(policy)) {
+		return nil, InvalidPolicyError{policy: string(policy)}
+	}
+
+	registry := Registry{
+		scopes: make(map[string]Scope),
+		order:  make([]string, 0),
+		policy: policy,
+	}
+
+	for _, scope := range defaultScopes() {
+		registry.set(scope)
+	}
+
+	for _, definition := range definitions {
+		if existing, ok := registry.scopes[definition.Name]; ok {
+			if definition.Description == "" {
+				definition.Description = existing.Description
+			}
+
+			if definition.Risk == "" {
+				definition.Risk = existing.Risk
+			}
+		}
+
+		if definition.Risk == "" {
+			definition.Risk = RiskMedium
+		}
+
+		if !ValidRisk(string(definition.Risk)) {
+			return nil, InvalidRiskError{scope: definition.Name, risk: string(definition.Risk)}
+		}
+
+		registry.set(definition)
+	}
+
+	return &registry, nil
+}
+
+func (r *Registry) set(scope Scope) {
+	if _, exists := r.scopes[scope.Name]; !exists {
+		r.order = append(r.order, scope.Name)
+	}
+
+	r.scopes[scope.Name] = scope
+}
+
+// Lookup returns the scope registered under the given name.
+func (r *Registry) Lookup(name string) (Scope, bool) {
+	scope, ok := r.scopes[name]
+
+	return scope, ok
+}
+
+// Supported returns the names of all the allowed scopes in the registry.
+func (r *Registry) Supported() []string {
+	supported := make([]string, 0, len(r.order))
+
+	for _, name := range r.order {
+		if r.scopes[name].Allowed {
+			supported = append(supported, name)
+		}
+	}
+
+	return supported
+}
+
+// Resolve validates the requested scopes against the registry and returns the
+// allowed scopes. Duplicate scopes are removed. Scopes that are unknown or not
+// allowed are either dropped from the result or cause an InvalidScopeError to be
+// returned depending on the registry's policy.
+func (r *Registry) Resolve(requested []string) ([]Scope, error) {
+	resolved := make([]Scope, 0, len(requested))
+	invalid := make([]string, 0)
+	seen := make(map[string]struct{})
+
+	for _, name := range requested {
+		if name == "" {
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = struct{}{}
+
+		scope, ok := r.scopes[name]
+		if !ok || !scope.Allowed {
+			invalid = append(invalid, name)
+
+			continue
+		}
+
+		resolved = append(resolved, scope)
+	}
+
+	if len(invalid) > 0 && r.policy == PolicyReject {
+		return nil, InvalidScopeError{scopes: invalid}
+	}
+
+	return resolved, nil
+}
+
+// Names returns the names of the given scopes.
+func Names(scopes []Scope) []string {
+	names := make([]string, len(scopes))
+
+	for ind := range scopes {
+		names[ind] = scopes[ind].Name
+	}
+
+	return names
+}
+
+// ValidRisk returns true if the given value is a recognised risk level.
+func ValidRisk(risk string) bool {
+	return slices.Contains([]Risk{RiskLow, RiskMedium, RiskHigh}, Risk(risk))
+}
+
+// ValidPolicy returns true if the given value is a recognised unknown scope policy.
+func ValidPolicy(policy string) bool {
+	return slices.Contains([]Policy{PolicyDrop, PolicyReject}, Policy(policy))
+}
+
+func defaultScopes() []Scope {
+	return []Scope{
+		// IndieAuth
+		{Name: "profile", Description: "View your name, website and photo", Risk: RiskLow, Allowed: true},
+		{Name: "email", Description: "View your email address", Risk: RiskMedium, Allowed: true},
+
+		// Micropub
+		{Name: "create", Description: "Create new posts on your website", Risk: RiskMedium, Allowed: true},
+		{Name: "draft", Description: "Create draft posts on your website", Risk: RiskLow, Allowed: true},
+		{Name: "update", Description: "Edit the existing posts on your website", Risk: RiskHigh, Allowed: true},
+		{Name: "delete", Description: "Delete posts from your website", Risk: RiskHigh, Allowed: true},
+		{Name: "undelete", Description: "Restore deleted posts on your website", Risk: RiskMedium, Allowed: true},
+		{Name: "media", Description: "Upload files to your website's media endpoint", Risk: RiskMedium, Allowed: true},
+
+		// Microsub
+		{Name: "read", Description: "Read your channels and timelines", Risk: RiskLow, Allowed: true},
+		{Name: "follow", Description: "Manage the feeds that you follow", Risk: RiskMedium, Allowed: true},
+		{Name: "mute", Description: "Manage the accounts that you have muted", Risk: RiskMedium, Allowed: true},
+		{Name: "block", Description: "Manage the accounts that you have blocked", Risk: RiskMedium, Allowed: true},
+		{Name: "channels", Description: "Manage your channels", Risk: RiskMedium, Allowed: true},
+	}
+}
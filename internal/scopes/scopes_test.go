@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scopes_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/scopes"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	definitions := []scopes.Scope{
+		{
+			Name:    "delete",
+			Allowed: false,
+		},
+		{
+			Name:        "journal",
+			Description: "Write entries in your journal",
+			Risk:        scopes.RiskHigh,
+			Allowed:     true,
+		},
+	}
+
+	t.Run("Test Supported Scopes", testSupportedScopes(definitions))
+	t.Run("Test Drop Policy", testResolveScopes(scopes.PolicyDrop, definitions))
+	t.Run("Test Reject Policy", testRejectScopes(definitions))
+	t.Run("Test Invalid Risk", testInvalidRisk)
+}
+
+func testSupportedScopes(definitions []scopes.Scope) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		registry, err := scopes.NewRegistry(scopes.PolicyDrop, definitions)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to create the scope registry: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		supported := registry.Supported()
+
+		if slices.Contains(supported, "delete") {
+			t.Errorf(
+				"FAILED test %s: The disallowed scope 'delete' is listed as a supported scope.\ngot: %v",
+				t.Name(),
+				supported,
+			)
+		}
+
+		for _, name := range []string{"profile", "email", "create", "read", "journal"} {
+			if !slices.Contains(supported, name) {
+				t.Errorf(
+					"FAILED test %s: The scope %q is not listed as a supported scope.\ngot: %v",
+					t.Name(),
+					name,
+					supported,
+				)
+			}
+		}
+
+		deleteScope, ok := registry.Lookup("delete")
+		if !ok {
+			t.Fatalf(
+				"FAILED test %s: The scope 'delete' was not found in the registry",
+				t.Name(),
+			)
+		}
+
+		if deleteScope.Description == "" || deleteScope.Risk != scopes.RiskHigh {
+			t.Errorf(
+				"FAILED test %s: The default description and risk were not kept for the 'delete' scope.\ngot: %+v",
+				t.Name(),
+				deleteScope,
+			)
+		} else {
+			t.Logf(
+				"Expected scope found in the registry.\ngot: %+v",
+				deleteScope,
+			)
+		}
+	}
+}
+
+func testResolveScopes(policy scopes.Policy, definitions []scopes.Scope) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		registry, err := scopes.NewRegistry(policy, definitions)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to create the scope registry: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		resolved, err := registry.Resolve([]string{"profile", "delete", "unknown", "journal", "profile"})
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error after resolving the scopes: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		want := []string{"profile", "journal"}
+		got := scopes.Names(resolved)
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf(
+				"FAILED test %s: Unexpected scopes resolved.\nwant: %v\ngot: %v",
+				t.Name(),
+				want,
+				got,
+			)
+		} else {
+			t.Logf(
+				"Expected scopes resolved.\ngot: %v",
+				got,
+			)
+		}
+	}
+}
+
+func testRejectScopes(definitions []scopes.Scope) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		registry, err := scopes.NewRegistry(scopes.PolicyReject, definitions)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to create the scope registry: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		if _, err := registry.Resolve([]string{"profile", "create"}); err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error after resolving valid scopes: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		_, err = registry.Resolve([]string{"profile", "delete", "unknown"})
+		if err == nil {
+			t.Fatalf(
+				"FAILED test %s: Did not receive an error after resolving invalid scopes",
+				t.Name(),
+			)
+		}
+
+		invalidScopeErr := scopes.InvalidScopeError{}
+		if !errors.As(err, &invalidScopeErr) {
+			t.Fatalf(
+				"FAILED test %s: Unexpected error received.\nwant: %T\ngot: %v",
+				t.Name(),
+				invalidScopeErr,
+				err,
+			)
+		}
+
+		want := []string{"delete", "unknown"}
+
+		if !reflect.DeepEqual(want, invalidScopeErr.Scopes()) {
+			t.Errorf(
+				"FAILED test %s: Unexpected invalid scopes reported.\nwant: %v\ngot: %v",
+				t.Name(),
+				want,
+				invalidScopeErr.Scopes(),
+			)
+		} else {
+			t.Logf(
+				"Expected error received.\ngot: %q",
+				err.Error(),
+			)
+		}
+	}
+}
+
+func testInvalidRisk(t *testing.T) {
+	t.Parallel()
+
+	_, err := scopes.NewRegistry(
+		scopes.PolicyDrop,
+		[]scopes.Scope{{Name: "journal", Risk: "extreme", Allowed: true}},
+	)
+	if err == nil {
+		t.Fatalf(
+			"FAILED test %s: Did not receive an error after creating a registry with an invalid risk level",
+			t.Name(),
+		)
+	}
+
+	invalidRiskErr := scopes.InvalidRiskError{}
+	if !errors.As(err, &invalidRiskErr) {
+		t.Errorf(
+			"FAILED test %s: Unexpected error received.\nwant: %T\ngot: %v",
+			t.Name(),
+			invalidRiskErr,
+			err,
+		)
+	} else {
+		t.Logf(
+			"Expected error received.\ngot: %q",
+			err.Error(),
+		)
+	}
+}
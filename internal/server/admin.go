@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/cache"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+const (
+	// AdminPathBackup is the path on the admin socket that streams a backup of the
+	// live database.
+	AdminPathBackup string = "/backup"
+
+	// AdminPathExport is the path on the admin socket that streams an archive of the
+	// live database.
+	AdminPathExport string = "/export"
+
+	// AdminQueryIncludeReceivedTokens is the query parameter of the export that
+	// includes the received access tokens in the archive when set to "true".
+	AdminQueryIncludeReceivedTokens string = "include_received_tokens"
+
+	// AdminPathStats is the path on the admin socket that returns the counters
+	// of the server's caches.
+	AdminPathStats string = "/stats"
+)
+
+// serveAdmin serves the admin endpoints on the Unix socket. The socket can only
+// be used by the user running Beacon.
+func (s *Server) serveAdmin() error {
+	fileInfo, err := os.Lstat(s.adminSocketPath)
+
+	switch {
+	case err == nil && fileInfo.Mode().Type() == fs.ModeSocket:
+		// Remove the socket left behind by a previous run.
+		if err := os.Remove(s.adminSocketPath); err != nil {
+			return fmt.Errorf("error removing the old admin socket: %w", err)
+		}
+	case err == nil:
+		return fmt.Errorf("%w: %s", ErrAdminSocketPathInUse, s.adminSocketPath)
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("error checking the admin socket path: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.adminSocketPath)
+	if err != nil {
+		return fmt.Errorf("error listening on the admin socket: %w", err)
+	}
+
+	if err := os.Chmod(s.adminSocketPath, 0o600); err != nil {
+		_ = listener.Close()
+
+		return fmt.Errorf("error setting the permissions of the admin socket: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+AdminPathBackup, s.adminBackup)
+	mux.HandleFunc("GET "+AdminPathExport, s.adminExport)
+	mux.HandleFunc("GET "+AdminPathStats, s.adminStats)
+
+	s.adminServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelInfo,
+			"The admin socket is ready.",
+			slog.String("path", s.adminSocketPath),
+		)
+
+		if err := s.adminServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			slog.LogAttrs(
+				context.Background(),
+				slog.LevelError,
+				"Admin server error",
+				slog.Any("error", err),
+			)
+		}
+	}()
+
+	return nil
+}
+
+// adminBackup streams a consistent backup of the live database.
+func (s *Server) adminBackup(writer http.ResponseWriter, _ *http.Request) {
+	boltdb, ok := database.BoltDB(s.store)
+	if !ok {
+		http.Error(writer, database.ErrRequiresBoltDB.Error(), http.StatusNotImplemented)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/octet-stream")
+
+	size, err := database.Backup(boltdb, writer)
+	if err != nil {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelError,
+			"Unable to stream the database backup.",
+			slog.Any("error", err),
+		)
+
+		return
+	}
+
+	slog.LogAttrs(
+		context.Background(),
+		slog.LevelInfo,
+		"Streamed a backup of the database through the admin socket.",
+		slog.Int64("size", size),
+	)
+}
+
+// adminExport streams an archive of the live database.
+func (s *Server) adminExport(writer http.ResponseWriter, request *http.Request) {
+	options := database.ExportOptions{
+		IncludeReceivedTokens: request.URL.Query().Get(AdminQueryIncludeReceivedTokens) == "true",
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	if err := s.store.Export(writer, options); err != nil {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelError,
+			"Unable to stream the database archive.",
+			slog.Any("error", err),
+		)
+
+		return
+	}
+
+	slog.LogAttrs(
+		context.Background(),
+		slog.LevelInfo,
+		"Streamed an archive of the database through the admin socket.",
+		slog.Bool("received_tokens", options.IncludeReceivedTokens),
+	)
+}
+
+type adminStatsResponse struct {
+	Cache       cache.Stats `json:"cache"`
+	ReplayCache cache.Stats `json:"replay_cache"`
+}
+
+// adminStats returns the counters of the server's caches so that they can be
+// collected as metrics.
+func (s *Server) adminStats(writer http.ResponseWriter, _ *http.Request) {
+	stats := adminStatsResponse{
+		Cache:       s.cache.Stats(),
+		ReplayCache: s.replayCache.Stats(),
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(writer).Encode(stats); err != nil {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelError,
+			"Unable to send the server statistics.",
+			slog.Any("error", err),
+		)
+	}
+}
+
+// backupOnSchedule backs up the database to the backup directory at every interval
+// until the context is cancelled.
+func (s *Server) backupOnSchedule(ctx context.Context) {
+	// The configuration is only valid with scheduled backups if
+	// the bolt driver is used.
+	boltdb, ok := database.BoltDB(s.store)
+	if !ok {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelError,
+			"Unable to back up the database on a schedule.",
+			slog.Any("error", database.ErrRequiresBoltDB),
+		)
+
+		return
+	}
+
+	ticker := time.NewTicker(s.backupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			path, err := database.BackupToDir(boltdb, s.backupDir, s.backupRetention)
+			if err != nil {
+				slog.LogAttrs(
+					context.Background(),
+					slog.LevelError,
+					"Unable to back up the database.",
+					slog.Any("error", err),
+				)
+
+				continue
+			}
+
+			slog.LogAttrs(
+				context.Background(),
+				slog.LevelInfo,
+				"Backed up the database.",
+				slog.String("path", path),
+			)
+		}
+	}
+}
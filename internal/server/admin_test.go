@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testAdminBackup(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		writer := httptest.NewRecorder()
+		srv.adminBackup(writer, httptest.NewRequest(http.MethodGet, AdminPathBackup, nil))
+
+		if writer.Code != http.StatusOK || writer.Body.Len() == 0 {
+			t.Fatalf(
+				"FAILED test %s: Unexpected response from the admin backup endpoint.\ngot: %d (%d bytes)",
+				t.Name(),
+				writer.Code,
+				writer.Body.Len(),
+			)
+		}
+
+		// The streamed backup passes the integrity check.
+		if err := database.SaveBackup(writer.Body, filepath.Join(t.TempDir(), "backup.db")); err != nil {
+			t.Errorf("FAILED test %s: Unable to save the streamed backup: %v", t.Name(), err)
+		} else {
+			t.Log("The streamed backup was saved.")
+		}
+	}
+}
+
+func testAdminStats(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		if err := srv.cache.Add(t.Name(), "value", time.Now().Add(1*time.Minute)); err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the entry to the cache: %v", t.Name(), err)
+		}
+
+		srv.cache.Get(t.Name())
+
+		writer := httptest.NewRecorder()
+		srv.adminStats(writer, httptest.NewRequest(http.MethodGet, AdminPathStats, nil))
+
+		var got adminStatsResponse
+
+		if err := json.NewDecoder(writer.Body).Decode(&got); err != nil || writer.Code != http.StatusOK {
+			t.Fatalf(
+				"FAILED test %s: Unexpected response from the admin stats endpoint.\nstatus: %d\nerror: %v",
+				t.Name(),
+				writer.Code,
+				err,
+			)
+		}
+
+		if got.Cache.Hits == 0 || got.Cache.Size == 0 || got.Cache.MaxEntries == 0 {
+			t.Errorf("FAILED test %s: Unexpected cache statistics.\ngot: %+v", t.Name(), got.Cache)
+		} else {
+			t.Logf("Received the cache statistics.\ngot: %+v", got.Cache)
+		}
+	}
+}
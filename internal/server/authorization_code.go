@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// authCodeKeyFmt is the replay cache key of an authorization code. The key holds
+	// the hash of the code so that the codes presented by the clients can only be
+	// used to look up other authorization codes.
+	authCodeKeyFmt = "auth_code:%s"
+
+	// authRequestKeyFmt is the cache key of a pending authorization request. The key
+	// holds the encoded state of the request.
+	authRequestKeyFmt = "auth_request:%s"
+)
+
+// authorizationCode is the replay cache entry of an authorization code. The codes are
+// kept in the replay cache so that they are not evicted. When the code is redeemed the
+// entry is replaced with a tombstone in the same step so that a code presented again
+// is always detected as a replay until the code would have expired.
+type authorizationCode struct {
+	Request  clientRequestData `json:"request"`
+	Redeemed bool              `json:"redeemed"`
+	Replayed bool              `json:"replayed"`
+}
+
+// saveAuthorizationCode saves the data associated with the authorization code.
+func (s *Server) saveAuthorizationCode(codeHash string, data clientRequestData, expiresAt time.Time) error {
+	if err := s.replayCache.Add(
+		fmt.Sprintf(authCodeKeyFmt, codeHash),
+		authorizationCode{Request: data},
+		expiresAt,
+	); err != nil {
+		return fmt.Errorf("error saving the authorization code to the cache: %w", err)
+	}
+
+	return nil
+}
+
+// redeemAuthorizationCode returns the data associated with the authorization code and
+// replaces the code with a tombstone in a single step so that only one exchange receives
+// the data. If the code was already redeemed then every access token issued from the
+// code is revoked as recommended in RFC 6749 section 4.1.2 since either the client or
+// an attacker is replaying it, and ErrReusedAuthorizationCode is returned.
+func (s *Server) redeemAuthorizationCode(codeHash, clientID string) (clientRequestData, error) {
+	key := fmt.Sprintf(authCodeKeyFmt, codeHash)
+
+	for {
+		entry, exists := s.replayCache.Get(key)
+		if !exists {
+			return clientRequestData{}, ErrMissingAuthorizationCode
+		}
+
+		if entry.Expired() {
+			return clientRequestData{}, ErrExpiredAuthorizationCode
+		}
+
+		var code authorizationCode
+
+		if err := entry.Decode(&code); err != nil {
+			return clientRequestData{}, fmt.Errorf("unable to decode the authorization code from the cache: %w", err)
+		}
+
+		// The tombstone is marked as replayed so that an exchange that is still
+		// issuing an access token from the code does not issue it or revokes it
+		// after saving it.
+		tombstone := authorizationCode{Redeemed: true, Replayed: code.Redeemed}
+
+		swapped, err := s.replayCache.CompareAndSwap(key, entry, tombstone, entry.ExpiresAt())
+		if err != nil {
+			return clientRequestData{}, fmt.Errorf("error redeeming the authorization code: %w", err)
+		}
+
+		if !swapped {
+			// The code was redeemed or replayed at the same time so it is read again.
+			continue
+		}
+
+		if !code.Redeemed {
+			return code.Request, nil
+		}
+
+		return clientRequestData{}, s.revokeReplayedCode(codeHash, clientID)
+	}
+}
+
+// revokeReplayedCode revokes every access token issued from the replayed authorization code.
+func (s *Server) revokeReplayedCode(codeHash, clientID string) error {
+	count, err := s.store.DeleteAccessTokensIssuedFromCode(codeHash)
+	if err != nil {
+		return fmt.Errorf("error revoking the access tokens issued from the authorization code: %w", err)
+	}
+
+	slog.LogAttrs(
+		context.Background(),
+		slog.LevelWarn,
+		"An authorization code was used more than once so the access tokens issued from it were revoked.",
+		slog.String("client_id", clientID),
+		slog.Int("revoked_tokens", count),
+	)
+
+	return ErrReusedAuthorizationCode
+}
+
+// codeReplayed returns true if the authorization code was presented again after
+// it was redeemed.
+func (s *Server) codeReplayed(codeHash string) bool {
+	entry, exists := s.replayCache.Get(fmt.Sprintf(authCodeKeyFmt, codeHash))
+	if !exists {
+		return false
+	}
+
+	var code authorizationCode
+
+	return entry.Decode(&code) == nil && code.Replayed
+}
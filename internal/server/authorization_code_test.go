@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/cache"
+)
+
+func testAuthorizationCodeReplay(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		codeVerifier := "Replay5CodeVerifierThatIsLongEnoughForTheTestExchange"
+		challenge := sha256.Sum256([]byte(codeVerifier))
+
+		authReq := clientAuthRequest{
+			ClientID:            "https://app.example.net/",
+			CodeChallenge:       base64.RawURLEncoding.EncodeToString(challenge[:]),
+			CodeChallengeMethod: "S256",
+			RedirectURI:         "https://app.example.net/callback",
+			Scope:               []string{"create"},
+		}
+
+		issueCode := func() url.Values {
+			code, err := srv.issueAuthorizationCode(authReq, "https://billjones.example.net/")
+			if err != nil {
+				t.Fatalf("FAILED test %s: Unable to issue the authorization code: %v", t.Name(), err)
+			}
+
+			return url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {code},
+				"client_id":     {authReq.ClientID},
+				"redirect_uri":  {authReq.RedirectURI},
+				"code_verifier": {codeVerifier},
+			}
+		}
+
+		form := issueCode()
+
+		response := sendTestForm(t, srv.tokenGrant, form)
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+		}
+
+		if err := json.NewDecoder(response.Body).Decode(&token); err != nil || response.Code != http.StatusOK {
+			t.Fatalf(
+				"FAILED test %s: Unable to exchange the authorization code.\nstatus: %d\nerror: %v",
+				t.Name(),
+				response.Code,
+				err,
+			)
+		}
+
+		// The access token is revoked when the authorization code is used again.
+		response = sendTestForm(t, srv.tokenGrant, form)
+		if response.Code != http.StatusUnauthorized {
+			t.Errorf(
+				"FAILED test %s: Unexpected status code received after replaying the authorization code.\nwant: %d\ngot: %d",
+				t.Name(),
+				http.StatusUnauthorized,
+				response.Code,
+			)
+		}
+
+		_, found, err := srv.store.GetAccessToken(auth.HashBearerToken(token.AccessToken))
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to get the access token: %v", t.Name(), err)
+		}
+
+		if found {
+			t.Errorf("FAILED test %s: The access token was not revoked after the authorization code was replayed.", t.Name())
+		} else {
+			t.Log("The access token was revoked after the authorization code was replayed.")
+		}
+
+		// Only one of the concurrent exchanges of the same code can succeed.
+		form = issueCode()
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			successes int
+		)
+
+		for range 10 {
+			wg.Go(func() {
+				response := sendTestForm(t, srv.tokenGrant, form)
+
+				if response.Code == http.StatusOK {
+					mu.Lock()
+					successes++
+					mu.Unlock()
+				}
+			})
+		}
+
+		wg.Wait()
+
+		if successes > 1 {
+			t.Errorf(
+				"FAILED test %s: The authorization code was exchanged more than once.\ngot: %d successful exchanges",
+				t.Name(),
+				successes,
+			)
+		} else {
+			t.Logf("The authorization code was exchanged %d time(s).", successes)
+		}
+
+		// A replay that arrives while the code is being exchanged stops the exchange.
+		form = issueCode()
+		codeHash := auth.HashBearerToken(form.Get("code"))
+
+		data, err := srv.redeemAuthorizationCode(codeHash, authReq.ClientID)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to redeem the authorization code: %v", t.Name(), err)
+		}
+
+		if response := sendTestForm(t, srv.tokenGrant, form); response.Code != http.StatusUnauthorized {
+			t.Errorf(
+				"FAILED test %s: Unexpected status code received after replaying the authorization code during the exchange.\nwant: %d\ngot: %d",
+				t.Name(),
+				http.StatusUnauthorized,
+				response.Code,
+			)
+		}
+
+		data.codeHash = codeHash
+		recorder := httptest.NewRecorder()
+		srv.tokenExchange(recorder, data, nil)
+
+		if recorder.Code == http.StatusOK {
+			t.Errorf("FAILED test %s: An access token was issued from the authorization code that was replayed during the exchange.", t.Name())
+		} else {
+			t.Log("No access token was issued from the authorization code that was replayed during the exchange.")
+		}
+	}
+}
+
+// redeemTestAuthorizationCode issues an authorization code, redeems it and returns its hash.
+func redeemTestAuthorizationCode(t *testing.T, srv *Server) string {
+	t.Helper()
+
+	code, err := srv.issueAuthorizationCode(clientAuthRequest{
+		ClientID:    "https://app.example.net/",
+		RedirectURI: "https://app.example.net/callback",
+	}, "https://billjones.example.net/")
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to issue the authorization code: %v", t.Name(), err)
+	}
+
+	codeHash := auth.HashBearerToken(code)
+
+	if _, err := srv.redeemAuthorizationCode(codeHash, "https://app.example.net/"); err != nil {
+		t.Fatalf("FAILED test %s: Unable to redeem the authorization code: %v", t.Name(), err)
+	}
+
+	return codeHash
+}
+
+func testCacheFlood(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		// A small cache is used so that it is quickly filled.
+		serverCache := srv.cache
+		srv.cache = cache.NewCache(time.Minute, 32)
+
+		defer func() {
+			srv.cache.Close()
+			srv.cache = serverCache
+		}()
+
+		codeHash := redeemTestAuthorizationCode(t, srv)
+
+		dpopProofKey := fmt.Sprintf(dpopProofKeyFmt, "flood-thumbprint", "flood-proof-id")
+		if err := srv.replayCache.Add(dpopProofKey, nil, time.Now().Add(auth.DPoPProofWindow)); err != nil {
+			t.Fatalf("FAILED test %s: Unable to record the DPoP proof: %v", t.Name(), err)
+		}
+
+		// Flood the cache with anonymous authorization requests.
+		// The oldest requests are evicted so the new requests are still accepted.
+		rejected := false
+
+		for ind := range 200 {
+			query := url.Values{
+				qKeyClientID:            {"https://flood.example.net/"},
+				qKeyCodeChallenge:       {"OfYAxt8zU2dAPDWQxTAUIteRzMsoj9QBdMIVEDOErUo"},
+				qKeyCodeChallengeMethod: {"S256"},
+				qKeyRedirectURI:         {"https://flood.example.net/callback"},
+				qKeyResponseType:        {"code"},
+				qKeyState:               {fmt.Sprintf("flood-%d", ind)},
+			}
+
+			writer := httptest.NewRecorder()
+			srv.authorizeRedirectToLogin(writer, httptest.NewRequest(http.MethodGet, pathAuth+"?"+query.Encode(), nil))
+
+			if writer.Code == http.StatusServiceUnavailable {
+				rejected = true
+
+				break
+			}
+		}
+
+		stats := srv.cache.Stats()
+
+		if rejected || stats.Evictions == 0 || stats.Size > stats.MaxEntries {
+			t.Errorf(
+				"FAILED test %s: The old authorization requests were not evicted from the full cache.\nrejected: %t\nstats: %+v",
+				t.Name(),
+				rejected,
+				stats,
+			)
+		} else {
+			t.Logf("%d authorization requests were evicted from the full cache.", stats.Evictions)
+		}
+
+		if _, exists := srv.replayCache.Get(fmt.Sprintf(authCodeKeyFmt, codeHash)); !exists {
+			t.Errorf("FAILED test %s: The record of the redeemed authorization code was lost.", t.Name())
+		}
+
+		if _, exists := srv.replayCache.Get(dpopProofKey); !exists {
+			t.Errorf("FAILED test %s: The record of the used DPoP proof was lost.", t.Name())
+		}
+
+		if _, err := srv.redeemAuthorizationCode(codeHash, "https://app.example.net/"); !errors.Is(err, ErrReusedAuthorizationCode) {
+			t.Errorf(
+				"FAILED test %s: The replayed authorization code was not detected after the flood.\nerror: %v",
+				t.Name(),
+				err,
+			)
+		} else {
+			t.Log("The replay records survived the flood.")
+		}
+	}
+}
+
+func testAuthorizationCodeCacheKeys(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		codeHash := redeemTestAuthorizationCode(t, srv)
+
+		encodedState, err := srv.addClientAuthRequestToCache(clientAuthRequest{
+			ClientID: "https://app.example.net/",
+			State:    "CacheKeyState",
+		})
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to add the authorization request to the cache: %v", t.Name(), err)
+		}
+
+		defer srv.cache.Delete(fmt.Sprintf(authRequestKeyFmt, encodedState))
+
+		// The keys of other cache entries are presented as authorization codes.
+		for _, key := range []string{
+			fmt.Sprintf(authCodeKeyFmt, codeHash),
+			fmt.Sprintf(authRequestKeyFmt, encodedState),
+		} {
+			for _, grantType := range []string{"authorization_code", "password"} {
+				form := url.Values{
+					"grant_type":   {grantType},
+					"code":         {key},
+					"client_id":    {"https://app.example.net/"},
+					"redirect_uri": {"https://app.example.net/callback"},
+				}
+
+				if response := sendTestForm(t, srv.tokenGrant, form); response.Code == http.StatusOK {
+					t.Errorf("FAILED test %s: The cache key %q was exchanged for an access token.", t.Name(), key)
+				}
+			}
+		}
+
+		if srv.codeReplayed(codeHash) {
+			t.Errorf("FAILED test %s: The redeemed authorization code was replayed by its cache key.", t.Name())
+		}
+
+		if _, exists := srv.cache.Get(fmt.Sprintf(authRequestKeyFmt, encodedState)); !exists {
+			t.Errorf("FAILED test %s: The pending authorization request was deleted.", t.Name())
+		} else {
+			t.Log("The cache entries were not affected by the keys presented as authorization codes.")
+		}
+	}
+}
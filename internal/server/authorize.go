@@ -5,12 +5,13 @@
 package server
 
 import (
-	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"slices"
@@ -18,16 +19,35 @@ import (
 	"time"
 
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/cache"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/scopes"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
 )
 
 func (s *Server) authorize(writer http.ResponseWriter, request *http.Request, profileID string) {
-	encodedState := request.URL.Query().Get(qKeyState)
+	query, err := s.authorizationRequestParameters(request.URL.Query())
+	if err != nil {
+		sendClientError(
+			writer,
+			http.StatusBadRequest,
+			fmt.Errorf("error getting the parameters of the authorization request: %w", err),
+		)
+
+		return
+	}
+
+	encodedState := query.Get(qKeyState)
+
+	authReq, err := s.getClientAuthRequest(&encodedState, query)
+	if errors.Is(err, cache.ErrCacheFull) {
+		sendCacheError(writer, err)
+
+		return
+	}
 
-	authReq, err := s.getClientAuthRequest(&encodedState, request.URL.Query())
 	if err != nil {
 		sendClientError(
 			writer,
@@ -53,38 +73,73 @@ func (s *Server) authorize(writer http.ResponseWriter, request *http.Request, pr
 		return
 	}
 
-	// Validate the client ID before fetching the metadata.
-	if err := discovery.ValidateClientID(authReq.ClientID); err != nil {
+	// Check that the client is permitted to use Beacon before fetching the metadata.
+	clientPolicy, err := s.clientPolicy()
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error getting the client policy: %w", err),
+		)
+
+		return
+	}
+
+	if err := clientPolicy.CheckClient(authReq.ClientID); err != nil {
 		sendClientError(
 			writer,
-			http.StatusUnauthorized,
-			fmt.Errorf(
-				"error validating the client ID: %w",
-				err,
-			),
+			http.StatusForbidden,
+			fmt.Errorf("the client is not permitted by the client policy: %w", err),
 		)
 
 		return
 	}
 
-	// Fetch the client's metadata and use it to validate the authorization request
-	clientMetadata, err := discovery.FetchClientMetadata(
-		request.Context(),
-		authReq.ClientID,
-		s.issuer,
-	)
+	outboundCtx := s.outboundContext(request.Context(), authReq.ClientID)
+
+	// The metadata of registered clients comes from the client registry.
+	clientMetadata, registered, err := s.registeredClientMetadata(authReq.ClientID)
 	if err != nil {
 		sendServerError(
 			writer,
-			fmt.Errorf(
-				"error fetching the client's metadata: %w",
-				err,
-			),
+			fmt.Errorf("error checking the client registry: %w", err),
 		)
 
 		return
 	}
 
+	if !registered {
+		// Validate the client ID before fetching the metadata.
+		if err := discovery.ValidateClientID(outboundCtx, s.outboundGuard, authReq.ClientID); err != nil {
+			sendClientError(
+				writer,
+				http.StatusUnauthorized,
+				fmt.Errorf(
+					"error validating the client ID: %w",
+					err,
+				),
+			)
+
+			return
+		}
+
+		// Fetch the client's metadata and use it to validate the authorization request
+		clientMetadata, err = s.clientMetadata.Get(
+			outboundCtx,
+			authReq.ClientID,
+		)
+		if err != nil {
+			sendServerError(
+				writer,
+				fmt.Errorf(
+					"error fetching the client's metadata: %w",
+					err,
+				),
+			)
+
+			return
+		}
+	}
+
 	if err := discovery.ValidateClientMetadata(
 		clientMetadata,
 		authReq.ClientID,
@@ -102,42 +157,152 @@ func (s *Server) authorize(writer http.ResponseWriter, request *http.Request, pr
 		return
 	}
 
-	consentPage := struct {
-		Title             string
-		ClientID          string
-		ClientName        string
-		ClientURI         string
-		ClientRedirectURI string
-		ProfileID         string
-		AcceptURI         string
-		RejectURI         string
-		State             string
-		Scopes            []string
-	}{
-		Title:             "Consent - " + info.ApplicationTitledName,
+	// Validate the requested scopes against the scope registry.
+	requestedScopes, err := s.scopes.Resolve(authReq.Scope)
+	if err != nil {
+		invalidScopeErr := scopes.InvalidScopeError{}
+		if errors.As(err, &invalidScopeErr) {
+			s.cache.Delete(fmt.Sprintf(authRequestKeyFmt, encodedState))
+
+			params := url.Values{}
+			params.Set(qKeyError, "invalid_scope")
+			params.Set(qKeyErrorDescription, invalidScopeErr.Error())
+
+			s.sendAuthorizationResponse(writer, request, authReq, params)
+
+			return
+		}
+
+		sendServerError(
+			writer,
+			fmt.Errorf("error resolving the requested scopes: %w", err),
+		)
+
+		return
+	}
+
+	// Limit the requested scopes to the scopes that the client can be granted.
+	grantableScopes := clientPolicy.LimitScopes(authReq.ClientID, scopes.Names(requestedScopes))
+	requestedScopes = slices.DeleteFunc(requestedScopes, func(scope scopes.Scope) bool {
+		return !slices.Contains(grantableScopes, scope.Name)
+	})
+
+	// Update the cached authorization request in case any of the
+	// requested scopes were dropped. The request is now validated
+	// so the profile can accept or reject it.
+	authReq.Scope = scopes.Names(requestedScopes)
+	authReq.ConsentProfileID = profileID
+
+	if err := s.saveClientAuthRequestToCache(encodedState, authReq); err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error updating the client authorization request in the cache: %w", err),
+		)
+
+		return
+	}
+
+	// Trusted first-party clients skip the consent page.
+	if clientPolicy.Trusted(authReq.ClientID) {
+		s.cache.Delete(fmt.Sprintf(authRequestKeyFmt, encodedState))
+
+		authCode, err := s.issueAuthorizationCode(authReq, profileID)
+		if err != nil {
+			sendCacheError(
+				writer,
+				fmt.Errorf("error issuing the authorization code: %w", err),
+			)
+
+			return
+		}
+
+		s.sendAuthorizationResponse(writer, request, authReq, url.Values{qKeyCode: {authCode}})
+
+		return
+	}
+
+	consent := consentPage{
+		Title:             consentPageTitle(),
 		ClientID:          clientMetadata.ClientID,
 		ClientName:        clientMetadata.ClientName,
 		ClientURI:         clientMetadata.ClientURI,
+		ClientLogoURI:     s.proxiedClientLogoURI(outboundCtx, writer, clientMetadata.LogoURI),
 		ProfileID:         profileID,
 		ClientRedirectURI: authReq.RedirectURI,
 		AcceptURI:         pathAuthAccept,
 		RejectURI:         pathAuthReject,
 		State:             encodedState,
-		Scopes:            authReq.Scope,
+		FormPost:          authReq.ResponseMode == responseModeFormPost,
+		Scopes:            requestedScopes,
 	}
 
 	s.sendHTMLResponseWithTemplate(
 		writer,
 		"consent",
 		http.StatusOK,
-		consentPage,
+		consent,
 		nil,
 		nil,
 	)
 }
 
+type consentPage struct {
+	Title             string
+	ClientID          string
+	ClientName        string
+	ClientURI         string
+	ClientLogoURI     string
+	ClientRedirectURI string
+	ProfileID         string
+	AcceptURI         string
+	RejectURI         string
+	State             string
+	FormPost          bool
+	Scopes            []scopes.Scope
+}
+
+// proxiedClientLogoURI returns the URI of the client's logo served from Beacon so that the
+// user's browser does not connect to the client before the user has given their consent.
+// An empty string is returned if the client does not have a logo or the logo can't be fetched.
+func (s *Server) proxiedClientLogoURI(ctx context.Context, writer http.ResponseWriter, logoURI string) string {
+	if logoURI == "" {
+		return ""
+	}
+
+	logoID, err := s.clientLogos.Fetch(ctx, logoURI)
+	if err != nil {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelWarn,
+			"Unable to fetch the client's logo",
+			slog.String("logo_uri", logoURI),
+			slog.Any("error", err),
+			slog.String("request_id", writer.Header().Get("X-Request-ID")),
+		)
+
+		return ""
+	}
+
+	return pathClientLogo + logoID
+}
+
+func consentPageTitle() string {
+	return "Consent - " + info.ApplicationTitledName
+}
+
 func (s *Server) authorizeRedirectToLogin(writer http.ResponseWriter, request *http.Request) {
-	authRequest, err := newClientAuthRequestFromQuery(request.URL.Query())
+	query, err := s.authorizationRequestParameters(request.URL.Query())
+	if err != nil {
+		sendClientError(
+			writer,
+			http.StatusBadRequest,
+			fmt.Errorf("error getting the parameters of the authorization request: %w", err),
+		)
+
+		return
+	}
+
+	authRequest, err := newClientAuthRequestFromQuery(query)
 	if err != nil {
 		sendClientError(
 			writer,
@@ -150,35 +315,39 @@ func (s *Server) authorizeRedirectToLogin(writer http.ResponseWriter, request *h
 
 	encodedState, err := s.addClientAuthRequestToCache(authRequest)
 	if err != nil {
-		sendServerError(
+		sendCacheError(
 			writer,
 			fmt.Errorf("error adding the client auth request to cache: %w", err),
 		)
+
+		return
 	}
 
 	profileID := authRequest.Me
 
-	query := url.Values{}
-	query.Set(qKeyLoginType, loginTypeIndieauth)
-	query.Set(qKeyProfileID, profileID)
-	query.Set(qKeyState, encodedState)
+	loginQuery := url.Values{}
+	loginQuery.Set(qKeyLoginType, loginTypeIndieauth)
+	loginQuery.Set(qKeyProfileID, profileID)
+	loginQuery.Set(qKeyState, encodedState)
 
-	http.Redirect(writer, request, "/profile/login?"+query.Encode(), http.StatusSeeOther)
+	http.Redirect(writer, request, "/profile/login?"+loginQuery.Encode(), http.StatusSeeOther)
 }
 
 type clientRequestData struct {
-	ClientID            string
-	CodeChallenge       string
-	CodeChallengeMethod string
-	RedirectURI         string
-	Scopes              []string
-	Me                  string
+	ClientID            string   `json:"client_id"`
+	CodeChallenge       string   `json:"code_challenge"`
+	CodeChallengeMethod string   `json:"code_challenge_method"`
+	RedirectURI         string   `json:"redirect_uri"`
+	Scopes              []string `json:"scopes"`
+	Me                  string   `json:"me"`
+
+	// codeHash is the hash of the authorization code that is being exchanged.
+	// It is not saved in the cache.
+	codeHash string
 }
 
 func (s *Server) authorizeAccept(writer http.ResponseWriter, request *http.Request, profileID string) {
-	encodedState := request.PostFormValue("state")
-
-	authReq, err := s.getClientAuthRequest(&encodedState, request.URL.Query())
+	authReq, err := s.takeConsentedAuthRequest(request.PostFormValue("state"), profileID)
 	if err != nil {
 		sendClientError(
 			writer,
@@ -189,18 +358,29 @@ func (s *Server) authorizeAccept(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 
-	// Create the authorization code
-	authCodeBytes := make([]byte, 32)
-
-	if _, err := rand.Read(authCodeBytes); err != nil {
-		sendServerError(
+	authCode, err := s.issueAuthorizationCode(authReq, profileID)
+	if err != nil {
+		sendCacheError(
 			writer,
-			fmt.Errorf("unable to create random bytes: %w", err),
+			fmt.Errorf("error issuing the authorization code: %w", err),
 		)
 
 		return
 	}
 
+	s.sendAuthorizationResponse(writer, request, authReq, url.Values{qKeyCode: {authCode}})
+}
+
+// issueAuthorizationCode creates the authorization code for the client's authorization
+// request and saves the associated data to the server's cache.
+func (s *Server) issueAuthorizationCode(authReq clientAuthRequest, profileID string) (string, error) {
+	// Create the authorization code
+	authCodeBytes := make([]byte, 32)
+
+	if _, err := rand.Read(authCodeBytes); err != nil {
+		return "", fmt.Errorf("unable to create random bytes: %w", err)
+	}
+
 	authCode := hex.EncodeToString(authCodeBytes)
 
 	// Data associated with the authorization code
@@ -213,36 +393,20 @@ func (s *Server) authorizeAccept(writer http.ResponseWriter, request *http.Reque
 		Me:                  profileID,
 	}
 
-	authRespBytes, err := utilities.GobEncode(authResp)
-	if err != nil {
-		sendServerError(
-			writer,
-			fmt.Errorf("unable to encode the data: %w", err),
-		)
-
-		return
-	}
-
-	// Save code and associated data to the server's cache.
-	s.cache.Add(
-		authCode,
-		authRespBytes,
+	// Save the hash of the code and associated data to the replay cache.
+	if err := s.saveAuthorizationCode(
+		auth.HashBearerToken(authCode),
+		authResp,
 		time.Now().Add(1*time.Minute),
-	)
-
-	// Construct the query string for the redirect.
-	query := url.Values{}
-	query.Set(qKeyCode, authCode)
-	query.Set(qKeyState, authReq.State)
-	query.Set(qKeyIssuer, s.issuer)
+	); err != nil {
+		return "", err
+	}
 
-	writer.Header().Set("Hx-Redirect", authReq.RedirectURI+"?"+query.Encode())
+	return authCode, nil
 }
 
-func (s *Server) authorizeReject(writer http.ResponseWriter, request *http.Request, _ string) {
-	encodedState := request.PostFormValue("state")
-
-	authReq, err := s.getClientAuthRequest(&encodedState, request.URL.Query())
+func (s *Server) authorizeReject(writer http.ResponseWriter, request *http.Request, profileID string) {
+	authReq, err := s.takeConsentedAuthRequest(request.PostFormValue("state"), profileID)
 	if err != nil {
 		sendClientError(
 			writer,
@@ -253,12 +417,7 @@ func (s *Server) authorizeReject(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 
-	// Construct the query string for the redirect.
-	query := url.Values{}
-	query.Set(qKeyError, "access_denied")
-	query.Set(qKeyState, authReq.State)
-
-	writer.Header().Set("Hx-Redirect", authReq.RedirectURI+"?"+query.Encode())
+	s.sendAuthorizationResponse(writer, request, authReq, url.Values{qKeyError: {"access_denied"}})
 }
 
 func (s *Server) profileExchange(writer http.ResponseWriter, data clientRequestData) {
@@ -267,7 +426,7 @@ func (s *Server) profileExchange(writer http.ResponseWriter, data clientRequestD
 
 	if slices.Contains(data.Scopes, "profile") {
 		// Get the profile information from the database
-		info, err := database.GetProfileInformation(s.boltdb, data.Me)
+		info, err := s.store.GetProfileInformation(data.Me)
 		if err != nil {
 			sendServerError(
 				writer,
@@ -300,22 +459,121 @@ func (s *Server) profileExchange(writer http.ResponseWriter, data clientRequestD
 	sendJSONResponse(writer, http.StatusOK, response)
 }
 
-func (s *Server) tokenExchange(writer http.ResponseWriter, data clientRequestData) {
-	var err error
+// tokenGrant handles the requests to the token endpoint
+// based on the grant type. The DPoP proof is verified before
+// the grant so that an invalid proof does not use up the grant.
+// The proof is recorded as used once the grant is validated.
+func (s *Server) tokenGrant(writer http.ResponseWriter, request *http.Request) {
+	proof, err := s.verifyDPoPProof(request, s.tokenEndpoint)
+	if err != nil {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_dpop_proof", err)
+
+		return
+	}
+
+	switch request.PostFormValue("grant_type") {
+	case deviceGrantType:
+		s.deviceTokenExchange(writer, request, proof)
+
+		return
+	case ticketGrantType:
+		s.ticketTokenExchange(writer, request, proof)
+
+		return
+	}
+
+	s.exchangeAuthorization(func(writer http.ResponseWriter, data clientRequestData) {
+		s.tokenExchange(writer, data, proof)
+	})(writer, request)
+}
+
+// tokenExchange issues the access token to the client. The access token is bound to
+// the client's key when the client sent a DPoP proof with the token request.
+func (s *Server) tokenExchange(writer http.ResponseWriter, data clientRequestData, proof *auth.DPoPProof) {
+	// The client policy may have changed since the authorization code was issued.
+	clientPolicy, err := s.clientPolicy()
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error getting the client policy: %w", err),
+		)
+
+		return
+	}
+
+	if err := clientPolicy.CheckClient(data.ClientID); err != nil {
+		sendClientError(
+			writer,
+			http.StatusForbidden,
+			fmt.Errorf("the client is not permitted by the client policy: %w", err),
+		)
+
+		return
+	}
+
+	data.Scopes = clientPolicy.LimitScopes(data.ClientID, data.Scopes)
+
+	if err := s.recordDPoPProof(proof); err != nil {
+		sendDPoPProofError(writer, err)
+
+		return
+	}
+
+	// The authorization code may have been presented again since it was redeemed.
+	if data.codeHash != "" && s.codeReplayed(data.codeHash) {
+		sendClientError(writer, http.StatusUnauthorized, ErrReusedAuthorizationCode)
+
+		return
+	}
 
 	// Create the access token.
 	// If there are no requested scopes then the access token won't be created.
-	bearerToken := ""
+	var (
+		bearerToken   string
+		expiresIn     int64
+		tokenType     = tokenTypeBearer
+		keyThumbprint = dpopKeyThumbprint(proof)
+	)
+
+	if keyThumbprint != "" {
+		tokenType = tokenTypeDPoP
+	}
+
 	if len(data.Scopes) > 0 {
-		bearerToken, err = auth.CreateBearerToken()
+		lifetime := clientPolicy.TokenLifetime(data.ClientID)
+		issuedAt := time.Now()
+
+		bearerToken, err = s.createAccessToken(database.AccessToken{
+			ClientID:  data.ClientID,
+			Me:        data.Me,
+			Scopes:    data.Scopes,
+			IssuedAt:  issuedAt,
+			ExpiresAt: issuedAt.Add(lifetime),
+			JKT:       keyThumbprint,
+
+			AuthorizationCodeHash: data.codeHash,
+		})
 		if err != nil {
-			sendServerError(
-				writer,
-				fmt.Errorf("unable to create the bearer token: %w", err),
-			)
+			sendServerError(writer, err)
+
+			return
+		}
+
+		// The authorization code may have been presented again while the access
+		// token was being saved, in which case the access token is revoked.
+		if data.codeHash != "" && s.codeReplayed(data.codeHash) {
+			if _, err := s.store.DeleteAccessTokensIssuedFromCode(data.codeHash); err != nil {
+				sendServerError(writer, err)
+
+				return
+			}
+
+			sendClientError(writer, http.StatusUnauthorized, ErrReusedAuthorizationCode)
 
 			return
 		}
+
+		expiresIn = int64(lifetime.Seconds())
 	}
 
 	// Get the profile information if requested.
@@ -323,7 +581,7 @@ func (s *Server) tokenExchange(writer http.ResponseWriter, data clientRequestDat
 
 	if slices.Contains(data.Scopes, "profile") {
 		// Get the profile information from the database
-		info, err := database.GetProfileInformation(s.boltdb, data.Me)
+		info, err := s.store.GetProfileInformation(data.Me)
 		if err != nil {
 			sendServerError(
 				writer,
@@ -349,12 +607,14 @@ func (s *Server) tokenExchange(writer http.ResponseWriter, data clientRequestDat
 		AccessToken string            `json:"access_token"`
 		TokenType   string            `json:"token_type"`
 		Scope       string            `json:"scope"`
+		ExpiresIn   int64             `json:"expires_in,omitempty"`
 		Me          string            `json:"me"`
 		Profile     map[string]string `json:"profile,omitempty"`
 	}{
 		AccessToken: bearerToken,
-		TokenType:   "Bearer",
+		TokenType:   tokenType,
 		Scope:       strings.Join(data.Scopes, " "),
+		ExpiresIn:   expiresIn,
 		Me:          data.Me,
 		Profile:     profile,
 	}
@@ -362,15 +622,97 @@ func (s *Server) tokenExchange(writer http.ResponseWriter, data clientRequestDat
 	sendJSONResponse(writer, http.StatusOK, response)
 }
 
+// createAccessToken creates a new access token and saves its record in the database.
+func (s *Server) createAccessToken(record database.AccessToken) (string, error) {
+	bearerToken, err := auth.CreateBearerToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to create the bearer token: %w", err)
+	}
+
+	if err := s.store.SaveAccessToken(auth.HashBearerToken(bearerToken), record); err != nil {
+		return "", fmt.Errorf("unable to save the access token: %w", err)
+	}
+
+	return bearerToken, nil
+}
+
+// purgeExpiredRecords deletes the records of the expired access tokens and the stale
+// client metadata from the database at every interval until the context is cancelled.
+func (s *Server) purgeExpiredRecords(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.purgeExpiredAccessTokens()
+			s.purgeStaleClientMetadata()
+		}
+	}
+}
+
+func (s *Server) purgeExpiredAccessTokens() {
+	count, err := s.store.DeleteExpiredAccessTokens(time.Now())
+	if err != nil {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelError,
+			"Unable to delete the expired access tokens.",
+			slog.Any("error", err),
+		)
+
+		return
+	}
+
+	if count > 0 {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelInfo,
+			"Deleted the expired access tokens.",
+			slog.Int("count", count),
+		)
+	}
+}
+
+func (s *Server) purgeStaleClientMetadata() {
+	count, err := s.clientMetadata.PurgeStale()
+	if err != nil {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelError,
+			"Unable to delete the stale client metadata.",
+			slog.Any("error", err),
+		)
+
+		return
+	}
+
+	if count > 0 {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelInfo,
+			"Deleted the stale client metadata.",
+			slog.Int("count", count),
+		)
+	}
+}
+
 type clientAuthRequest struct {
-	ClientID            string
-	CodeChallenge       string
-	CodeChallengeMethod string
-	Me                  string
-	RedirectURI         string
-	ResponseType        string
-	Scope               []string
-	State               string
+	ClientID            string   `json:"client_id"`
+	CodeChallenge       string   `json:"code_challenge"`
+	CodeChallengeMethod string   `json:"code_challenge_method"`
+	Me                  string   `json:"me"`
+	RedirectURI         string   `json:"redirect_uri"`
+	ResponseMode        string   `json:"response_mode"`
+	ResponseType        string   `json:"response_type"`
+	Scope               []string `json:"scope"`
+	State               string   `json:"state"`
+
+	// ConsentProfileID is the profile that was shown the consent page. It is
+	// only set once the request is validated.
+	ConsentProfileID string `json:"consent_profile_id,omitempty"`
 }
 
 // getClientAuthRequest attempts to retrieve the client's authorization request from cache. If this is not found
@@ -436,7 +778,11 @@ func newClientAuthRequestFromQuery(queryValues url.Values) (clientAuthRequest, e
 		scopes = strings.Split(scopeStr, " ")
 	}
 
-	canonicalizedClientID, err := utilities.ValidateAndCanonicalizeURL(queryValues.Get(qKeyClientID), true)
+	if err := validateResponseMode(queryValues.Get(qKeyResponseMode), queryValues.Get(qKeyRedirectURI)); err != nil {
+		return clientAuthRequest{}, err
+	}
+
+	canonicalizedClientID, err := utilities.ValidateAndCanonicalizeClientID(queryValues.Get(qKeyClientID))
 	if err != nil {
 		return clientAuthRequest{}, fmt.Errorf("error canonicalizing the client ID: %w", err)
 	}
@@ -447,6 +793,7 @@ func newClientAuthRequestFromQuery(queryValues url.Values) (clientAuthRequest, e
 		CodeChallengeMethod: queryValues.Get(qKeyCodeChallengeMethod),
 		Me:                  queryValues.Get(qKeyMe),
 		RedirectURI:         queryValues.Get(qKeyRedirectURI),
+		ResponseMode:        queryValues.Get(qKeyResponseMode),
 		ResponseType:        queryValues.Get(qKeyResponseType),
 		Scope:               scopes,
 		State:               queryValues.Get(qKeyState),
@@ -461,31 +808,63 @@ func newClientAuthRequestFromQuery(queryValues url.Values) (clientAuthRequest, e
 func (s *Server) addClientAuthRequestToCache(request clientAuthRequest) (string, error) {
 	encodedState := base64.URLEncoding.EncodeToString([]byte(request.State))
 
-	if _, exists := s.cache.Get(encodedState); exists {
+	if _, exists := s.cache.Get(fmt.Sprintf(authRequestKeyFmt, encodedState)); exists {
 		return "", ExistingStateKeyInCacheError{encodedState: encodedState}
 	}
 
-	requestBytes, err := utilities.GobEncode(request)
-	if err != nil {
-		return "", fmt.Errorf("error gob encoding the client auth request: %w", err)
+	if err := s.saveClientAuthRequestToCache(encodedState, request); err != nil {
+		return "", err
 	}
 
-	s.cache.Add(encodedState, requestBytes, time.Now().Add(10*time.Minute))
-
 	return encodedState, nil
 }
 
+// saveClientAuthRequestToCache saves the client's authorize request to the cache using the encoded
+// state as the key. Any existing request stored under the same key is replaced.
+func (s *Server) saveClientAuthRequestToCache(encodedState string, request clientAuthRequest) error {
+	key := fmt.Sprintf(authRequestKeyFmt, encodedState)
+
+	if err := s.cache.Add(key, request, time.Now().Add(10*time.Minute)); err != nil {
+		return fmt.Errorf("error saving the client auth request: %w", err)
+	}
+
+	return nil
+}
+
+// takeConsentedAuthRequest returns the client's authorization request that the profile accepted
+// or rejected on the consent page and deletes it from the cache so that it is only answered once.
+// Only the requests that were validated before the consent page was shown are returned. The
+// requests are never created from the HTTP request so that the validation cannot be skipped.
+func (s *Server) takeConsentedAuthRequest(encodedState, profileID string) (clientAuthRequest, error) {
+	cachedRequest, exists := s.cache.GetAndDelete(fmt.Sprintf(authRequestKeyFmt, encodedState))
+	if !exists || cachedRequest.Expired() {
+		return clientAuthRequest{}, StateKeyNotFoundInCacheError{encodedState: encodedState}
+	}
+
+	var request clientAuthRequest
+
+	if err := cachedRequest.Decode(&request); err != nil {
+		return clientAuthRequest{}, fmt.Errorf("error decoding the client auth request: %w", err)
+	}
+
+	if request.ConsentProfileID == "" || request.ConsentProfileID != profileID {
+		return clientAuthRequest{}, ErrUnvalidatedAuthRequest
+	}
+
+	return request, nil
+}
+
 // getClientAuthRequestFromCache attempts to retrieve the client's authorization request from the cache.
 func (s *Server) getClientAuthRequestFromCache(encodedState string) (clientAuthRequest, error) {
-	cachedRequest, exists := s.cache.Get(encodedState)
+	cachedRequest, exists := s.cache.Get(fmt.Sprintf(authRequestKeyFmt, encodedState))
 	if !exists {
 		return clientAuthRequest{}, StateKeyNotFoundInCacheError{encodedState: encodedState}
 	}
 
 	var request clientAuthRequest
 
-	if err := utilities.GobDecode(bytes.NewBuffer(cachedRequest.Value()), &request); err != nil {
-		return clientAuthRequest{}, fmt.Errorf("error gob decoding the client auth request: %w", err)
+	if err := cachedRequest.Decode(&request); err != nil {
+		return clientAuthRequest{}, fmt.Errorf("error decoding the client auth request: %w", err)
 	}
 
 	return request, nil
@@ -0,0 +1,346 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/config"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/policy"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+const (
+	settingsClientPolicy = "client_policy"
+
+	pathSettingsClientPolicy = "/profile/settings/clients"
+)
+
+// clientPolicy returns the effective client policy which combines the policy from the
+// configuration with the policy managed from the settings page. Rules from the settings
+// page replace the rules from the configuration for the same client.
+func (s *Server) clientPolicy() (policy.Policy, error) {
+	managedPolicy, err := s.store.GetClientPolicy()
+	if err != nil {
+		return policy.Policy{}, fmt.Errorf("error getting the client policy from the database: %w", err)
+	}
+
+	configRules := s.configClientPolicy.Rules
+	managedRules := managedPolicy.Rules
+	rules := make([]policy.Rule, 0, len(configRules)+len(managedRules))
+
+	for _, rule := range slices.Concat(configRules, managedRules) {
+		rules = append(rules, policy.Rule{
+			ClientID:      rule.ClientID,
+			MaxScopes:     rule.MaxScopes,
+			TokenLifetime: time.Duration(rule.TokenLifetime) * time.Second,
+			Trusted:       rule.Trusted,
+		})
+	}
+
+	clientPolicy, err := policy.NewPolicy(
+		slices.Concat(s.configClientPolicy.Allow, managedPolicy.Allow),
+		slices.Concat(s.configClientPolicy.Deny, managedPolicy.Deny),
+		rules,
+		s.defaultTokenLifetime,
+	)
+	if err != nil {
+		return policy.Policy{}, fmt.Errorf("error creating the client policy: %w", err)
+	}
+
+	return clientPolicy, nil
+}
+
+// newConfigClientPolicy converts the client policy from the configuration. The client IDs
+// are canonicalized so that they match the client IDs in the authorization requests.
+func newConfigClientPolicy(cfg config.ClientPolicy) (database.ClientPolicy, error) {
+	clientPolicy := database.ClientPolicy{
+		Allow: cfg.Allow,
+		Deny:  cfg.Deny,
+		Rules: make([]database.ClientPolicyRule, len(cfg.Clients)),
+	}
+
+	for ind, client := range cfg.Clients {
+		clientID, err := utilities.ValidateAndCanonicalizeClientID(client.ClientID)
+		if err != nil {
+			return database.ClientPolicy{}, fmt.Errorf("error canonicalizing the client ID %q: %w", client.ClientID, err)
+		}
+
+		clientPolicy.Rules[ind] = database.ClientPolicyRule{
+			ClientID:      clientID,
+			MaxScopes:     client.MaxScopes,
+			TokenLifetime: client.TokenLifetime,
+			Trusted:       client.Trusted,
+		}
+	}
+
+	return clientPolicy, nil
+}
+
+type settingsClientPolicyPage struct {
+	ActiveTab            string
+	ProfileID            string
+	Title                string
+	SettingsCategory     string
+	ConfiguredAllow      []string
+	ConfiguredDeny       []string
+	ConfiguredRules      []database.ClientPolicyRule
+	Allow                string
+	Deny                 string
+	Rules                []database.ClientPolicyRule
+	DefaultTokenLifetime int
+}
+
+func (s *Server) getClientPolicyPage(writer http.ResponseWriter, _ *http.Request, profileID string) {
+	managedPolicy, err := s.store.GetClientPolicy()
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error getting the client policy: %w", err),
+		)
+
+		return
+	}
+
+	page := settingsClientPolicyPage{
+		ActiveTab:            activeTabSettings,
+		ProfileID:            profileID,
+		Title:                clientPolicyPageTitle(),
+		SettingsCategory:     settingsClientPolicy,
+		ConfiguredAllow:      s.configClientPolicy.Allow,
+		ConfiguredDeny:       s.configClientPolicy.Deny,
+		ConfiguredRules:      s.configClientPolicy.Rules,
+		Allow:                strings.Join(managedPolicy.Allow, "\n"),
+		Deny:                 strings.Join(managedPolicy.Deny, "\n"),
+		Rules:                managedPolicy.Rules,
+		DefaultTokenLifetime: int(s.defaultTokenLifetime.Seconds()),
+	}
+
+	s.sendHTMLResponseWithTemplate(
+		writer,
+		"settings",
+		http.StatusOK,
+		page,
+		nil,
+		nil,
+	)
+}
+
+func (s *Server) updateClientPolicyHosts(writer http.ResponseWriter, request *http.Request, _ string) {
+	allow := policy.ParseList(request.PostFormValue("allow"))
+	deny := policy.ParseList(request.PostFormValue("deny"))
+
+	for _, pattern := range slices.Concat(allow, deny) {
+		if err := policy.ValidateHostPattern(pattern); err != nil {
+			s.sendFieldError(
+				writer,
+				fieldErrorLabel{
+					labelID: "host_patterns_error",
+					message: fmt.Sprintf("%q is not a valid host pattern", pattern),
+				},
+				err,
+			)
+
+			return
+		}
+	}
+
+	managedPolicy, err := s.store.GetClientPolicy()
+	if err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to update the client policy"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error getting the client policy: %w", err),
+		)
+
+		return
+	}
+
+	managedPolicy.Allow = allow
+	managedPolicy.Deny = deny
+
+	if err := s.store.SaveClientPolicy(managedPolicy); err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to update the client policy"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error saving the client policy: %w", err),
+		)
+
+		return
+	}
+
+	s.sendHTMLResponse(
+		writer,
+		fmt.Appendf([]byte{}, responseSuccessFmt, "Successfully updated the allowed and denied clients"),
+		http.StatusOK,
+		nil,
+		nil,
+	)
+}
+
+func (s *Server) saveClientPolicyRule(writer http.ResponseWriter, request *http.Request, _ string) {
+	clientID, err := utilities.ValidateAndCanonicalizeClientID(request.PostFormValue("clientID"))
+	if err != nil {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "client_id_error",
+				message: "Please enter a valid client ID",
+			},
+			fmt.Errorf("error canonicalizing the client ID: %w", err),
+		)
+
+		return
+	}
+
+	maxScopes := policy.ParseList(request.PostFormValue("maxScopes"))
+
+	for _, scope := range maxScopes {
+		if _, ok := s.scopes.Lookup(scope); !ok {
+			s.sendFieldError(
+				writer,
+				fieldErrorLabel{
+					labelID: "max_scopes_error",
+					message: fmt.Sprintf("%q is not a supported scope", scope),
+				},
+				formValidationError{reason: "the scope " + scope + " is not in the scope registry"},
+			)
+
+			return
+		}
+	}
+
+	tokenLifetime := 0
+
+	if value := request.PostFormValue("tokenLifetime"); value != "" {
+		tokenLifetime, err = strconv.Atoi(value)
+		if err != nil || tokenLifetime < 0 {
+			s.sendFieldError(
+				writer,
+				fieldErrorLabel{
+					labelID: "token_lifetime_error",
+					message: "The token lifetime must be a positive number of seconds",
+				},
+				formValidationError{reason: "the token lifetime " + value + " is invalid"},
+			)
+
+			return
+		}
+	}
+
+	rule := database.ClientPolicyRule{
+		ClientID:      clientID,
+		MaxScopes:     maxScopes,
+		TokenLifetime: tokenLifetime,
+		Trusted:       request.PostFormValue("trusted") == "on",
+	}
+
+	managedPolicy, err := s.store.GetClientPolicy()
+	if err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to save the client rule"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error getting the client policy: %w", err),
+		)
+
+		return
+	}
+
+	// Replace the existing rule for the client if there is one.
+	ind := slices.IndexFunc(managedPolicy.Rules, func(existing database.ClientPolicyRule) bool {
+		return existing.ClientID == clientID
+	})
+
+	if ind >= 0 {
+		managedPolicy.Rules[ind] = rule
+	} else {
+		managedPolicy.Rules = append(managedPolicy.Rules, rule)
+	}
+
+	if err := s.store.SaveClientPolicy(managedPolicy); err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to save the client rule"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error saving the client policy: %w", err),
+		)
+
+		return
+	}
+
+	writer.Header().Set("Hx-Redirect", pathSettingsClientPolicy)
+}
+
+func (s *Server) deleteClientPolicyRule(writer http.ResponseWriter, request *http.Request, _ string) {
+	clientID := request.PostFormValue("clientID")
+
+	managedPolicy, err := s.store.GetClientPolicy()
+	if err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to delete the client rule"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error getting the client policy: %w", err),
+		)
+
+		return
+	}
+
+	managedPolicy.Rules = slices.DeleteFunc(managedPolicy.Rules, func(rule database.ClientPolicyRule) bool {
+		return rule.ClientID == clientID
+	})
+
+	if err := s.store.SaveClientPolicy(managedPolicy); err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to delete the client rule"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error saving the client policy: %w", err),
+		)
+
+		return
+	}
+
+	writer.Header().Set("Hx-Redirect", pathSettingsClientPolicy)
+}
+
+// sendFieldError sends the error message for the form field identified by the label.
+func (s *Server) sendFieldError(writer http.ResponseWriter, label fieldErrorLabel, err error) {
+	writer.Header().Set("HX-Retarget", "#"+label.labelID)
+	writer.Header().Set("HX-Reswap", "outerHTML")
+
+	s.sendHTMLResponse(
+		writer,
+		fmt.Appendf(
+			[]byte{},
+			responselabelErrorFmt,
+			label.labelID,
+			label.message,
+		),
+		http.StatusUnprocessableEntity,
+		fmt.Errorf("error validating the form: %w", err),
+		nil,
+	)
+}
+
+func clientPolicyPageTitle() string {
+	return "Client policy - Settings - " + info.ApplicationTitledName
+}
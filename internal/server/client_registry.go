@@ -0,0 +1,494 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/policy"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+const (
+	settingsClientRegistry = "client_registry"
+
+	pathSettingsClientRegistry = "/profile/settings/registry"
+
+	qKeyClientSecret        string = "client_secret" // #nosec G101 -- This is not hardcoded credentials.
+	qKeyClientAssertion     string = "client_assertion"
+	qKeyClientAssertionType string = "client_assertion_type"
+
+	clientAssertionKeyFmt string = "client_assertion:%s:%s"
+)
+
+// registeredClientMetadata returns the metadata of the client from the client registry.
+// The boolean value is false if the client is not registered. Registered clients do not
+// need to publish their metadata.
+func (s *Server) registeredClientMetadata(clientID string) (discovery.ClientIDMetadata, bool, error) {
+	client, registered, err := s.store.GetRegisteredClient(clientID)
+	if err != nil {
+		return discovery.ClientIDMetadata{}, false, fmt.Errorf("error getting the registered client: %w", err)
+	}
+
+	if !registered {
+		return discovery.ClientIDMetadata{}, false, nil
+	}
+
+	return discovery.ClientIDMetadata{
+		ClientID:     client.ClientID,
+		ClientName:   client.Name,
+		RedirectURIs: client.RedirectURIs,
+	}, true, nil
+}
+
+// checkBackChannelClient runs the client checks shared by the endpoints that clients call
+// directly. The client must be permitted by the client policy and must authenticate itself if
+// it is registered. The client's metadata is returned if the checks pass, otherwise the OAuth
+// error is sent to the client and the boolean value is false.
+func (s *Server) checkBackChannelClient(
+	writer http.ResponseWriter,
+	request *http.Request,
+	clientID string,
+) (discovery.ClientIDMetadata, bool) {
+	clientPolicy, err := s.clientPolicy()
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error getting the client policy: %w", err),
+		)
+
+		return discovery.ClientIDMetadata{}, false
+	}
+
+	if err := clientPolicy.CheckClient(clientID); err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"unauthorized_client",
+			fmt.Errorf("the client is not permitted by the client policy: %w", err),
+		)
+
+		return discovery.ClientIDMetadata{}, false
+	}
+
+	if err := s.authenticateClient(request, clientID); err != nil {
+		if errors.As(err, &ClientAuthenticationError{}) {
+			sendOAuthError(writer, http.StatusUnauthorized, "invalid_client", err)
+		} else {
+			sendOAuthError(
+				writer,
+				http.StatusInternalServerError,
+				"server_error",
+				fmt.Errorf("error authenticating the client: %w", err),
+			)
+		}
+
+		return discovery.ClientIDMetadata{}, false
+	}
+
+	clientMetadata, registered, err := s.registeredClientMetadata(clientID)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error checking the client registry: %w", err),
+		)
+
+		return discovery.ClientIDMetadata{}, false
+	}
+
+	if registered {
+		return clientMetadata, true
+	}
+
+	outboundCtx := s.outboundContext(request.Context(), clientID)
+
+	if err := discovery.ValidateClientID(outboundCtx, s.outboundGuard, clientID); err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_request",
+			fmt.Errorf("error validating the client ID: %w", err),
+		)
+
+		return discovery.ClientIDMetadata{}, false
+	}
+
+	clientMetadata, err = s.clientMetadata.Get(outboundCtx, clientID)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error fetching the client's metadata: %w", err),
+		)
+
+		return discovery.ClientIDMetadata{}, false
+	}
+
+	return clientMetadata, true
+}
+
+// requestClientID returns the client ID from the form of the client's request. Confidential
+// clients using HTTP Basic authentication may only send their client ID in the Authorization
+// header in which case it is taken from there.
+func requestClientID(request *http.Request) string {
+	if clientID := request.PostForm.Get(qKeyClientID); clientID != "" {
+		return clientID
+	}
+
+	username, _, ok := request.BasicAuth()
+	if !ok {
+		return ""
+	}
+
+	clientID, err := url.QueryUnescape(username)
+	if err != nil {
+		return ""
+	}
+
+	return clientID
+}
+
+// authenticateClient authenticates the client at the token and pushed authorization request
+// endpoints. Registered clients must authenticate with the method they were registered with.
+// Public clients are identified by their client ID alone and must not send any credentials.
+// A ClientAuthenticationError is returned if the client fails to authenticate.
+func (s *Server) authenticateClient(request *http.Request, clientID string) error {
+	client, registered, err := s.store.GetRegisteredClient(clientID)
+	if err != nil {
+		return fmt.Errorf("error getting the registered client: %w", err)
+	}
+
+	_, _, hasBasic := request.BasicAuth()
+	hasSecret := request.PostForm.Has(qKeyClientSecret)
+	hasAssertion := request.PostForm.Has(qKeyClientAssertion)
+
+	if !registered {
+		if hasBasic || hasSecret || hasAssertion {
+			return ClientAuthenticationError{reason: "the client is not registered as a confidential client"}
+		}
+
+		return nil
+	}
+
+	switch client.AuthMethod {
+	case auth.ClientAuthMethodSecretBasic:
+		if hasSecret || hasAssertion {
+			return ClientAuthenticationError{reason: "the client used more than one authentication method"}
+		}
+
+		return s.checkBasicAuthentication(request, client)
+	case auth.ClientAuthMethodSecretPost:
+		if hasBasic || hasAssertion {
+			return ClientAuthenticationError{reason: "the client used more than one authentication method"}
+		}
+
+		return checkClientSecret(client, request.PostForm.Get(qKeyClientSecret))
+	case auth.ClientAuthMethodPrivateKeyJWT:
+		if hasBasic || hasSecret {
+			return ClientAuthenticationError{reason: "the client used more than one authentication method"}
+		}
+
+		return s.checkClientAssertion(request.PostForm, client)
+	default:
+		return fmt.Errorf("unsupported authentication method %q for the registered client", client.AuthMethod)
+	}
+}
+
+// checkBasicAuthentication checks the client's credentials in the Authorization header.
+// The credentials are form-urlencoded before they are encoded with Base64 (RFC 6749, section 2.3.1).
+func (s *Server) checkBasicAuthentication(request *http.Request, client database.RegisteredClient) error {
+	username, password, ok := request.BasicAuth()
+	if !ok {
+		return ClientAuthenticationError{reason: "the client did not use HTTP Basic authentication"}
+	}
+
+	username, err := url.QueryUnescape(username)
+	if err != nil {
+		return ClientAuthenticationError{reason: "the client ID in the Authorization header is malformed"}
+	}
+
+	password, err = url.QueryUnescape(password)
+	if err != nil {
+		return ClientAuthenticationError{reason: "the client secret in the Authorization header is malformed"}
+	}
+
+	clientID, err := utilities.ValidateAndCanonicalizeClientID(username)
+	if err != nil || clientID != client.ClientID {
+		return ClientAuthenticationError{reason: "the client ID in the Authorization header does not match the client ID in the request"}
+	}
+
+	return checkClientSecret(client, password)
+}
+
+func checkClientSecret(client database.RegisteredClient, secret string) error {
+	if secret == "" {
+		return ClientAuthenticationError{reason: "the client secret is missing"}
+	}
+
+	if err := auth.CheckPasswordHash(client.SecretHash, secret); err != nil {
+		return ClientAuthenticationError{reason: "the client secret is incorrect"}
+	}
+
+	return nil
+}
+
+// checkClientAssertion verifies the client assertion sent by a client authenticating with
+// private_key_jwt. Each assertion can only be used once so the IDs of the used assertions
+// are kept in the replay cache until the assertions expire.
+func (s *Server) checkClientAssertion(form url.Values, client database.RegisteredClient) error {
+	if form.Get(qKeyClientAssertionType) != auth.ClientAssertionType {
+		return ClientAuthenticationError{reason: "the client assertion type is missing or unsupported"}
+	}
+
+	publicKey, err := auth.ParsePublicKey(client.PublicKey)
+	if err != nil {
+		return fmt.Errorf("error parsing the public key of the registered client: %w", err)
+	}
+
+	assertion, err := auth.VerifyClientAssertion(
+		form.Get(qKeyClientAssertion),
+		publicKey,
+		[]string{s.issuer, s.tokenEndpoint, s.parEndpoint},
+	)
+	if err != nil {
+		return ClientAuthenticationError{reason: err.Error()}
+	}
+
+	for _, claim := range []string{assertion.Issuer, assertion.Subject} {
+		clientID, err := utilities.ValidateAndCanonicalizeClientID(claim)
+		if err != nil || clientID != client.ClientID {
+			return ClientAuthenticationError{reason: "the issuer and subject of the client assertion must be the client ID"}
+		}
+	}
+
+	key := fmt.Sprintf(clientAssertionKeyFmt, client.ClientID, assertion.ID)
+
+	added, err := s.replayCache.AddIfAbsent(key, nil, assertion.ExpiresAt.Add(1*time.Minute))
+	if err != nil {
+		return fmt.Errorf("error saving the ID of the client assertion: %w", err)
+	}
+
+	if !added {
+		return ClientAuthenticationError{reason: "the client assertion has already been used"}
+	}
+
+	return nil
+}
+
+type settingsClientRegistryPage struct {
+	ActiveTab        string
+	ProfileID        string
+	Title            string
+	SettingsCategory string
+	Clients          []database.RegisteredClient
+	AuthMethods      []string
+}
+
+func (s *Server) getClientRegistryPage(writer http.ResponseWriter, _ *http.Request, profileID string) {
+	clients, err := s.store.GetRegisteredClients()
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error getting the registered clients: %w", err),
+		)
+
+		return
+	}
+
+	page := settingsClientRegistryPage{
+		ActiveTab:        activeTabSettings,
+		ProfileID:        profileID,
+		Title:            clientRegistryPageTitle(),
+		SettingsCategory: settingsClientRegistry,
+		Clients:          clients,
+		AuthMethods:      auth.ClientAuthMethods(),
+	}
+
+	s.sendHTMLResponseWithTemplate(
+		writer,
+		"settings",
+		http.StatusOK,
+		page,
+		nil,
+		nil,
+	)
+}
+
+func (s *Server) registerClient(writer http.ResponseWriter, request *http.Request, _ string) {
+	clientID, err := utilities.ValidateAndCanonicalizeClientID(request.PostFormValue("clientID"))
+	if err != nil {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "client_id_error",
+				message: "Please enter a valid client ID",
+			},
+			fmt.Errorf("error canonicalizing the client ID: %w", err),
+		)
+
+		return
+	}
+
+	redirectURIs := policy.ParseList(request.PostFormValue("redirectURIs"))
+
+	if len(redirectURIs) == 0 {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "redirect_uris_error",
+				message: "Please enter at least one redirect URI",
+			},
+			formValidationError{reason: "the redirect URIs are missing"},
+		)
+
+		return
+	}
+
+	for _, redirectURI := range redirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			s.sendFieldError(
+				writer,
+				fieldErrorLabel{
+					labelID: "redirect_uris_error",
+					message: fmt.Sprintf("%q is not a valid redirect URI", redirectURI),
+				},
+				formValidationError{reason: "the redirect URI " + redirectURI + " is invalid"},
+			)
+
+			return
+		}
+	}
+
+	authMethod := request.PostFormValue("authMethod")
+
+	if !auth.ValidClientAuthMethod(authMethod) {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "auth_method_error",
+				message: "Please select a supported authentication method",
+			},
+			formValidationError{reason: "the authentication method " + authMethod + " is not supported"},
+		)
+
+		return
+	}
+
+	client := database.RegisteredClient{
+		ClientID:     clientID,
+		Name:         request.PostFormValue("name"),
+		RedirectURIs: redirectURIs,
+		AuthMethod:   authMethod,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	var secret string
+
+	if authMethod == auth.ClientAuthMethodPrivateKeyJWT {
+		publicKey := request.PostFormValue("publicKey")
+
+		if _, err := auth.ParsePublicKey(publicKey); err != nil {
+			s.sendFieldError(
+				writer,
+				fieldErrorLabel{
+					labelID: "public_key_error",
+					message: "Please enter a PEM encoded RSA, ECDSA or Ed25519 public key",
+				},
+				err,
+			)
+
+			return
+		}
+
+		client.PublicKey = publicKey
+	} else {
+		secret, err = auth.CreateClientSecret()
+		if err != nil {
+			s.sendHTMLResponse(
+				writer,
+				fmt.Appendf([]byte{}, responseFailureFmt, "Unable to register the client"),
+				http.StatusInternalServerError,
+				nil,
+				fmt.Errorf("error creating the client secret: %w", err),
+			)
+
+			return
+		}
+
+		client.SecretHash, err = auth.HashPassword(secret)
+		if err != nil {
+			s.sendHTMLResponse(
+				writer,
+				fmt.Appendf([]byte{}, responseFailureFmt, "Unable to register the client"),
+				http.StatusInternalServerError,
+				nil,
+				fmt.Errorf("error hashing the client secret: %w", err),
+			)
+
+			return
+		}
+	}
+
+	if err := s.store.SaveRegisteredClient(client); err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to register the client"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error saving the registered client: %w", err),
+		)
+
+		return
+	}
+
+	message := "Successfully registered the client"
+
+	// The client secret is only shown once as only its hash is stored.
+	if secret != "" {
+		message += `. The client secret is <span class="highlight">` + secret + `</span>. Copy it now as it will not be shown again.`
+	}
+
+	s.sendHTMLResponse(
+		writer,
+		fmt.Appendf([]byte{}, responseSuccessFmt, message),
+		http.StatusOK,
+		nil,
+		nil,
+	)
+}
+
+func (s *Server) deleteRegisteredClient(writer http.ResponseWriter, request *http.Request, _ string) {
+	if err := s.store.DeleteRegisteredClient(request.PostFormValue("clientID")); err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to delete the client"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error deleting the registered client: %w", err),
+		)
+
+		return
+	}
+
+	writer.Header().Set("Hx-Redirect", pathSettingsClientRegistry)
+}
+
+func clientRegistryPageTitle() string {
+	return "Client registry - Settings - " + info.ApplicationTitledName
+}
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testClientAuthentication(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		secret := "Xq6cGhJz1yTnIGUvBWYB2fkgTt4jQmyl"
+
+		secretHash, err := auth.HashPassword(secret)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to hash the client secret: %v", t.Name(), err)
+		}
+
+		clients := []database.RegisteredClient{
+			{
+				ClientID:   "https://basic.example.org/",
+				AuthMethod: auth.ClientAuthMethodSecretBasic,
+				SecretHash: secretHash,
+			},
+			{
+				ClientID:   "https://post.example.org/",
+				AuthMethod: auth.ClientAuthMethodSecretPost,
+				SecretHash: secretHash,
+			},
+		}
+
+		for _, client := range clients {
+			if err := srv.store.SaveRegisteredClient(client); err != nil {
+				t.Fatalf("FAILED test %s: Unable to register the client: %v", t.Name(), err)
+			}
+		}
+
+		testCases := []struct {
+			name          string
+			clientID      string
+			form          url.Values
+			basicUsername string
+			basicPassword string
+			wantErr       bool
+		}{
+			{
+				name:          "Registered client with the correct secret in the Authorization header",
+				clientID:      "https://basic.example.org/",
+				basicUsername: url.QueryEscape("https://basic.example.org/"),
+				basicPassword: secret,
+				wantErr:       false,
+			},
+			{
+				name:          "Registered client with an incorrect secret in the Authorization header",
+				clientID:      "https://basic.example.org/",
+				basicUsername: url.QueryEscape("https://basic.example.org/"),
+				basicPassword: "incorrect",
+				wantErr:       true,
+			},
+			{
+				name:     "Registered client using a different authentication method",
+				clientID: "https://basic.example.org/",
+				form:     url.Values{qKeyClientSecret: {secret}},
+				wantErr:  true,
+			},
+			{
+				name:     "Registered client with the correct secret in the form",
+				clientID: "https://post.example.org/",
+				form:     url.Values{qKeyClientSecret: {secret}},
+				wantErr:  false,
+			},
+			{
+				name:     "Registered client without credentials",
+				clientID: "https://post.example.org/",
+				wantErr:  true,
+			},
+			{
+				name:     "Public client without credentials",
+				clientID: "https://public.example.org/",
+				wantErr:  false,
+			},
+			{
+				name:     "Public client with credentials",
+				clientID: "https://public.example.org/",
+				form:     url.Values{qKeyClientSecret: {secret}},
+				wantErr:  true,
+			},
+		}
+
+		for _, tc := range testCases {
+			request := httptest.NewRequest(http.MethodPost, pathToken, strings.NewReader(tc.form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			if tc.basicUsername != "" {
+				request.SetBasicAuth(tc.basicUsername, tc.basicPassword)
+			}
+
+			if err := request.ParseForm(); err != nil {
+				t.Fatalf("FAILED test %s: Unable to parse the form: %v", t.Name(), err)
+			}
+
+			err := srv.authenticateClient(request, tc.clientID)
+
+			switch {
+			case tc.wantErr && !errors.As(err, &ClientAuthenticationError{}):
+				t.Errorf(
+					"FAILED test %s: %s: Unexpected error received.\nwant: ClientAuthenticationError\ngot: %v",
+					t.Name(),
+					tc.name,
+					err,
+				)
+			case !tc.wantErr && err != nil:
+				t.Errorf(
+					"FAILED test %s: %s: Received an error authenticating the client.\ngot: %q",
+					t.Name(),
+					tc.name,
+					err.Error(),
+				)
+			default:
+				t.Logf("%s: Expected result received.", tc.name)
+			}
+		}
+	}
+}
+
+func testClientAssertionReplay(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to generate the key pair: %v", t.Name(), err)
+		}
+
+		publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to encode the public key: %v", t.Name(), err)
+		}
+
+		client := database.RegisteredClient{
+			ClientID:   "https://jwt.example.org/",
+			AuthMethod: auth.ClientAuthMethodPrivateKeyJWT,
+			PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})),
+		}
+
+		assertion, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+			ID:        "assertion-1",
+			Issuer:    client.ClientID,
+			Subject:   client.ClientID,
+			Audience:  jwt.ClaimStrings{srv.tokenEndpoint},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Minute)),
+		}).SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to sign the client assertion: %v", t.Name(), err)
+		}
+
+		form := url.Values{
+			qKeyClientAssertionType: {auth.ClientAssertionType},
+			qKeyClientAssertion:     {assertion},
+		}
+
+		// Only one of the concurrent requests with the same assertion is authenticated.
+		var (
+			wg            sync.WaitGroup
+			authenticated atomic.Int32
+		)
+
+		for range 10 {
+			wg.Go(func() {
+				if err := srv.checkClientAssertion(form, client); err == nil {
+					authenticated.Add(1)
+				}
+			})
+		}
+
+		wg.Wait()
+
+		if authenticated.Load() != 1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of concurrent requests authenticated with the same assertion.\nwant: 1\ngot: %d",
+				t.Name(),
+				authenticated.Load(),
+			)
+		} else {
+			t.Log("Only one of the concurrent requests was authenticated with the assertion.")
+		}
+	}
+}
@@ -0,0 +1,627 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/scopes"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+const (
+	deviceGrantType string = "urn:ietf:params:oauth:grant-type:device_code"
+
+	qKeyDeviceCode string = "device_code"
+	qKeyUserCode   string = "user_code"
+
+	pathDevice       string = "/device"
+	pathDeviceAccept string = pathDevice + "/accept"
+	pathDeviceReject string = pathDevice + "/reject"
+
+	deviceCodeKeyFmt string = "device_code:%s"
+	userCodeKeyFmt   string = "user_code:%s"
+
+	deviceCodeLifetime    time.Duration = 10 * time.Minute
+	devicePollingInterval time.Duration = 5 * time.Second
+
+	// The user codes only use consonants to avoid spelling out words
+	// and to avoid characters that are easily confused with each other.
+	userCodeCharacters string = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength     int    = 8
+)
+
+type deviceAuthStatus int
+
+const (
+	deviceAuthPending deviceAuthStatus = iota
+	deviceAuthApproved
+	deviceAuthDenied
+)
+
+// deviceAuthRequest is the state of a device authorization request (RFC 8628)
+// which is kept in the cache under the device code.
+type deviceAuthRequest struct {
+	ClientID     string           `json:"client_id"`
+	ClientName   string           `json:"client_name"`
+	ClientURI    string           `json:"client_uri"`
+	LogoURI      string           `json:"logo_uri"`
+	Scopes       []string         `json:"scopes"`
+	Status       deviceAuthStatus `json:"status"`
+	Me           string           `json:"me"`
+	Interval     time.Duration    `json:"interval"`
+	LastPolledAt time.Time        `json:"last_polled_at"`
+	ExpiresAt    time.Time        `json:"expires_at"`
+}
+
+type deviceAuthResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// authorizeDevice handles the device authorization requests from clients running on devices
+// that can't redirect the user to a browser. The client displays the user code and the
+// verification URI to the user and polls the token endpoint with the device code while the
+// user approves the request from another device.
+func (s *Server) authorizeDevice(writer http.ResponseWriter, request *http.Request) {
+	clientID, err := utilities.ValidateAndCanonicalizeClientID(requestClientID(request))
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_request",
+			fmt.Errorf("error canonicalizing the client ID: %w", err),
+		)
+
+		return
+	}
+
+	clientMetadata, ok := s.checkBackChannelClient(writer, request, clientID)
+	if !ok {
+		return
+	}
+
+	requestedScopes := make([]string, 0)
+
+	if scopeStr := request.PostFormValue(qKeyScope); scopeStr != "" {
+		requestedScopes = strings.Split(scopeStr, " ")
+	}
+
+	resolvedScopes, err := s.scopes.Resolve(requestedScopes)
+	if err != nil {
+		if errors.As(err, &scopes.InvalidScopeError{}) {
+			sendOAuthError(writer, http.StatusBadRequest, "invalid_scope", err)
+
+			return
+		}
+
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error resolving the requested scopes: %w", err),
+		)
+
+		return
+	}
+
+	deviceCodeBytes := make([]byte, 32)
+
+	if _, err := rand.Read(deviceCodeBytes); err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("unable to create random bytes: %w", err),
+		)
+
+		return
+	}
+
+	deviceCode := hex.EncodeToString(deviceCodeBytes)
+
+	userCode, err := s.newUserCode()
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error creating the user code: %w", err),
+		)
+
+		return
+	}
+
+	expiresAt := time.Now().Add(deviceCodeLifetime)
+
+	deviceReq := deviceAuthRequest{
+		ClientID:   clientID,
+		ClientName: clientMetadata.ClientName,
+		ClientURI:  clientMetadata.ClientURI,
+		LogoURI:    clientMetadata.LogoURI,
+		Scopes:     scopes.Names(resolvedScopes),
+		Status:     deviceAuthPending,
+		Interval:   devicePollingInterval,
+		ExpiresAt:  expiresAt,
+	}
+
+	if err := s.saveDeviceAuthRequest(deviceCode, deviceReq); err != nil {
+		sendOAuthCacheError(
+			writer,
+			fmt.Errorf("error saving the device authorization request: %w", err),
+		)
+
+		return
+	}
+
+	if err := s.cache.Add(fmt.Sprintf(userCodeKeyFmt, userCode), deviceCode, expiresAt); err != nil {
+		s.cache.Delete(fmt.Sprintf(deviceCodeKeyFmt, deviceCode))
+		sendOAuthCacheError(
+			writer,
+			fmt.Errorf("error saving the user code: %w", err),
+		)
+
+		return
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+
+	sendJSONResponse(
+		writer,
+		http.StatusOK,
+		deviceAuthResponse{
+			DeviceCode:              deviceCode,
+			UserCode:                formatUserCode(userCode),
+			VerificationURI:         s.deviceVerificationURI,
+			VerificationURIComplete: s.deviceVerificationURI + "?" + qKeyUserCode + "=" + formatUserCode(userCode),
+			ExpiresIn:               int64(deviceCodeLifetime.Seconds()),
+			Interval:                int64(devicePollingInterval.Seconds()),
+		},
+	)
+}
+
+// deviceTokenExchange handles the device access token requests from the polling clients.
+func (s *Server) deviceTokenExchange(writer http.ResponseWriter, request *http.Request, proof *auth.DPoPProof) {
+	clientID, err := utilities.ValidateAndCanonicalizeClientID(requestClientID(request))
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_request",
+			fmt.Errorf("error canonicalizing the client ID: %w", err),
+		)
+
+		return
+	}
+
+	if err := s.authenticateClient(request, clientID); err != nil {
+		if errors.As(err, &ClientAuthenticationError{}) {
+			sendOAuthError(writer, http.StatusUnauthorized, "invalid_client", err)
+		} else {
+			sendOAuthError(
+				writer,
+				http.StatusInternalServerError,
+				"server_error",
+				fmt.Errorf("error authenticating the client: %w", err),
+			)
+		}
+
+		return
+	}
+
+	deviceCode := request.PostFormValue(qKeyDeviceCode)
+
+	// The poll is recorded in the same step as it is read so that it does not
+	// overwrite a decision that the user makes at the same time.
+	var errorCode string
+
+	deviceReq, found, err := s.updateDeviceAuthRequest(deviceCode, func(deviceReq *deviceAuthRequest) bool {
+		if deviceReq.Status != deviceAuthPending ||
+			deviceReq.ClientID != clientID ||
+			time.Now().After(deviceReq.ExpiresAt) {
+			return false
+		}
+
+		// Clients that poll faster than the interval are asked to slow down
+		// and the interval is increased by 5 seconds (RFC 8628, section 3.5).
+		now := time.Now()
+		errorCode = "authorization_pending"
+
+		if now.Sub(deviceReq.LastPolledAt) < deviceReq.Interval {
+			errorCode = "slow_down"
+			deviceReq.Interval += devicePollingInterval
+		}
+
+		deviceReq.LastPolledAt = now
+
+		return true
+	})
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error getting the device authorization request: %w", err),
+		)
+
+		return
+	}
+
+	if !found {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_grant", ErrInvalidDeviceCode)
+
+		return
+	}
+
+	if deviceReq.ClientID != clientID {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_grant",
+			MismatchedClientIDError{
+				exchangedClientID: clientID,
+				initialClientID:   deviceReq.ClientID,
+			},
+		)
+
+		return
+	}
+
+	if time.Now().After(deviceReq.ExpiresAt) {
+		s.cache.Delete(fmt.Sprintf(deviceCodeKeyFmt, deviceCode))
+		sendOAuthError(writer, http.StatusBadRequest, "expired_token", ErrExpiredDeviceCode)
+
+		return
+	}
+
+	switch deviceReq.Status {
+	case deviceAuthApproved:
+		// The device code can only be exchanged once.
+		if _, exists := s.cache.GetAndDelete(fmt.Sprintf(deviceCodeKeyFmt, deviceCode)); !exists {
+			sendOAuthError(writer, http.StatusBadRequest, "invalid_grant", ErrInvalidDeviceCode)
+
+			return
+		}
+
+		s.tokenExchange(writer, clientRequestData{
+			ClientID: deviceReq.ClientID,
+			Scopes:   deviceReq.Scopes,
+			Me:       deviceReq.Me,
+		}, proof)
+	case deviceAuthDenied:
+		s.cache.Delete(fmt.Sprintf(deviceCodeKeyFmt, deviceCode))
+		sendOAuthError(writer, http.StatusBadRequest, "access_denied", ErrDeviceAccessDenied)
+	default:
+		// The pending responses are part of the normal flow so they are not logged as errors.
+		writer.Header().Set("Cache-Control", "no-store")
+		sendJSONResponse(writer, http.StatusBadRequest, oauthErrorResponse{Error: errorCode})
+	}
+}
+
+type devicePage struct {
+	Title     string
+	ProfileID string
+	UserCode  string
+	Status    string
+	Error     string
+}
+
+func (s *Server) getDevicePage(writer http.ResponseWriter, request *http.Request, profileID string) {
+	s.sendHTMLResponseWithTemplate(
+		writer,
+		"device",
+		http.StatusOK,
+		devicePage{
+			Title:     devicePageTitle(),
+			ProfileID: profileID,
+			UserCode:  request.URL.Query().Get(qKeyUserCode),
+			Status:    request.URL.Query().Get("status"),
+		},
+		nil,
+		nil,
+	)
+}
+
+// deviceConsent shows the consent page for the device authorization request
+// identified by the user code that the user entered.
+func (s *Server) deviceConsent(writer http.ResponseWriter, request *http.Request, profileID string) {
+	userCode := normalizeUserCode(request.PostFormValue(qKeyUserCode))
+
+	_, deviceReq, found, err := s.getDeviceAuthRequestByUserCode(userCode)
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error getting the device authorization request: %w", err),
+		)
+
+		return
+	}
+
+	if !found || deviceReq.Status != deviceAuthPending {
+		s.sendHTMLResponseWithTemplate(
+			writer,
+			"device",
+			http.StatusNotFound,
+			devicePage{
+				Title:     devicePageTitle(),
+				ProfileID: profileID,
+				UserCode:  request.PostFormValue(qKeyUserCode),
+				Error:     "The code is invalid or has expired. Please check the code on your device and try again.",
+			},
+			ErrInvalidUserCode,
+			nil,
+		)
+
+		return
+	}
+
+	requestedScopes, err := s.scopes.Resolve(deviceReq.Scopes)
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error resolving the requested scopes: %w", err),
+		)
+
+		return
+	}
+
+	consent := consentPage{
+		Title:         consentPageTitle(),
+		ClientID:      deviceReq.ClientID,
+		ClientName:    deviceReq.ClientName,
+		ClientURI:     deviceReq.ClientURI,
+		ClientLogoURI: s.proxiedClientLogoURI(s.outboundContext(request.Context(), deviceReq.ClientID), writer, deviceReq.LogoURI),
+		ProfileID:     profileID,
+		AcceptURI:     pathDeviceAccept,
+		RejectURI:     pathDeviceReject,
+		State:         userCode,
+		Scopes:        requestedScopes,
+	}
+
+	s.sendHTMLResponseWithTemplate(
+		writer,
+		"consent",
+		http.StatusOK,
+		consent,
+		nil,
+		nil,
+	)
+}
+
+func (s *Server) deviceAccept(writer http.ResponseWriter, request *http.Request, profileID string) {
+	s.completeDeviceAuthRequest(writer, request, profileID, deviceAuthApproved)
+}
+
+func (s *Server) deviceReject(writer http.ResponseWriter, request *http.Request, profileID string) {
+	s.completeDeviceAuthRequest(writer, request, profileID, deviceAuthDenied)
+}
+
+// completeDeviceAuthRequest records the user's decision for the device authorization request.
+// The client receives the decision the next time it polls the token endpoint.
+func (s *Server) completeDeviceAuthRequest(
+	writer http.ResponseWriter,
+	request *http.Request,
+	profileID string,
+	status deviceAuthStatus,
+) {
+	userCode := normalizeUserCode(request.PostFormValue("state"))
+
+	// The user code can only be used once.
+	entry, exists := s.cache.GetAndDelete(fmt.Sprintf(userCodeKeyFmt, userCode))
+	if !exists || entry.Expired() {
+		sendClientError(writer, http.StatusNotFound, ErrInvalidUserCode)
+
+		return
+	}
+
+	var deviceCode string
+
+	if err := entry.Decode(&deviceCode); err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error decoding the device code: %w", err),
+		)
+
+		return
+	}
+
+	completed := false
+
+	if _, _, err := s.updateDeviceAuthRequest(deviceCode, func(deviceReq *deviceAuthRequest) bool {
+		completed = deviceReq.Status == deviceAuthPending && !time.Now().After(deviceReq.ExpiresAt)
+		if !completed {
+			return false
+		}
+
+		deviceReq.Status = status
+		deviceReq.Me = profileID
+
+		return true
+	}); err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error updating the device authorization request: %w", err),
+		)
+
+		return
+	}
+
+	if !completed {
+		sendClientError(writer, http.StatusNotFound, ErrInvalidUserCode)
+
+		return
+	}
+
+	result := "approved"
+	if status == deviceAuthDenied {
+		result = "denied"
+	}
+
+	writer.Header().Set("Hx-Redirect", pathDevice+"?status="+result)
+}
+
+func (s *Server) saveDeviceAuthRequest(deviceCode string, deviceReq deviceAuthRequest) error {
+	return s.cache.Add(fmt.Sprintf(deviceCodeKeyFmt, deviceCode), deviceReq, deviceAuthRequestExpiresAt(deviceReq))
+}
+
+// updateDeviceAuthRequest applies the update to the device authorization request for the
+// device code and saves it only if the request has not changed in the meantime. The update
+// is applied again to the changed request until it is saved. The update returns false to
+// leave the request unchanged. The updated request is returned and the boolean value is
+// false if there is no request for the device code.
+func (s *Server) updateDeviceAuthRequest(
+	deviceCode string,
+	update func(deviceReq *deviceAuthRequest) bool,
+) (deviceAuthRequest, bool, error) {
+	if deviceCode == "" {
+		return deviceAuthRequest{}, false, nil
+	}
+
+	key := fmt.Sprintf(deviceCodeKeyFmt, deviceCode)
+
+	for {
+		entry, exists := s.cache.Get(key)
+		if !exists {
+			return deviceAuthRequest{}, false, nil
+		}
+
+		var deviceReq deviceAuthRequest
+
+		if err := entry.Decode(&deviceReq); err != nil {
+			return deviceAuthRequest{}, false, fmt.Errorf("error decoding the device authorization request: %w", err)
+		}
+
+		if !update(&deviceReq) {
+			return deviceReq, true, nil
+		}
+
+		swapped, err := s.cache.CompareAndSwap(key, entry, deviceReq, deviceAuthRequestExpiresAt(deviceReq))
+		if err != nil {
+			return deviceAuthRequest{}, false, fmt.Errorf("error saving the device authorization request: %w", err)
+		}
+
+		if swapped {
+			return deviceReq, true, nil
+		}
+	}
+}
+
+// deviceAuthRequestExpiresAt returns the time when the request is removed from the cache.
+// The request is kept for a little longer than the device code's lifetime so that
+// polling clients are told that the device code has expired.
+func deviceAuthRequestExpiresAt(deviceReq deviceAuthRequest) time.Time {
+	return deviceReq.ExpiresAt.Add(1 * time.Minute)
+}
+
+// getDeviceAuthRequest returns the device authorization request for the device code.
+// The boolean value is false if there is no request for the device code.
+func (s *Server) getDeviceAuthRequest(deviceCode string) (deviceAuthRequest, bool, error) {
+	if deviceCode == "" {
+		return deviceAuthRequest{}, false, nil
+	}
+
+	entry, exists := s.cache.Get(fmt.Sprintf(deviceCodeKeyFmt, deviceCode))
+	if !exists {
+		return deviceAuthRequest{}, false, nil
+	}
+
+	var deviceReq deviceAuthRequest
+
+	if err := entry.Decode(&deviceReq); err != nil {
+		return deviceAuthRequest{}, false, fmt.Errorf("error decoding the device authorization request: %w", err)
+	}
+
+	return deviceReq, true, nil
+}
+
+// getDeviceAuthRequestByUserCode returns the device code and the device authorization request
+// for the normalized user code. The boolean value is false if the user code is unknown or expired.
+func (s *Server) getDeviceAuthRequestByUserCode(userCode string) (string, deviceAuthRequest, bool, error) {
+	if userCode == "" {
+		return "", deviceAuthRequest{}, false, nil
+	}
+
+	entry, exists := s.cache.Get(fmt.Sprintf(userCodeKeyFmt, userCode))
+	if !exists || entry.Expired() {
+		return "", deviceAuthRequest{}, false, nil
+	}
+
+	var deviceCode string
+
+	if err := entry.Decode(&deviceCode); err != nil {
+		return "", deviceAuthRequest{}, false, fmt.Errorf("error decoding the device code: %w", err)
+	}
+
+	deviceReq, found, err := s.getDeviceAuthRequest(deviceCode)
+	if err != nil || !found {
+		return "", deviceAuthRequest{}, false, err
+	}
+
+	if time.Now().After(deviceReq.ExpiresAt) {
+		return "", deviceAuthRequest{}, false, nil
+	}
+
+	return deviceCode, deviceReq, true, nil
+}
+
+// newUserCode creates a new random user code that is not already in use.
+func (s *Server) newUserCode() (string, error) {
+	maxIndex := big.NewInt(int64(len(userCodeCharacters)))
+
+	for range 10 {
+		var builder strings.Builder
+
+		for range userCodeLength {
+			ind, err := rand.Int(rand.Reader, maxIndex)
+			if err != nil {
+				return "", fmt.Errorf("unable to create a random number: %w", err)
+			}
+
+			builder.WriteByte(userCodeCharacters[ind.Int64()])
+		}
+
+		userCode := builder.String()
+
+		if _, exists := s.cache.Get(fmt.Sprintf(userCodeKeyFmt, userCode)); !exists {
+			return userCode, nil
+		}
+	}
+
+	return "", errors.New("unable to create a unique user code")
+}
+
+// formatUserCode formats the user code for display (e.g. WDJB-MJHT).
+func formatUserCode(userCode string) string {
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+// normalizeUserCode normalizes the user code entered by the user. The user code is
+// case insensitive and any separators entered by the user are ignored.
+func normalizeUserCode(input string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, strings.ToUpper(input))
+}
+
+func devicePageTitle() string {
+	return "Connect a device - " + info.ApplicationTitledName
+}
@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+const (
+	testDeviceClientID     string = "https://device.example.org/"
+	testDeviceClientSecret string = "dmHCmYRfpGRfdKv1X4CWN5jRAV0LDQNS"
+)
+
+func testDeviceAuthorization(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		deviceAuth := startTestDeviceAuthorization(t, srv)
+
+		pollForm := url.Values{
+			"grant_type":     {deviceGrantType},
+			qKeyDeviceCode:   {deviceAuth.DeviceCode},
+			qKeyClientID:     {testDeviceClientID},
+			qKeyClientSecret: {testDeviceClientSecret},
+		}
+
+		checkOAuthError(t, sendTestForm(t, srv.tokenGrant, pollForm), "authorization_pending")
+		checkOAuthError(t, sendTestForm(t, srv.tokenGrant, pollForm), "slow_down")
+
+		// The user enters the code in a different format to the one displayed.
+		approval := httptest.NewRecorder()
+		approvalRequest := newTestFormRequest(t, url.Values{"state": {strings.ToLower(deviceAuth.UserCode)}})
+
+		srv.deviceAccept(approval, approvalRequest, "https://billjones.example.net/")
+
+		if redirect := approval.Header().Get("Hx-Redirect"); redirect != pathDevice+"?status=approved" {
+			t.Fatalf(
+				"FAILED test %s: Unexpected redirect after approving the device.\ngot: %q (%s)",
+				t.Name(),
+				redirect,
+				approval.Body.String(),
+			)
+		}
+
+		response := sendTestForm(t, srv.tokenGrant, pollForm)
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			Me          string `json:"me"`
+		}
+
+		if err := json.NewDecoder(response.Body).Decode(&token); err != nil || response.Code != http.StatusOK {
+			t.Fatalf(
+				"FAILED test %s: Unable to get the access token after the device was approved.\nstatus: %d\nerror: %v",
+				t.Name(),
+				response.Code,
+				err,
+			)
+		}
+
+		if token.AccessToken == "" || token.Me != "https://billjones.example.net/" {
+			t.Errorf(
+				"FAILED test %s: Unexpected token response received.\ngot: %+v",
+				t.Name(),
+				token,
+			)
+		} else {
+			t.Logf("Expected token response received for %s.", token.Me)
+		}
+
+		// The device code can only be exchanged once.
+		checkOAuthError(t, sendTestForm(t, srv.tokenGrant, pollForm), "invalid_grant")
+	}
+}
+
+func testDeviceAuthorizationConcurrency(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		deviceAuth := startTestDeviceAuthorization(t, srv)
+
+		pollForm := url.Values{
+			"grant_type":     {deviceGrantType},
+			qKeyDeviceCode:   {deviceAuth.DeviceCode},
+			qKeyClientID:     {testDeviceClientID},
+			qKeyClientSecret: {testDeviceClientSecret},
+		}
+
+		var (
+			wg     sync.WaitGroup
+			issued atomic.Int32
+		)
+
+		poll := func() {
+			for range 10 {
+				wg.Go(func() {
+					if sendTestForm(t, srv.tokenGrant, pollForm).Code == http.StatusOK {
+						issued.Add(1)
+					}
+				})
+			}
+		}
+
+		// The polls that run while the user approves the device don't overwrite the approval.
+		poll()
+
+		approval := httptest.NewRecorder()
+		approvalRequest := newTestFormRequest(t, url.Values{"state": {deviceAuth.UserCode}})
+
+		srv.deviceAccept(approval, approvalRequest, "https://billjones.example.net/")
+
+		if redirect := approval.Header().Get("Hx-Redirect"); redirect != pathDevice+"?status=approved" {
+			t.Fatalf(
+				"FAILED test %s: Unexpected redirect after approving the device.\ngot: %q (%s)",
+				t.Name(),
+				redirect,
+				approval.Body.String(),
+			)
+		}
+
+		poll()
+		wg.Wait()
+
+		// Only one of the concurrent requests receives the access token.
+		if issued.Load() != 1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of access tokens issued for the approved device code.\nwant: 1\ngot: %d",
+				t.Name(),
+				issued.Load(),
+			)
+		} else {
+			t.Log("Only one access token was issued for the approved device code.")
+		}
+	}
+}
+
+// startTestDeviceAuthorization registers the test device client and starts
+// a new device authorization request.
+func startTestDeviceAuthorization(t *testing.T, srv *Server) deviceAuthResponse {
+	t.Helper()
+
+	secretHash, err := auth.HashPassword(testDeviceClientSecret)
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to hash the client secret: %v", t.Name(), err)
+	}
+
+	if err := srv.store.SaveRegisteredClient(database.RegisteredClient{
+		ClientID:   testDeviceClientID,
+		AuthMethod: auth.ClientAuthMethodSecretPost,
+		SecretHash: secretHash,
+	}); err != nil {
+		t.Fatalf("FAILED test %s: Unable to register the client: %v", t.Name(), err)
+	}
+
+	response := sendTestForm(t, srv.authorizeDevice, url.Values{
+		qKeyClientID:     {testDeviceClientID},
+		qKeyClientSecret: {testDeviceClientSecret},
+		qKeyScope:        {"create"},
+	})
+
+	if response.Code != http.StatusOK {
+		t.Fatalf(
+			"FAILED test %s: Unexpected status code received from the device authorization endpoint.\nwant: %d\ngot: %d (%s)",
+			t.Name(),
+			http.StatusOK,
+			response.Code,
+			response.Body.String(),
+		)
+	}
+
+	var deviceAuth deviceAuthResponse
+
+	if err := json.NewDecoder(response.Body).Decode(&deviceAuth); err != nil {
+		t.Fatalf("FAILED test %s: Unable to decode the device authorization response: %v", t.Name(), err)
+	}
+
+	return deviceAuth
+}
+
+func newTestFormRequest(t *testing.T, form url.Values) *http.Request {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if err := request.ParseForm(); err != nil {
+		t.Fatalf("FAILED test %s: Unable to parse the form: %v", t.Name(), err)
+	}
+
+	return request
+}
+
+func sendTestForm(t *testing.T, handler http.HandlerFunc, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, newTestFormRequest(t, form))
+
+	return recorder
+}
+
+func checkOAuthError(t *testing.T, response *httptest.ResponseRecorder, want string) {
+	t.Helper()
+
+	var got oauthErrorResponse
+
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("FAILED test %s: Unable to decode the error response: %v", t.Name(), err)
+	}
+
+	if response.Code != http.StatusBadRequest || got.Error != want {
+		t.Errorf(
+			"FAILED test %s: Unexpected error response received.\nwant: %d %q\ngot: %d %q",
+			t.Name(),
+			http.StatusBadRequest,
+			want,
+			response.Code,
+			got.Error,
+		)
+	} else {
+		t.Logf("Expected error response received.\ngot: %q", got.Error)
+	}
+}
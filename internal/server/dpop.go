@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+)
+
+const (
+	headerDPoP      string = "DPoP"
+	dpopProofKeyFmt string = "dpop_proof:%s:%s"
+
+	tokenTypeBearer string = "Bearer"
+	tokenTypeDPoP   string = "DPoP"
+)
+
+// verifyDPoPProof verifies the DPoP proof sent with the request to the given endpoint. A nil proof
+// is returned if the client did not send a proof. The proof is not recorded as used until
+// recordDPoPProof is called so that the proofs sent with invalid requests don't fill the replay cache.
+func (s *Server) verifyDPoPProof(request *http.Request, endpoint string) (*auth.DPoPProof, error) {
+	proofs := request.Header.Values(headerDPoP)
+
+	switch len(proofs) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, ErrMultipleDPoPProofs
+	}
+
+	proof, err := auth.VerifyDPoPProof(proofs[0], request.Method, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying the DPoP proof: %w", err)
+	}
+
+	return &proof, nil
+}
+
+// recordDPoPProof records the ID of the used proof in the replay cache for as long as the proof
+// is within the acceptable time window so that the proof cannot be replayed. It is called once
+// the client and the grant are validated. Nothing is recorded if the client did not send a proof.
+func (s *Server) recordDPoPProof(proof *auth.DPoPProof) error {
+	if proof == nil {
+		return nil
+	}
+
+	key := fmt.Sprintf(dpopProofKeyFmt, proof.KeyThumbprint, proof.ID)
+
+	added, err := s.replayCache.AddIfAbsent(key, nil, proof.IssuedAt.Add(auth.DPoPProofWindow))
+	if err != nil {
+		return fmt.Errorf("error saving the ID of the DPoP proof: %w", err)
+	}
+
+	if !added {
+		return ErrReplayedDPoPProof
+	}
+
+	return nil
+}
+
+// dpopKeyThumbprint returns the thumbprint of the proof's public key or an empty
+// string if the client did not send a proof.
+func dpopKeyThumbprint(proof *auth.DPoPProof) string {
+	if proof == nil {
+		return ""
+	}
+
+	return proof.KeyThumbprint
+}
+
+// sendDPoPProofError sends the error response for an error from recording the DPoP proof.
+func sendDPoPProofError(writer http.ResponseWriter, err error) {
+	if errors.Is(err, ErrReplayedDPoPProof) {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_dpop_proof", err)
+
+		return
+	}
+
+	sendOAuthCacheError(writer, err)
+}
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testDPoPBoundTokens(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to generate the key pair: %v", t.Name(), err)
+		}
+
+		point, err := privateKey.PublicKey.Bytes()
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to encode the public key: %v", t.Name(), err)
+		}
+
+		x := base64.RawURLEncoding.EncodeToString(point[1:33])
+		y := base64.RawURLEncoding.EncodeToString(point[33:])
+
+		thumbprint := sha256.Sum256([]byte(`{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`))
+		wantThumbprint := base64.RawURLEncoding.EncodeToString(thumbprint[:])
+
+		signProof := func(proofID string) string {
+			proof := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+				"jti": proofID,
+				"htm": http.MethodPost,
+				"htu": srv.tokenEndpoint,
+				"iat": time.Now().Unix(),
+			})
+			proof.Header["typ"] = "dpop+jwt"
+			proof.Header["jwk"] = map[string]any{"kty": "EC", "crv": "P-256", "x": x, "y": y}
+
+			signedProof, err := proof.SignedString(privateKey)
+			if err != nil {
+				t.Fatalf("FAILED test %s: Unable to sign the DPoP proof: %v", t.Name(), err)
+			}
+
+			return signedProof
+		}
+
+		signedProof := signProof("dpop-proof-1")
+
+		codeVerifier := "Dpop9CodeVerifierThatIsLongEnoughForTheTestExchange"
+		challenge := sha256.Sum256([]byte(codeVerifier))
+
+		authReq := clientAuthRequest{
+			ClientID:            "https://app.example.net/",
+			CodeChallenge:       base64.RawURLEncoding.EncodeToString(challenge[:]),
+			CodeChallengeMethod: "S256",
+			RedirectURI:         "https://app.example.net/callback",
+			Scope:               []string{"create"},
+		}
+
+		exchangeCode := func() *httptest.ResponseRecorder {
+			code, err := srv.issueAuthorizationCode(authReq, "https://billjones.example.net/")
+			if err != nil {
+				t.Fatalf("FAILED test %s: Unable to issue the authorization code: %v", t.Name(), err)
+			}
+
+			request := newTestFormRequest(t, url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {code},
+				"client_id":     {authReq.ClientID},
+				"redirect_uri":  {authReq.RedirectURI},
+				"code_verifier": {codeVerifier},
+			})
+			request.Header.Set(headerDPoP, signedProof)
+
+			recorder := httptest.NewRecorder()
+			srv.tokenGrant(recorder, request)
+
+			return recorder
+		}
+
+		response := exchangeCode()
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			TokenType   string `json:"token_type"`
+		}
+
+		if err := json.NewDecoder(response.Body).Decode(&token); err != nil || response.Code != http.StatusOK {
+			t.Fatalf(
+				"FAILED test %s: Unable to exchange the authorization code with a DPoP proof.\nstatus: %d\nerror: %v",
+				t.Name(),
+				response.Code,
+				err,
+			)
+		}
+
+		if token.TokenType != tokenTypeDPoP {
+			t.Errorf(
+				"FAILED test %s: Unexpected token type received.\nwant: %s\ngot: %s",
+				t.Name(),
+				tokenTypeDPoP,
+				token.TokenType,
+			)
+		} else {
+			t.Logf("Expected token type received.\ngot: %s", token.TokenType)
+		}
+
+		// The same DPoP proof cannot be used again.
+		checkOAuthError(t, exchangeCode(), "invalid_dpop_proof")
+
+		// Only one of the concurrent requests with the same DPoP proof is accepted.
+		concurrentProof := signProof("dpop-proof-2")
+
+		var (
+			wg       sync.WaitGroup
+			accepted atomic.Int32
+		)
+
+		for range 10 {
+			wg.Go(func() {
+				request := httptest.NewRequest(http.MethodPost, pathToken, nil)
+				request.Header.Set(headerDPoP, concurrentProof)
+
+				proof, err := srv.verifyDPoPProof(request, srv.tokenEndpoint)
+				if err != nil {
+					return
+				}
+
+				if err := srv.recordDPoPProof(proof); err == nil {
+					accepted.Add(1)
+				}
+			})
+		}
+
+		wg.Wait()
+
+		if accepted.Load() != 1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of concurrent requests accepted the same DPoP proof.\nwant: 1\ngot: %d",
+				t.Name(),
+				accepted.Load(),
+			)
+		} else {
+			t.Log("Only one of the concurrent requests accepted the DPoP proof.")
+		}
+
+		// The proofs sent with invalid grants are not recorded.
+		unusedProof := signProof("dpop-proof-3")
+
+		request := newTestFormRequest(t, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {"UnknownAuthorizationCode"},
+			"client_id":     {authReq.ClientID},
+			"redirect_uri":  {authReq.RedirectURI},
+			"code_verifier": {codeVerifier},
+		})
+		request.Header.Set(headerDPoP, unusedProof)
+
+		recorder := httptest.NewRecorder()
+		srv.tokenGrant(recorder, request)
+
+		proof, err := srv.verifyDPoPProof(request, srv.tokenEndpoint)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to verify the DPoP proof: %v", t.Name(), err)
+		}
+
+		if _, exists := srv.replayCache.Get(fmt.Sprintf(dpopProofKeyFmt, proof.KeyThumbprint, proof.ID)); recorder.Code == http.StatusOK || exists {
+			t.Errorf(
+				"FAILED test %s: The DPoP proof sent with an invalid authorization code was recorded.\nstatus: %d\nrecorded: %t",
+				t.Name(),
+				recorder.Code,
+				exists,
+			)
+		} else {
+			t.Log("The DPoP proof sent with an invalid authorization code was not recorded.")
+		}
+
+		// Only registered confidential clients can introspect the access token.
+		resourceServerID := "https://resource.example.org/"
+		secret := "bXlSZXNvdXJjZVNlcnZlclNlY3JldDEyMzQ1"
+
+		secretHash, err := auth.HashPassword(secret)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to hash the client secret: %v", t.Name(), err)
+		}
+
+		if err := srv.store.SaveRegisteredClient(database.RegisteredClient{
+			ClientID:   resourceServerID,
+			AuthMethod: auth.ClientAuthMethodSecretPost,
+			SecretHash: secretHash,
+		}); err != nil {
+			t.Fatalf("FAILED test %s: Unable to register the resource server: %v", t.Name(), err)
+		}
+
+		response = sendTestForm(t, srv.introspectToken, url.Values{
+			qKeyClientID: {authReq.ClientID},
+			qKeyToken:    {token.AccessToken},
+		})
+
+		if response.Code != http.StatusUnauthorized {
+			t.Errorf(
+				"FAILED test %s: Unexpected status code received when a public client introspected the token.\nwant: %d\ngot: %d",
+				t.Name(),
+				http.StatusUnauthorized,
+				response.Code,
+			)
+		} else {
+			t.Log("The public client was not permitted to introspect the token.")
+		}
+
+		response = sendTestForm(t, srv.introspectToken, url.Values{
+			qKeyClientID:     {resourceServerID},
+			qKeyClientSecret: {secret},
+			qKeyToken:        {token.AccessToken},
+		})
+
+		var introspection introspectionResponse
+
+		if err := json.NewDecoder(response.Body).Decode(&introspection); err != nil || response.Code != http.StatusOK {
+			t.Fatalf(
+				"FAILED test %s: Unable to introspect the access token.\nstatus: %d\nerror: %v",
+				t.Name(),
+				response.Code,
+				err,
+			)
+		}
+
+		if !introspection.Active ||
+			introspection.TokenType != tokenTypeDPoP ||
+			introspection.Confirmation == nil ||
+			introspection.Confirmation.JKT != wantThumbprint {
+			t.Errorf(
+				"FAILED test %s: Unexpected introspection response received.\nwant thumbprint: %s\ngot: %+v",
+				t.Name(),
+				wantThumbprint,
+				introspection,
+			)
+		} else {
+			t.Logf("Expected introspection response received.\ngot: %+v", introspection)
+		}
+	}
+}
@@ -4,16 +4,46 @@
 
 package server
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
 	ErrDatabaseAlreadyInitialized = errors.New("the database is already initialized")
 	ErrDatabaseNotInitialized     = errors.New("the database does not appear to be initialized")
 	ErrMissingAuthorizationCode   = errors.New("invalid authorization code: the code is not present in the cache")
 	ErrExpiredAuthorizationCode   = errors.New("invalid authorization code: the code has expired")
+	ErrReusedAuthorizationCode    = errors.New("invalid authorization code: the code has already been used")
 	ErrMissingGrantType           = errors.New("the required parameter 'grant_type' is missing")
 	ErrInvalidProfileAccessToken  = errors.New("invalid profile access token")
 	ErrInvalidFileserverPath      = errors.New("the path must not end with a '/'")
+	ErrPushedAuthRequestRequired  = errors.New("the authorization request must be pushed to the pushed authorization request endpoint")
+	ErrInvalidRequestURI          = errors.New("the request URI is invalid, expired or has already been used")
+	ErrUnvalidatedAuthRequest     = errors.New("the authorization request was not validated for the signed in profile")
+	ErrRequestURIInPushedRequest  = errors.New("the pushed authorization request must not contain the 'request_uri' parameter")
+	ErrMismatchedRequestURIClient = errors.New("the client ID does not match the client ID of the pushed authorization request")
+	ErrInvalidDeviceCode          = errors.New("the device code is invalid")
+	ErrExpiredDeviceCode          = errors.New("the device code has expired")
+	ErrDeviceAccessDenied         = errors.New("the user denied the device authorization request")
+	ErrInvalidUserCode            = errors.New("the user code is invalid or has expired")
+	ErrInvalidFormPostRedirectURI = errors.New("the form_post response mode requires an http or https redirect URI")
+	ErrMultipleDPoPProofs         = errors.New("the request contains more than one DPoP proof")
+	ErrReplayedDPoPProof          = errors.New("the DPoP proof has already been used")
+	ErrIntrospectionNotPermitted  = errors.New("only registered confidential clients can use the introspection endpoint")
+	ErrForwardAuthNotSignedIn     = errors.New("the user is not signed in")
+	ErrInvalidReturnURL           = errors.New("the return URL is not on one of the allowed hosts")
+	ErrMissingBearerToken         = errors.New("the bearer token is missing from the Authorization header")
+	ErrInvalidAccessToken         = errors.New("the access token is invalid or has expired")
+	ErrDPoPBoundAccessToken       = errors.New("the access token is bound to the client's key and cannot be verified as a bearer token")
+	ErrInvalidTicket              = errors.New("the ticket is invalid, expired or has already been used")
+	ErrIncompleteTicket           = errors.New("the ticket, subject and resource are required")
+	ErrInvalidTicketIssuer        = errors.New("the issuer of the ticket is invalid")
+	ErrNoTicketScopes             = errors.New("none of the ticket's scopes are supported")
+	ErrTooManyTickets             = errors.New("too many tickets were received for the subject, please try again later")
+	ErrMissingTokenEndpoint       = errors.New("the token endpoint of the ticket's issuer could not be found")
+	ErrMissingTicketEndpoint      = errors.New("the website does not advertise a ticket endpoint")
+	ErrAdminSocketPathInUse       = errors.New("the admin socket path is used by a file that is not a socket")
 )
 
 type MismatchedProfileIDError struct {
@@ -94,3 +124,53 @@ type formValidationError struct {
 func (e formValidationError) Error() string {
 	return "form validation failed: " + e.reason
 }
+
+type ClientAuthenticationError struct {
+	reason string
+}
+
+func (e ClientAuthenticationError) Error() string {
+	return "client authentication failed: " + e.reason
+}
+
+type UnsupportedResponseModeError struct {
+	responseMode string
+}
+
+func (e UnsupportedResponseModeError) Error() string {
+	return "unsupported response mode: " + e.responseMode
+}
+
+type UnknownTicketSubjectError struct {
+	subject string
+}
+
+func (e UnknownTicketSubjectError) Error() string {
+	return "the subject of the ticket (" + e.subject + ") is not a profile on this server"
+}
+
+type ReceivedTokenIssuerMismatchError struct {
+	resource string
+	issuer   string
+}
+
+func (e ReceivedTokenIssuerMismatchError) Error() string {
+	return "an access token for " + e.resource + " was already received from a different issuer (" + e.issuer + ")"
+}
+
+type UnexpectedStatusError struct {
+	url    string
+	status string
+}
+
+func (e UnexpectedStatusError) Error() string {
+	return "received an unexpected response status from " + e.url + ": " + e.status
+}
+
+type ProfileLinkError struct {
+	problems []string
+}
+
+func (e ProfileLinkError) Error() string {
+	return "the website does not delegate to this server: " + strings.Join(e.problems, " ")
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/policy"
+)
+
+const (
+	pathForwardAuth string = "/auth/verify"
+
+	qKeyReturnTo         string = "return_to"
+	loginTypeForwardAuth string = "forward_auth"
+
+	headerAuthUser  string = "X-Auth-User"
+	headerAuthName  string = "X-Auth-Name"
+	headerAuthEmail string = "X-Auth-Email"
+	headerAuthURL   string = "X-Auth-URL"
+	headerAuthPhoto string = "X-Auth-Photo"
+)
+
+// verifyForwardAuth handles the authentication subrequests from reverse proxies such as
+// nginx (auth_request), Traefik (ForwardAuth) and Caddy (forward_auth). The session cookie
+// has already been checked by the profileAuthorization middleware so the signed in user's
+// profile ID and information are returned in the response headers.
+func (s *Server) verifyForwardAuth(writer http.ResponseWriter, _ *http.Request, profileID string) {
+	info, err := s.store.GetProfileInformation(profileID)
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("unable to get the profile information for %q: %w", profileID, err),
+		)
+
+		return
+	}
+
+	writer.Header().Set(headerAuthUser, profileID)
+
+	for header, value := range map[string]string{
+		headerAuthName:  info.Name,
+		headerAuthEmail: info.Email,
+		headerAuthURL:   info.URL,
+		headerAuthPhoto: info.PhotoURL,
+	} {
+		if value != "" {
+			writer.Header().Set(header, value)
+		}
+	}
+
+	writer.WriteHeader(http.StatusOK)
+}
+
+// forwardAuthRedirectToLogin responds to the reverse proxy when the user is not signed in.
+// The Location header points to the login page which sends the user back to the original
+// URL after signing in, provided that the original URL is on one of the allowed hosts.
+func (s *Server) forwardAuthRedirectToLogin(writer http.ResponseWriter, request *http.Request) {
+	loginQuery := url.Values{}
+	loginQuery.Set(qKeyLoginType, loginTypeForwardAuth)
+
+	if returnTo := forwardAuthOriginalURL(request); s.validReturnURL(returnTo) {
+		loginQuery.Set(qKeyReturnTo, returnTo)
+	}
+
+	writer.Header().Set("Location", fmt.Sprintf("https://%s/profile/login?%s", s.domainName, loginQuery.Encode()))
+
+	sendClientError(
+		writer,
+		http.StatusUnauthorized,
+		ErrForwardAuthNotSignedIn,
+	)
+}
+
+// forwardAuthOriginalURL returns the URL of the request that the reverse proxy is authenticating.
+// nginx is expected to send the URL in the X-Original-URL header while Traefik and Caddy send
+// the components of the URL in the X-Forwarded-* headers.
+func forwardAuthOriginalURL(request *http.Request) string {
+	if originalURL := request.Header.Get("X-Original-URL"); originalURL != "" {
+		return originalURL
+	}
+
+	host := request.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		return ""
+	}
+
+	scheme := request.Header.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + host + request.Header.Get("X-Forwarded-Uri")
+}
+
+// validReturnURL returns true if the user can be sent back to the URL after signing in.
+// Only the hosts that are allowed in the forward auth configuration are accepted so that
+// the login page cannot be used as an open redirect.
+func (s *Server) validReturnURL(returnTo string) bool {
+	if returnTo == "" {
+		return false
+	}
+
+	parsedURL, err := url.Parse(returnTo)
+	if err != nil {
+		return false
+	}
+
+	if (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") || parsedURL.User != nil {
+		return false
+	}
+
+	return policy.MatchesAnyHost(s.forwardAuthAllowedHosts, parsedURL.Hostname())
+}
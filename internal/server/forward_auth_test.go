@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testForwardAuth(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		allowedHosts := srv.forwardAuthAllowedHosts
+		srv.forwardAuthAllowedHosts = []string{"*.apps.example.net"}
+
+		defer func() {
+			srv.forwardAuthAllowedHosts = allowedHosts
+		}()
+
+		handler := srv.profileAuthorization(srv.verifyForwardAuth, srv.forwardAuthRedirectToLogin)
+
+		newProxyRequest := func(host string) *http.Request {
+			request := httptest.NewRequest(http.MethodGet, pathForwardAuth, nil)
+			request.Header.Set("X-Forwarded-Proto", "https")
+			request.Header.Set("X-Forwarded-Host", host)
+			request.Header.Set("X-Forwarded-Uri", "/dashboard?tab=1")
+
+			return request
+		}
+
+		// Users who are not signed in are sent to the login page which
+		// returns them to the original URL on the allowed hosts only.
+		testCases := []struct {
+			host         string
+			wantReturnTo string
+		}{
+			{
+				host:         "wiki.apps.example.net",
+				wantReturnTo: "https://wiki.apps.example.net/dashboard?tab=1",
+			},
+			{
+				host:         "evil.example.org",
+				wantReturnTo: "",
+			},
+		}
+
+		for _, tc := range testCases {
+			writer := httptest.NewRecorder()
+			handler(writer, newProxyRequest(tc.host))
+
+			location, err := url.Parse(writer.Header().Get("Location"))
+			if err != nil {
+				t.Fatalf("FAILED test %s: Unable to parse the login URL: %v", t.Name(), err)
+			}
+
+			if writer.Code != http.StatusUnauthorized ||
+				location.Path != "/profile/login" ||
+				location.Query().Get(qKeyLoginType) != loginTypeForwardAuth ||
+				location.Query().Get(qKeyReturnTo) != tc.wantReturnTo {
+				t.Errorf(
+					"FAILED test %s: Unexpected response for the unauthenticated request to %s.\nwant: %d %q\ngot: %d %q",
+					t.Name(),
+					tc.host,
+					http.StatusUnauthorized,
+					tc.wantReturnTo,
+					writer.Code,
+					location.String(),
+				)
+			} else {
+				t.Logf("Expected login URL received for the request to %s.\ngot: %s", tc.host, location.String())
+			}
+		}
+
+		// Signed in users are identified in the response headers.
+		profileID := "https://forwardauth.example.net/"
+
+		if err := srv.store.Setup(profileID, database.Profile{
+			Information: database.ProfileInformation{
+				Name:  "Forward Auth",
+				Email: "me@forwardauth.example.net",
+			},
+		}); err != nil {
+			t.Fatalf("FAILED test %s: Unable to create the profile: %v", t.Name(), err)
+		}
+
+		token, err := auth.CreateJWT(profileID, srv.jwtSecret, 0, 1*time.Minute)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to create the JWT: %v", t.Name(), err)
+		}
+
+		request := newProxyRequest("wiki.apps.example.net")
+		request.AddCookie(&http.Cookie{Name: srv.jwtCookieName, Value: token})
+
+		writer := httptest.NewRecorder()
+		handler(writer, request)
+
+		if writer.Code != http.StatusOK ||
+			writer.Header().Get(headerAuthUser) != profileID ||
+			writer.Header().Get(headerAuthName) != "Forward Auth" ||
+			writer.Header().Get(headerAuthEmail) != "me@forwardauth.example.net" {
+			t.Errorf(
+				"FAILED test %s: Unexpected response for the authenticated request.\ngot: %d %v",
+				t.Name(),
+				writer.Code,
+				writer.Header(),
+			)
+		} else {
+			t.Logf("Expected response for the authenticated request.\ngot: %s", writer.Header().Get(headerAuthUser))
+		}
+	}
+}
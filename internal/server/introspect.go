@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+const qKeyToken string = "token"
+
+type introspectionConfirmation struct {
+	JKT string `json:"jkt"`
+}
+
+// introspectionResponse is the response from the introspection endpoint (RFC 7662, section 2.2).
+// The confirmation contains the thumbprint of the client's key for DPoP bound access tokens
+// (RFC 9449, section 6.2).
+type introspectionResponse struct {
+	Active       bool                       `json:"active"`
+	ClientID     string                     `json:"client_id,omitempty"`
+	Me           string                     `json:"me,omitempty"`
+	Scope        string                     `json:"scope,omitempty"`
+	TokenType    string                     `json:"token_type,omitempty"`
+	IssuedAt     int64                      `json:"iat,omitempty"`
+	ExpiresAt    int64                      `json:"exp,omitempty"`
+	Confirmation *introspectionConfirmation `json:"cnf,omitempty"`
+}
+
+// introspectToken handles the requests to the introspection endpoint. Only registered
+// confidential clients, such as resource servers, can introspect access tokens.
+// Inactive tokens are reported without any further information about the token.
+func (s *Server) introspectToken(writer http.ResponseWriter, request *http.Request) {
+	clientID, err := utilities.ValidateAndCanonicalizeClientID(requestClientID(request))
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusUnauthorized,
+			"invalid_client",
+			fmt.Errorf("error canonicalizing the client ID: %w", err),
+		)
+
+		return
+	}
+
+	client, registered, err := s.store.GetRegisteredClient(clientID)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error getting the registered client: %w", err),
+		)
+
+		return
+	}
+
+	if !registered || client.AuthMethod == auth.ClientAuthMethodNone {
+		sendOAuthError(writer, http.StatusUnauthorized, "invalid_client", ErrIntrospectionNotPermitted)
+
+		return
+	}
+
+	if err := s.authenticateClient(request, clientID); err != nil {
+		if errors.As(err, &ClientAuthenticationError{}) {
+			sendOAuthError(writer, http.StatusUnauthorized, "invalid_client", err)
+		} else {
+			sendOAuthError(
+				writer,
+				http.StatusInternalServerError,
+				"server_error",
+				fmt.Errorf("error authenticating the client: %w", err),
+			)
+		}
+
+		return
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+
+	token := request.PostFormValue(qKeyToken)
+	if token == "" {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_request", errors.New("the token is missing"))
+
+		return
+	}
+
+	record, found, err := s.store.GetAccessToken(auth.HashBearerToken(token))
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error getting the access token: %w", err),
+		)
+
+		return
+	}
+
+	if !found || time.Now().After(record.ExpiresAt) {
+		sendJSONResponse(writer, http.StatusOK, introspectionResponse{Active: false})
+
+		return
+	}
+
+	response := introspectionResponse{
+		Active:    true,
+		ClientID:  record.ClientID,
+		Me:        record.Me,
+		Scope:     strings.Join(record.Scopes, " "),
+		TokenType: tokenTypeBearer,
+		IssuedAt:  record.IssuedAt.Unix(),
+		ExpiresAt: record.ExpiresAt.Unix(),
+	}
+
+	if record.JKT != "" {
+		response.TokenType = tokenTypeDPoP
+		response.Confirmation = &introspectionConfirmation{JKT: record.JKT}
+	}
+
+	sendJSONResponse(writer, http.StatusOK, response)
+}
@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
-	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
 )
@@ -21,6 +20,7 @@ type loginPage struct {
 	ProfileID string
 	LoginType string
 	State     string
+	ReturnTo  string
 	Title     string
 }
 
@@ -29,6 +29,7 @@ type loginForm struct {
 	password  string
 	loginType string
 	state     string
+	returnTo  string
 }
 
 func (f *loginForm) validate() error {
@@ -56,6 +57,27 @@ func (s *Server) getLoginPage(writer http.ResponseWriter, request *http.Request)
 	}
 
 	loginType := query.Get(qKeyLoginType)
+
+	// The user is sent back to the application protected by the reverse proxy after signing in.
+	if returnTo := query.Get(qKeyReturnTo); loginType == loginTypeForwardAuth && s.validReturnURL(returnTo) {
+		s.sendHTMLResponseWithTemplate(
+			writer,
+			"login",
+			http.StatusOK,
+			loginPage{
+				ProfileID: "",
+				LoginType: loginTypeForwardAuth,
+				State:     "",
+				ReturnTo:  returnTo,
+				Title:     loginPageTitle(),
+			},
+			nil,
+			nil,
+		)
+
+		return
+	}
+
 	if loginType != loginTypeIndieauth {
 		s.sendHTMLResponseWithTemplate(
 			writer,
@@ -98,6 +120,7 @@ func (s *Server) authenticate(writer http.ResponseWriter, request *http.Request)
 		password:  request.PostFormValue("password"),
 		loginType: request.PostFormValue("loginType"),
 		state:     request.PostFormValue("state"),
+		returnTo:  request.PostFormValue("returnTo"),
 	}
 
 	err := form.validate()
@@ -126,7 +149,7 @@ func (s *Server) authenticate(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	exists, err := database.ProfileExists(s.boltdb, form.profileID)
+	exists, err := s.store.ProfileExists(form.profileID)
 	if err != nil {
 		s.sendHTMLResponse(
 			writer,
@@ -151,7 +174,7 @@ func (s *Server) authenticate(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	profile, err := database.GetProfile(s.boltdb, profileID)
+	profile, err := s.store.GetProfile(profileID)
 	if err != nil {
 		s.sendHTMLResponse(
 			writer,
@@ -198,7 +221,7 @@ func (s *Server) authenticate(writer http.ResponseWriter, request *http.Request)
 		Path:     "/",
 		MaxAge:   int(expiry.Seconds()),
 		Quoted:   false,
-		Domain:   s.domainName,
+		Domain:   s.cookieDomain,
 		Secure:   true,
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
@@ -206,9 +229,22 @@ func (s *Server) authenticate(writer http.ResponseWriter, request *http.Request)
 
 	http.SetCookie(writer, &cookie)
 
+	if form.loginType == loginTypeForwardAuth && !s.validReturnURL(form.returnTo) {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to login"),
+			http.StatusBadRequest,
+			fmt.Errorf("%w: %s", ErrInvalidReturnURL, form.returnTo),
+			nil,
+		)
+
+		return
+	}
+
 	redirectMap := map[string]string{
-		loginTypeProfile:   "/profile/overview",
-		loginTypeIndieauth: fmt.Sprintf("%s?state=%s", pathAuth, form.state),
+		loginTypeProfile:     "/profile/overview",
+		loginTypeIndieauth:   fmt.Sprintf("%s?state=%s", pathAuth, form.state),
+		loginTypeForwardAuth: form.returnTo,
 	}
 
 	redirectURL, ok := redirectMap[form.loginType]
@@ -228,7 +264,7 @@ func (s *Server) authenticate(writer http.ResponseWriter, request *http.Request)
 }
 
 func (s *Server) logout(writer http.ResponseWriter, _ *http.Request, profileID string) {
-	if err := database.IncrementTokenVersion(s.boltdb, profileID); err != nil {
+	if err := s.store.IncrementTokenVersion(profileID); err != nil {
 		sendServerError(
 			writer,
 			fmt.Errorf("error incrementing the profile's token version: %w", err),
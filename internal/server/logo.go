@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+)
+
+// getClientLogo serves the client's logo from the cache. The logo is served with
+// headers that prevent the browser from treating the image as any other type of
+// content.
+func (s *Server) getClientLogo(writer http.ResponseWriter, request *http.Request, _ string) {
+	logo, err := s.clientLogos.Get(request.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, discovery.ErrClientLogoNotCached) {
+			sendClientError(writer, http.StatusNotFound, err)
+
+			return
+		}
+
+		sendServerError(
+			writer,
+			fmt.Errorf("error getting the client logo: %w", err),
+		)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", logo.ContentType)
+	writer.Header().Set("Content-Length", strconv.Itoa(len(logo.Data)))
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	writer.Header().Set("Content-Disposition", "inline")
+	writer.WriteHeader(http.StatusOK)
+
+	_, _ = writer.Write(logo.Data)
+}
@@ -6,6 +6,8 @@ package server
 
 import (
 	"net/http"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
 )
 
 type metadata struct {
@@ -16,8 +18,18 @@ type metadata struct {
 	CodeChallengeMethodsSupported          []string `json:"code_challenge_methods_supported"`
 	GrantTypesSupported                    []string `json:"grant_types_supported"`
 	ResponseTypesSupported                 []string `json:"response_types_supported"`
+	ResponseModesSupported                 []string `json:"response_modes_supported"`
 	ScopesSupported                        []string `json:"scopes_supported"`
 	AuthorizationResponseISSParamSupported bool     `json:"authorization_response_iss_parameter_supported"`
+	PushedAuthorizationRequestEndpoint     string   `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorizationRequests     bool     `json:"require_pushed_authorization_requests"`
+	TokenEndpointAuthMethodsSupported      []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgsSupported  []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	DeviceAuthorizationEndpoint            string   `json:"device_authorization_endpoint"`
+	IntrospectionEndpoint                  string   `json:"introspection_endpoint"`
+	IntrospectionEndpointAuthMethods       []string `json:"introspection_endpoint_auth_methods_supported"`
+	DPoPSigningAlgsSupported               []string `json:"dpop_signing_alg_values_supported"`
+	TicketEndpoint                         string   `json:"ticket_endpoint"`
 }
 
 func (s *Server) getMetadata(writer http.ResponseWriter, _ *http.Request) {
@@ -27,10 +39,20 @@ func (s *Server) getMetadata(writer http.ResponseWriter, _ *http.Request) {
 		TokenEndpoint:                          s.tokenEndpoint,
 		ServiceDocumentation:                   "https://indieauth.spec.indieweb.org",
 		CodeChallengeMethodsSupported:          []string{"S256"},
-		GrantTypesSupported:                    []string{"authorization_code"},
+		GrantTypesSupported:                    []string{"authorization_code", deviceGrantType, ticketGrantType},
 		ResponseTypesSupported:                 []string{"code"},
-		ScopesSupported:                        []string{"profile", "email"},
+		ResponseModesSupported:                 supportedResponseModes(),
+		ScopesSupported:                        s.scopes.Supported(),
 		AuthorizationResponseISSParamSupported: true,
+		PushedAuthorizationRequestEndpoint:     s.parEndpoint,
+		RequirePushedAuthorizationRequests:     s.requirePAR,
+		TokenEndpointAuthMethodsSupported:      append([]string{auth.ClientAuthMethodNone}, auth.ClientAuthMethods()...),
+		TokenEndpointAuthSigningAlgsSupported:  auth.ClientAssertionSigningMethods(),
+		DeviceAuthorizationEndpoint:            s.deviceAuthEndpoint,
+		IntrospectionEndpoint:                  s.introspectionEndpoint,
+		IntrospectionEndpointAuthMethods:       auth.ClientAuthMethods(),
+		DPoPSigningAlgsSupported:               auth.ClientAssertionSigningMethods(),
+		TicketEndpoint:                         s.ticketEndpoint,
 	}
 
 	sendJSONResponse(writer, http.StatusOK, metadata)
@@ -50,15 +50,74 @@ func testGetMetadata(srv *Server) func(t *testing.T) {
 		}
 
 		want := metadata{
-			Issuer:                                 "https://indieauth.test.example/",
-			AuthorizationEndpoint:                  "https://indieauth.test.example/indieauth/authorize",
-			TokenEndpoint:                          "https://indieauth.test.example/indieauth/token",
-			ServiceDocumentation:                   "https://indieauth.spec.indieweb.org",
-			CodeChallengeMethodsSupported:          []string{"S256"},
-			GrantTypesSupported:                    []string{"authorization_code"},
-			ResponseTypesSupported:                 []string{"code"},
-			ScopesSupported:                        []string{"profile", "email"},
+			Issuer:                        "https://indieauth.test.example/",
+			AuthorizationEndpoint:         "https://indieauth.test.example/indieauth/authorize",
+			TokenEndpoint:                 "https://indieauth.test.example/indieauth/token",
+			ServiceDocumentation:          "https://indieauth.spec.indieweb.org",
+			CodeChallengeMethodsSupported: []string{"S256"},
+			GrantTypesSupported: []string{
+				"authorization_code",
+				"urn:ietf:params:oauth:grant-type:device_code",
+				"ticket",
+			},
+			ResponseTypesSupported: []string{"code"},
+			ResponseModesSupported: []string{"query", "fragment", "form_post"},
+			ScopesSupported: []string{
+				"profile",
+				"email",
+				"create",
+				"draft",
+				"update",
+				"delete",
+				"undelete",
+				"media",
+				"read",
+				"follow",
+				"mute",
+				"block",
+				"channels",
+			},
 			AuthorizationResponseISSParamSupported: true,
+			PushedAuthorizationRequestEndpoint:     "https://indieauth.test.example/indieauth/par",
+			RequirePushedAuthorizationRequests:     false,
+			TokenEndpointAuthMethodsSupported: []string{
+				"none",
+				"client_secret_basic",
+				"client_secret_post",
+				"private_key_jwt",
+			},
+			TokenEndpointAuthSigningAlgsSupported: []string{
+				"RS256",
+				"RS384",
+				"RS512",
+				"PS256",
+				"PS384",
+				"PS512",
+				"ES256",
+				"ES384",
+				"ES512",
+				"EdDSA",
+			},
+			DeviceAuthorizationEndpoint: "https://indieauth.test.example/indieauth/device",
+			IntrospectionEndpoint:       "https://indieauth.test.example/indieauth/introspect",
+			IntrospectionEndpointAuthMethods: []string{
+				"client_secret_basic",
+				"client_secret_post",
+				"private_key_jwt",
+			},
+			DPoPSigningAlgsSupported: []string{
+				"RS256",
+				"RS384",
+				"RS512",
+				"PS256",
+				"PS384",
+				"PS512",
+				"ES256",
+				"ES384",
+				"ES512",
+				"EdDSA",
+			},
+			TicketEndpoint: "https://indieauth.test.example/indieauth/ticket",
 		}
 
 		if !reflect.DeepEqual(want, got) {
@@ -5,7 +5,6 @@
 package server
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -112,7 +111,7 @@ func (s *Server) profileAuthorization(next profileHandlerFunc, redirectToLogin h
 			return
 		}
 
-		profileTokenVersion, err := database.GetProfileTokenVersion(s.boltdb, data.ProfileID)
+		profileTokenVersion, err := s.store.GetProfileTokenVersion(data.ProfileID)
 		if err != nil {
 			profileNotExistErr := database.ProfileNotExistError{}
 			if errors.As(err, &profileNotExistErr) {
@@ -162,16 +161,11 @@ func (s *Server) exchangeAuthorization(exchange exchangeHandlerFunc) http.Handle
 		var (
 			grantType    = request.PostFormValue("grant_type")
 			code         = request.PostFormValue("code")
-			clientID     = request.PostFormValue("client_id")
+			clientID     = requestClientID(request)
 			redirectURI  = request.PostFormValue("redirect_uri")
 			codeVerifier = request.PostFormValue("code_verifier")
 		)
 
-		// Regardless of the success or failure of the exchange authorization the code
-		// and associated data is deleted from the cache to ensure that is doesn't get
-		// used again.
-		defer s.cache.Delete(code)
-
 		// The grant type must be "authorization_code"
 		if grantType == "" {
 			sendClientError(
@@ -193,41 +187,28 @@ func (s *Server) exchangeAuthorization(exchange exchangeHandlerFunc) http.Handle
 			return
 		}
 
-		// Using the code to get the associated data from the server's cache
-		cacheEntry, exists := s.cache.Get(code)
-		if !exists {
-			sendClientError(
-				writer,
-				http.StatusUnauthorized,
-				ErrMissingAuthorizationCode,
-			)
-
-			return
-		}
-
-		if cacheEntry.Expired() {
-			sendClientError(
-				writer,
-				http.StatusUnauthorized,
-				ErrExpiredAuthorizationCode,
-			)
-
-			return
-		}
-
-		var initialClientAuthReq clientRequestData
+		// Regardless of the success or failure of the exchange authorization the code
+		// is redeemed as it is retrieved to ensure that is doesn't get used again. The
+		// code is only looked up by its hash so that the code presented by the client
+		// cannot refer to other cache entries.
+		codeHash := auth.HashBearerToken(code)
 
-		if err := utilities.GobDecode(bytes.NewBuffer(cacheEntry.Value()), &initialClientAuthReq); err != nil {
-			sendServerError(
-				writer,
-				fmt.Errorf("unable to decode the data from the cache: %w", err),
-			)
+		initialClientAuthReq, err := s.redeemAuthorizationCode(codeHash, clientID)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrMissingAuthorizationCode),
+				errors.Is(err, ErrExpiredAuthorizationCode),
+				errors.Is(err, ErrReusedAuthorizationCode):
+				sendClientError(writer, http.StatusUnauthorized, err)
+			default:
+				sendServerError(writer, err)
+			}
 
 			return
 		}
 
 		// The client ID must match
-		canonicalizedClientID, err := utilities.ValidateAndCanonicalizeURL(clientID, true)
+		canonicalizedClientID, err := utilities.ValidateAndCanonicalizeClientID(clientID)
 		if err != nil {
 			sendClientError(
 				writer,
@@ -265,6 +246,17 @@ func (s *Server) exchangeAuthorization(exchange exchangeHandlerFunc) http.Handle
 			return
 		}
 
+		// Registered clients must also authenticate themselves.
+		if err := s.authenticateClient(request, canonicalizedClientID); err != nil {
+			if errors.As(err, &ClientAuthenticationError{}) {
+				sendClientError(writer, http.StatusUnauthorized, err)
+			} else {
+				sendServerError(writer, fmt.Errorf("error authenticating the client: %w", err))
+			}
+
+			return
+		}
+
 		// Verify the code verifier
 		if err := auth.VerifyAuthorizationCode(
 			initialClientAuthReq.CodeChallengeMethod,
@@ -281,6 +273,8 @@ func (s *Server) exchangeAuthorization(exchange exchangeHandlerFunc) http.Handle
 		}
 
 		// The client is now authorized to complete the required exchange.
+		initialClientAuthReq.codeHash = codeHash
+
 		exchange(writer, initialClientAuthReq)
 	}
 }
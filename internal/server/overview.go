@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"net/http"
 
-	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
 )
 
@@ -23,7 +22,7 @@ type overviewPage struct {
 }
 
 func (s *Server) getOverviewPage(writer http.ResponseWriter, _ *http.Request, profileID string) {
-	profileInfo, err := database.GetProfileInformation(s.boltdb, profileID)
+	profileInfo, err := s.store.GetProfileInformation(profileID)
 	if err != nil {
 		sendServerError(
 			writer,
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/outbound"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/scopes"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+const (
+	requestURIPrefix          string        = "urn:ietf:params:oauth:request_uri:"
+	pushedAuthRequestKeyFmt   string        = "par:%s"
+	pushedAuthRequestLifetime time.Duration = 60 * time.Second
+)
+
+// pushedAuthRequestParameters are the authorization request parameters that are stored
+// from the pushed authorization request. Any other parameters are discarded.
+var pushedAuthRequestParameters = []string{
+	qKeyClientID,
+	qKeyCodeChallenge,
+	qKeyCodeChallengeMethod,
+	qKeyMe,
+	qKeyRedirectURI,
+	qKeyResponseMode,
+	qKeyResponseType,
+	qKeyScope,
+	qKeyState,
+}
+
+type pushedAuthResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int64  `json:"expires_in"`
+}
+
+// pushAuthorizationRequest handles the pushed authorization requests (RFC 9126). The request
+// is validated in the same way as the authorization endpoint would validate it and is then
+// stored in the cache. The client receives a request URI which it then sends to the
+// authorization endpoint in place of the request parameters.
+func (s *Server) pushAuthorizationRequest(writer http.ResponseWriter, request *http.Request) {
+	if request.PostForm.Has(qKeyRequestURI) {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_request", ErrRequestURIInPushedRequest)
+
+		return
+	}
+
+	if clientID := requestClientID(request); clientID != "" {
+		request.PostForm.Set(qKeyClientID, clientID)
+	}
+
+	authReq, err := newClientAuthRequestFromQuery(request.PostForm)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_request",
+			fmt.Errorf("error creating the client authorization request: %w", err),
+		)
+
+		return
+	}
+
+	clientMetadata, ok := s.checkBackChannelClient(writer, request, authReq.ClientID)
+	if !ok {
+		return
+	}
+
+	if err := discovery.ValidateClientMetadata(
+		clientMetadata,
+		authReq.ClientID,
+		authReq.RedirectURI,
+	); err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_request",
+			fmt.Errorf("error validating the client's authorization request: %w", err),
+		)
+
+		return
+	}
+
+	if _, err := s.scopes.Resolve(authReq.Scope); err != nil {
+		if errors.As(err, &scopes.InvalidScopeError{}) {
+			sendOAuthError(writer, http.StatusBadRequest, "invalid_scope", err)
+
+			return
+		}
+
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error resolving the requested scopes: %w", err),
+		)
+
+		return
+	}
+
+	reference, err := s.savePushedAuthRequest(request.PostForm)
+	if err != nil {
+		sendOAuthCacheError(
+			writer,
+			fmt.Errorf("error saving the pushed authorization request: %w", err),
+		)
+
+		return
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+
+	sendJSONResponse(
+		writer,
+		http.StatusCreated,
+		pushedAuthResponse{
+			RequestURI: requestURIPrefix + reference,
+			ExpiresIn:  int64(pushedAuthRequestLifetime.Seconds()),
+		},
+	)
+}
+
+// savePushedAuthRequest saves the parameters of the pushed authorization request to
+// the cache. The random reference used to retrieve the parameters is returned.
+func (s *Server) savePushedAuthRequest(form url.Values) (string, error) {
+	referenceBytes := make([]byte, 32)
+
+	if _, err := rand.Read(referenceBytes); err != nil {
+		return "", fmt.Errorf("unable to create random bytes: %w", err)
+	}
+
+	reference := hex.EncodeToString(referenceBytes)
+
+	params := url.Values{}
+
+	for _, key := range pushedAuthRequestParameters {
+		if form.Has(key) {
+			params.Set(key, form.Get(key))
+		}
+	}
+
+	if err := s.cache.Add(
+		fmt.Sprintf(pushedAuthRequestKeyFmt, reference),
+		params,
+		time.Now().Add(pushedAuthRequestLifetime),
+	); err != nil {
+		return "", fmt.Errorf("error saving the pushed authorization request: %w", err)
+	}
+
+	return reference, nil
+}
+
+// authorizationRequestParameters returns the parameters of the client's authorization request.
+// If the query contains a request URI then the parameters of the pushed authorization request
+// are returned instead. Each request URI can only be used once.
+func (s *Server) authorizationRequestParameters(query url.Values) (url.Values, error) {
+	requestURI := query.Get(qKeyRequestURI)
+
+	if requestURI == "" {
+		// Requests that are resumed after the user has logged in only
+		// contain the state so they are not affected by the requirement.
+		if s.requirePAR && query.Has(qKeyClientID) {
+			return nil, ErrPushedAuthRequestRequired
+		}
+
+		return query, nil
+	}
+
+	reference, ok := strings.CutPrefix(requestURI, requestURIPrefix)
+	if !ok || reference == "" {
+		return nil, ErrInvalidRequestURI
+	}
+
+	// The request URI is deleted as it is read so that it can only be used once.
+	entry, exists := s.cache.GetAndDelete(fmt.Sprintf(pushedAuthRequestKeyFmt, reference))
+
+	if !exists || entry.Expired() {
+		return nil, ErrInvalidRequestURI
+	}
+
+	var params url.Values
+
+	if err := entry.Decode(&params); err != nil {
+		return nil, fmt.Errorf("error decoding the pushed authorization request: %w", err)
+	}
+
+	clientID, err := utilities.ValidateAndCanonicalizeClientID(query.Get(qKeyClientID))
+	if err != nil {
+		return nil, fmt.Errorf("error canonicalizing the client ID: %w", err)
+	}
+
+	pushedClientID, err := utilities.ValidateAndCanonicalizeClientID(params.Get(qKeyClientID))
+	if err != nil {
+		return nil, fmt.Errorf("error canonicalizing the client ID of the pushed authorization request: %w", err)
+	}
+
+	if clientID != pushedClientID {
+		return nil, ErrMismatchedRequestURIClient
+	}
+
+	return params, nil
+}
+
+// outboundContext returns the context used for the outbound requests to the client.
+// Localhost client IDs that are explicitly allowed for local development may resolve
+// to the loopback addresses.
+func (s *Server) outboundContext(ctx context.Context, clientID string) context.Context {
+	if slices.Contains(s.localhostClientIDs, clientID) {
+		return outbound.WithLoopbackAllowed(ctx)
+	}
+
+	return ctx
+}
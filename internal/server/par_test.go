@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func testPushedAuthRequests(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		form := url.Values{}
+		form.Set(qKeyClientID, "https://app.example.net/")
+		form.Set(qKeyCodeChallenge, "OfYAxt8zU2dAPDWQxTAUIteRzMsoj9QBdMIVEDOErUo")
+		form.Set(qKeyCodeChallengeMethod, "S256")
+		form.Set(qKeyRedirectURI, "https://app.example.net/callback")
+		form.Set(qKeyResponseType, "code")
+		form.Set(qKeyScope, "profile create")
+		form.Set(qKeyState, "1234567890")
+		form.Set("unknown", "discarded")
+
+		reference, err := srv.savePushedAuthRequest(form)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to save the pushed authorization request: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		query := url.Values{}
+		query.Set(qKeyClientID, "https://app.example.net")
+		query.Set(qKeyRequestURI, requestURIPrefix+reference)
+
+		params, err := srv.authorizationRequestParameters(query)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Received an error after resolving the request URI.\ngot: %q",
+				t.Name(),
+				err.Error(),
+			)
+		}
+
+		form.Del("unknown")
+
+		if params.Encode() != form.Encode() {
+			t.Errorf(
+				"FAILED test %s: Unexpected parameters returned for the request URI.\nwant: %s\ngot: %s",
+				t.Name(),
+				form.Encode(),
+				params.Encode(),
+			)
+		} else {
+			t.Logf("Expected parameters returned for the request URI.\ngot: %s", params.Encode())
+		}
+
+		// The request URI can only be used once.
+		if _, err := srv.authorizationRequestParameters(query); !errors.Is(err, ErrInvalidRequestURI) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error received after reusing the request URI.\nwant: %q\ngot: %v",
+				t.Name(),
+				ErrInvalidRequestURI.Error(),
+				err,
+			)
+		} else {
+			t.Logf("Expected error received after reusing the request URI.\ngot: %q", err.Error())
+		}
+
+		reference, err = srv.savePushedAuthRequest(form)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to save the pushed authorization request: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		query.Set(qKeyClientID, "https://other.example.net/")
+		query.Set(qKeyRequestURI, requestURIPrefix+reference)
+
+		if _, err := srv.authorizationRequestParameters(query); !errors.Is(err, ErrMismatchedRequestURIClient) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error received after using the request URI with a different client.\nwant: %q\ngot: %v",
+				t.Name(),
+				ErrMismatchedRequestURIClient.Error(),
+				err,
+			)
+		} else {
+			t.Logf("Expected error received after using the request URI with a different client.\ngot: %q", err.Error())
+		}
+
+		reference, err = srv.savePushedAuthRequest(form)
+		if err != nil {
+			t.Fatalf(
+				"FAILED test %s: Unable to save the pushed authorization request: %v",
+				t.Name(),
+				err,
+			)
+		}
+
+		query.Set(qKeyClientID, "https://app.example.net/")
+		query.Set(qKeyRequestURI, requestURIPrefix+reference)
+
+		// Only one of the concurrent requests with the same request URI is accepted.
+		var (
+			wg       sync.WaitGroup
+			accepted atomic.Int32
+		)
+
+		for range 10 {
+			wg.Go(func() {
+				if _, err := srv.authorizationRequestParameters(query); err == nil {
+					accepted.Add(1)
+				}
+			})
+		}
+
+		wg.Wait()
+
+		if accepted.Load() != 1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of concurrent requests accepted with the same request URI.\nwant: 1\ngot: %d",
+				t.Name(),
+				accepted.Load(),
+			)
+		} else {
+			t.Log("Only one of the concurrent requests was accepted with the request URI.")
+		}
+	}
+}
+
+func testConsentRequiresValidatedRequest(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		profileID := "https://billjones.example.net/"
+
+		authReq := clientAuthRequest{
+			ClientID:            "https://app.example.net/",
+			CodeChallenge:       "OfYAxt8zU2dAPDWQxTAUIteRzMsoj9QBdMIVEDOErUo",
+			CodeChallengeMethod: "S256",
+			RedirectURI:         "https://app.example.net/callback",
+			ResponseType:        "code",
+			Scope:               []string{"profile"},
+		}
+
+		accept := func(query url.Values, state, profileID string) *httptest.ResponseRecorder {
+			request := newTestFormRequest(t, url.Values{qKeyState: {state}})
+			request.URL.RawQuery = query.Encode()
+
+			recorder := httptest.NewRecorder()
+			srv.authorizeAccept(recorder, request, profileID)
+
+			return recorder
+		}
+
+		issuedCode := func(response *httptest.ResponseRecorder) bool {
+			location, err := url.Parse(response.Header().Get("Location"))
+
+			return err == nil && location.Query().Has(qKeyCode)
+		}
+
+		// The authorization request is not created from the query when the state is not cached.
+		query := url.Values{
+			qKeyClientID:            {authReq.ClientID},
+			qKeyCodeChallenge:       {authReq.CodeChallenge},
+			qKeyCodeChallengeMethod: {authReq.CodeChallengeMethod},
+			qKeyRedirectURI:         {"https://attacker.example.org/callback"},
+			qKeyResponseType:        {"code"},
+			qKeyState:               {"unknown-state"},
+		}
+
+		if response := accept(query, "unknown-state", profileID); response.Code != http.StatusUnauthorized || issuedCode(response) {
+			t.Errorf(
+				"FAILED test %s: Unexpected response after accepting a request that is not cached.\nwant: %d\ngot: %d",
+				t.Name(),
+				http.StatusUnauthorized,
+				response.Code,
+			)
+		} else {
+			t.Log("The request that is not cached was not accepted.")
+		}
+
+		// The requests that were cached but not validated cannot be accepted.
+		authReq.State = "unvalidated-state"
+
+		encodedState, err := srv.addClientAuthRequestToCache(authReq)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to cache the authorization request: %v", t.Name(), err)
+		}
+
+		if response := accept(nil, encodedState, profileID); response.Code != http.StatusUnauthorized || issuedCode(response) {
+			t.Errorf(
+				"FAILED test %s: Unexpected response after accepting a request that was not validated.\nwant: %d\ngot: %d",
+				t.Name(),
+				http.StatusUnauthorized,
+				response.Code,
+			)
+		} else {
+			t.Log("The request that was not validated was not accepted.")
+		}
+
+		// The validated requests can only be accepted once by the profile that validated them.
+		authReq.State = "validated-state"
+		authReq.ConsentProfileID = profileID
+
+		encodedState, err = srv.addClientAuthRequestToCache(authReq)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to cache the authorization request: %v", t.Name(), err)
+		}
+
+		if response := accept(nil, encodedState, profileID); !issuedCode(response) {
+			t.Errorf(
+				"FAILED test %s: The validated request was not accepted.\nstatus: %d",
+				t.Name(),
+				response.Code,
+			)
+		} else {
+			t.Log("The validated request was accepted.")
+		}
+
+		if response := accept(nil, encodedState, profileID); issuedCode(response) {
+			t.Errorf("FAILED test %s: The validated request was accepted twice.", t.Name())
+		}
+
+		authReq.State = "other-profile-state"
+
+		encodedState, err = srv.addClientAuthRequestToCache(authReq)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to cache the authorization request: %v", t.Name(), err)
+		}
+
+		if response := accept(nil, encodedState, "https://janedoe.example.net/"); issuedCode(response) {
+			t.Errorf("FAILED test %s: The request validated for another profile was accepted.", t.Name())
+		} else {
+			t.Log("The request validated for another profile was not accepted.")
+		}
+	}
+}
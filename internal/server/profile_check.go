@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"slices"
+	"strings"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+)
+
+const pathOverviewCheck string = "/profile/overview/check"
+
+// checkProfileLinks fetches the website at the profile URL and reports each IndieAuth
+// link that is missing from the website or that does not point to this server.
+// No problems are reported when the website delegates to this server correctly.
+// The legacy authorization_endpoint and token_endpoint links are optional when the
+// indieauth-metadata link points to this server. Redirects are not followed, the same
+// as when the endpoints are discovered during sign in, so a redirect is reported as
+// a problem.
+func (s *Server) checkProfileLinks(ctx context.Context, profileID string) ([]string, error) {
+	links, err := discovery.FetchProfileLinks(ctx, s.outboundClient, profileID, s.issuer)
+	if err != nil {
+		if errors.As(err, &discovery.AttemptedRedirectionError{}) {
+			return []string{
+				"Your website redirected the request for " + profileID + " to another page. " +
+					"Redirects are not followed so the links must be published at the profile URL itself.",
+			}, nil
+		}
+
+		return nil, fmt.Errorf("error fetching the links from %s: %w", profileID, err)
+	}
+
+	metadataLinked := len(links.IndieAuthMetadata) > 0 &&
+		!slices.ContainsFunc(links.IndieAuthMetadata, func(value string) bool { return value != s.metadataURL })
+
+	problems := make([]string, 0)
+
+	for _, link := range []struct {
+		rel      string
+		want     string
+		found    []string
+		optional bool
+	}{
+		{rel: "indieauth-metadata", want: s.metadataURL, found: links.IndieAuthMetadata},
+		{rel: "authorization_endpoint", want: s.authEndpoint, found: links.AuthorizationEndpoint, optional: metadataLinked},
+		{rel: "token_endpoint", want: s.tokenEndpoint, found: links.TokenEndpoint, optional: metadataLinked},
+	} {
+		if len(link.found) == 0 && link.optional {
+			continue
+		}
+
+		switch {
+		case len(link.found) == 0:
+			problems = append(
+				problems,
+				fmt.Sprintf("The %s link is missing. It should be set to %s.", link.rel, link.want),
+			)
+		case link.found[0] != link.want:
+			problems = append(
+				problems,
+				fmt.Sprintf("The %s link is set to %s instead of %s.", link.rel, link.found[0], link.want),
+			)
+		case len(link.found) > 1 && slices.ContainsFunc(link.found, func(value string) bool { return value != link.want }):
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"There are conflicting %s links (%s). Only %s should be set.",
+					link.rel,
+					strings.Join(link.found, ", "),
+					link.want,
+				),
+			)
+		}
+	}
+
+	return problems, nil
+}
+
+// checkProfileWebsite reports the result of the profile link check on the overview page.
+func (s *Server) checkProfileWebsite(writer http.ResponseWriter, request *http.Request, profileID string) {
+	problems, err := s.checkProfileLinks(request.Context(), profileID)
+	if err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to fetch your website: "+html.EscapeString(err.Error())),
+			http.StatusOK,
+			err,
+			nil,
+		)
+
+		return
+	}
+
+	if len(problems) > 0 {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, profileLinkProblemsHTML(problems)),
+			http.StatusOK,
+			nil,
+			nil,
+		)
+
+		return
+	}
+
+	s.sendHTMLResponse(
+		writer,
+		fmt.Appendf([]byte{}, responseSuccessFmt, "Your website links to "+html.EscapeString(s.issuer)+" correctly"),
+		http.StatusOK,
+		nil,
+		nil,
+	)
+}
+
+func profileLinkProblemsHTML(problems []string) string {
+	var builder strings.Builder
+
+	builder.WriteString("Your website is not set up to use this server:<ul>")
+
+	for _, problem := range problems {
+		builder.WriteString("<li>" + html.EscapeString(problem) + "</li>")
+	}
+
+	builder.WriteString("</ul>")
+
+	return builder.String()
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func testProfileLinkCheck(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		defer useTestOutboundClient(t, srv)()
+
+		mux := http.NewServeMux()
+
+		// The website that delegates to the server correctly.
+		mux.HandleFunc("/delegated/", func(writer http.ResponseWriter, _ *http.Request) {
+			writer.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"indieauth-metadata\"", srv.metadataURL))
+			writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = fmt.Fprintf(
+				writer,
+				`<html><head><link rel="authorization_endpoint" href=%q><link rel="token_endpoint" href=%q></head></html>`,
+				srv.authEndpoint,
+				srv.tokenEndpoint,
+			)
+		})
+
+		// The website that delegates to another server.
+		mux.HandleFunc("/misconfigured/", func(writer http.ResponseWriter, _ *http.Request) {
+			writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = fmt.Fprintf(
+				writer,
+				`<html><head><link rel="indieauth-metadata" href=%q><link rel="indieauth-metadata" href="https://auth.example.org/metadata"><link rel="authorization_endpoint" href="https://auth.example.org/auth"></head></html>`,
+				srv.metadataURL,
+			)
+		})
+
+		// The website that only links to the server's metadata.
+		mux.HandleFunc("/metadata-only/", func(writer http.ResponseWriter, _ *http.Request) {
+			writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = fmt.Fprintf(writer, `<html><head><link rel="indieauth-metadata" href=%q></head></html>`, srv.metadataURL)
+		})
+
+		// The website that redirects to another page.
+		mux.Handle("/redirected/", http.RedirectHandler("/delegated/", http.StatusFound))
+
+		website := httptest.NewServer(mux)
+		defer website.Close()
+
+		testCases := []struct {
+			path string
+			want []string
+		}{
+			{
+				path: "/delegated/",
+				want: []string{},
+			},
+			{
+				path: "/metadata-only/",
+				want: []string{},
+			},
+			{
+				path: "/redirected/",
+				want: []string{
+					"Your website redirected the request for " + website.URL + "/redirected/ to another page. " +
+						"Redirects are not followed so the links must be published at the profile URL itself.",
+				},
+			},
+			{
+				path: "/misconfigured/",
+				want: []string{
+					"There are conflicting indieauth-metadata links (" + srv.metadataURL + ", https://auth.example.org/metadata). Only " + srv.metadataURL + " should be set.",
+					"The authorization_endpoint link is set to https://auth.example.org/auth instead of " + srv.authEndpoint + ".",
+					"The token_endpoint link is missing. It should be set to " + srv.tokenEndpoint + ".",
+				},
+			},
+		}
+
+		for _, tc := range testCases {
+			got, err := srv.checkProfileLinks(context.Background(), website.URL+tc.path)
+			if err != nil {
+				t.Fatalf(
+					"FAILED test %s: Received an error checking the links of %s: %v",
+					t.Name(),
+					tc.path,
+					err,
+				)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf(
+					"FAILED test %s: Unexpected problems reported for %s.\nwant: %q\ngot: %q",
+					t.Name(),
+					tc.path,
+					tc.want,
+					got,
+				)
+			} else {
+				t.Logf("Expected problems reported for %s.\ngot: %q", tc.path, got)
+			}
+		}
+	}
+}
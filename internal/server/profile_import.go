@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+const (
+	pathSetupImport        string = "/setup/import"
+	pathSettingsInfoImport string = "/profile/settings/info/import"
+)
+
+type profileFields struct {
+	DisplayName string
+	URL         string
+	Email       string
+	PhotoURL    string
+}
+
+// fetchProfileInformation returns the profile information from the representative
+// h-card of the website at the profile URL. The fields missing from the h-card are
+// taken from the current profile information.
+func (s *Server) fetchProfileInformation(
+	ctx context.Context,
+	profileID string,
+	current database.ProfileInformation,
+) (database.ProfileInformation, error) {
+	hCard, err := discovery.FetchRepresentativeHCard(ctx, s.outboundClient, profileID, s.issuer)
+	if err != nil {
+		return database.ProfileInformation{}, fmt.Errorf("error fetching the h-card from %s: %w", profileID, err)
+	}
+
+	valueOrCurrent := func(value, currentValue string) string {
+		if value == "" {
+			return currentValue
+		}
+
+		return value
+	}
+
+	return database.ProfileInformation{
+		Name:     valueOrCurrent(hCard.Name, current.Name),
+		URL:      valueOrCurrent(hCard.URL, current.URL),
+		PhotoURL: valueOrCurrent(hCard.Photo, current.PhotoURL),
+		Email:    valueOrCurrent(hCard.Email, current.Email),
+	}, nil
+}
+
+// importSetupProfileInformation pre-fills the profile fields on the setup page
+// with the information from the h-card on the user's website.
+func (s *Server) importSetupProfileInformation(writer http.ResponseWriter, request *http.Request) {
+	if s.dbInitialized {
+		http.Redirect(writer, request, "/profile/login", http.StatusSeeOther)
+
+		return
+	}
+
+	profileID, err := utilities.ValidateAndCanonicalizeURL(
+		strings.TrimSpace(request.PostFormValue("profileID")),
+		false,
+	)
+	if err != nil {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "profile_id_error",
+				message: "Please enter a valid domain or website",
+			},
+			fmt.Errorf("error validating the profile ID: %w", err),
+		)
+
+		return
+	}
+
+	s.importProfileInformation(writer, request, profileID)
+}
+
+// importProfileInformation pre-fills the profile fields with the information from
+// the h-card on the user's website. The fields that are missing from the h-card keep
+// the values from the form. The profile information is not saved until the user
+// submits the form.
+func (s *Server) importProfileInformation(writer http.ResponseWriter, request *http.Request, profileID string) {
+	profileInfo, err := s.fetchProfileInformation(
+		request.Context(),
+		profileID,
+		database.ProfileInformation{
+			Name:     request.PostFormValue("profileDisplayName"),
+			URL:      request.PostFormValue("profileURL"),
+			PhotoURL: request.PostFormValue("profilePhotoURL"),
+			Email:    request.PostFormValue("profileEmail"),
+		},
+	)
+	if err != nil {
+		writer.Header().Set("HX-Retarget", "#status")
+		writer.Header().Set("HX-Reswap", "outerHTML")
+
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to import your profile from your website"),
+			http.StatusUnprocessableEntity,
+			err,
+			nil,
+		)
+
+		return
+	}
+
+	s.sendHTMLResponseWithTemplate(
+		writer,
+		"profile_fields",
+		http.StatusOK,
+		profileFields{
+			DisplayName: profileInfo.Name,
+			URL:         profileInfo.URL,
+			Email:       profileInfo.Email,
+			PhotoURL:    profileInfo.PhotoURL,
+		},
+		nil,
+		nil,
+	)
+}
+
+// syncProfiles updates the information of every profile from the h-card on the
+// profile's website at every interval until the context is cancelled.
+func (s *Server) syncProfiles(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.dbInitialized {
+				continue
+			}
+
+			profileIDs, err := s.store.GetProfileIDs()
+			if err != nil {
+				slog.LogAttrs(
+					context.Background(),
+					slog.LevelError,
+					"Unable to get the profiles to sync.",
+					slog.Any("error", err),
+				)
+
+				continue
+			}
+
+			for _, profileID := range profileIDs {
+				if err := s.syncProfile(ctx, profileID); err != nil {
+					slog.LogAttrs(
+						context.Background(),
+						slog.LevelWarn,
+						"Unable to sync the profile with the h-card on the website.",
+						slog.String("profile_id", profileID),
+						slog.Any("error", err),
+					)
+				}
+			}
+		}
+	}
+}
+
+// syncProfile updates the profile's information from the h-card on the profile's
+// website. The profile is only updated when the information has changed.
+func (s *Server) syncProfile(ctx context.Context, profileID string) error {
+	current, err := s.store.GetProfileInformation(profileID)
+	if err != nil {
+		return fmt.Errorf("error getting the profile's information: %w", err)
+	}
+
+	profileInfo, err := s.fetchProfileInformation(ctx, profileID, current)
+	if err != nil {
+		return err
+	}
+
+	if profileInfo == current {
+		return nil
+	}
+
+	if err := s.store.UpdateProfileInformation(profileID, profileInfo); err != nil {
+		return fmt.Errorf("error updating the profile: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testProfileImport(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		defer useTestOutboundClient(t, srv)()
+
+		website := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = fmt.Fprintf(
+				writer,
+				`<html><body><div class="h-card"><a class="p-name u-url u-uid" href="http://%s/">Jane Smith</a><img class="u-photo" src="/jane.png"></div></body></html>`,
+				request.Host,
+			)
+		}))
+		defer website.Close()
+
+		profileID := website.URL + "/"
+
+		if err := srv.store.CreateProfile(profileID, database.Profile{
+			Information: database.ProfileInformation{
+				Name:  "Jane",
+				Email: "jane@example.org",
+			},
+		}); err != nil {
+			t.Fatalf("FAILED test %s: Unable to create the profile: %v", t.Name(), err)
+		}
+
+		want := database.ProfileInformation{
+			Name:     "Jane Smith",
+			URL:      profileID,
+			PhotoURL: website.URL + "/jane.png",
+			Email:    "jane@example.org",
+		}
+
+		// The imported information pre-fills the form and keeps
+		// the values of the fields that are missing from the h-card.
+		writer := httptest.NewRecorder()
+		srv.importProfileInformation(
+			writer,
+			newTestFormRequest(t, url.Values{"profileEmail": {"jane@example.org"}}),
+			profileID,
+		)
+
+		body := writer.Body.String()
+
+		if writer.Code != http.StatusOK ||
+			!strings.Contains(body, `value="`+want.Name+`"`) ||
+			!strings.Contains(body, `value="`+want.PhotoURL+`"`) ||
+			!strings.Contains(body, `value="`+want.Email+`"`) {
+			t.Errorf(
+				"FAILED test %s: Unexpected profile fields after importing the h-card.\ngot: %d %s",
+				t.Name(),
+				writer.Code,
+				body,
+			)
+		} else {
+			t.Log("Expected profile fields after importing the h-card.")
+		}
+
+		// The profile sync saves the information from the h-card.
+		if err := srv.syncProfile(context.Background(), profileID); err != nil {
+			t.Fatalf("FAILED test %s: Unable to sync the profile: %v", t.Name(), err)
+		}
+
+		got, err := srv.store.GetProfileInformation(profileID)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to get the profile's information: %v", t.Name(), err)
+		}
+
+		if got != want {
+			t.Errorf(
+				"FAILED test %s: Unexpected profile information after syncing the profile.\nwant: %+v\ngot: %+v",
+				t.Name(),
+				want,
+				got,
+			)
+		} else {
+			t.Logf("Expected profile information after syncing the profile.\ngot: %+v", got)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+)
+
+const (
+	responseModeQuery    string = "query"
+	responseModeFragment string = "fragment"
+	responseModeFormPost string = "form_post"
+)
+
+func supportedResponseModes() []string {
+	return []string{
+		responseModeQuery,
+		responseModeFragment,
+		responseModeFormPost,
+	}
+}
+
+// validateResponseMode validates the response mode requested by the client.
+// The form_post response mode can only be used with web based redirect URIs
+// as the browser submits the form to the redirect URI.
+func validateResponseMode(responseMode, redirectURI string) error {
+	if responseMode == "" {
+		return nil
+	}
+
+	if !slices.Contains(supportedResponseModes(), responseMode) {
+		return UnsupportedResponseModeError{responseMode: responseMode}
+	}
+
+	if responseMode == responseModeFormPost {
+		parsedRedirectURI, err := url.Parse(redirectURI)
+		if err != nil || (parsedRedirectURI.Scheme != "https" && parsedRedirectURI.Scheme != "http") {
+			return ErrInvalidFormPostRedirectURI
+		}
+	}
+
+	return nil
+}
+
+type formPostParameter struct {
+	Name  string
+	Value string
+}
+
+type formPostPage struct {
+	Title       string
+	RedirectURI string
+	Parameters  []formPostParameter
+}
+
+// sendAuthorizationResponse sends the authorization response back to the client using the
+// response mode from the client's authorization request. The state and the issuer identifier
+// are added to the response parameters. The query and fragment response modes redirect the
+// user's browser, either directly or through htmx, while the form_post response mode responds
+// with a form that the browser automatically submits to the redirect URI.
+func (s *Server) sendAuthorizationResponse(
+	writer http.ResponseWriter,
+	request *http.Request,
+	authReq clientAuthRequest,
+	params url.Values,
+) {
+	params.Set(qKeyState, authReq.State)
+	params.Set(qKeyIssuer, s.issuer)
+
+	if authReq.ResponseMode == responseModeFormPost {
+		page := formPostPage{
+			Title:       "Redirecting - " + info.ApplicationTitledName,
+			RedirectURI: authReq.RedirectURI,
+			Parameters:  make([]formPostParameter, 0, len(params)),
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(params)) {
+			page.Parameters = append(page.Parameters, formPostParameter{Name: name, Value: params.Get(name)})
+		}
+
+		writer.Header().Set("Cache-Control", "no-store")
+
+		s.sendHTMLResponseWithTemplate(
+			writer,
+			"form_post",
+			http.StatusOK,
+			page,
+			nil,
+			nil,
+		)
+
+		return
+	}
+
+	redirectURL := authorizationResponseURL(authReq.RedirectURI, authReq.ResponseMode, params)
+
+	if request.Header.Get("HX-Request") == "true" {
+		writer.Header().Set("Hx-Redirect", redirectURL)
+
+		return
+	}
+
+	http.Redirect(writer, request, redirectURL, http.StatusFound)
+}
+
+// authorizationResponseURL returns the redirect URI with the response parameters added
+// to either the query or the fragment.
+func authorizationResponseURL(redirectURI, responseMode string, params url.Values) string {
+	if responseMode == responseModeFragment {
+		return redirectURI + "#" + params.Encode()
+	}
+
+	separator := "?"
+	if strings.Contains(redirectURI, "?") {
+		separator = "&"
+	}
+
+	return redirectURI + separator + params.Encode()
+}
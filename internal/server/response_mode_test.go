@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func testAuthorizationResponses(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		authReq := clientAuthRequest{
+			ClientID:    "https://app.example.net/",
+			RedirectURI: "https://app.example.net/callback",
+			State:       "1234567890",
+		}
+
+		params := func() url.Values {
+			return url.Values{qKeyCode: {"abcdef"}}
+		}
+
+		wantQuery := "code=abcdef&iss=" + url.QueryEscape(srv.issuer) + "&state=1234567890"
+
+		// The query response mode through htmx.
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, pathAuthAccept, nil)
+		request.Header.Set("HX-Request", "true")
+
+		srv.sendAuthorizationResponse(writer, request, authReq, params())
+
+		if got, want := writer.Header().Get("Hx-Redirect"), authReq.RedirectURI+"?"+wantQuery; got != want {
+			t.Errorf(
+				"FAILED test %s: Unexpected redirect for the query response mode.\nwant: %q\ngot: %q",
+				t.Name(),
+				want,
+				got,
+			)
+		} else {
+			t.Logf("Expected redirect for the query response mode.\ngot: %q", got)
+		}
+
+		// The fragment response mode through a direct redirect.
+		authReq.ResponseMode = responseModeFragment
+		writer = httptest.NewRecorder()
+		request = httptest.NewRequest(http.MethodGet, pathAuth, nil)
+
+		srv.sendAuthorizationResponse(writer, request, authReq, params())
+
+		if got, want := writer.Header().Get("Location"), authReq.RedirectURI+"#"+wantQuery; writer.Code != http.StatusFound || got != want {
+			t.Errorf(
+				"FAILED test %s: Unexpected redirect for the fragment response mode.\nwant: %d %q\ngot: %d %q",
+				t.Name(),
+				http.StatusFound,
+				want,
+				writer.Code,
+				got,
+			)
+		} else {
+			t.Logf("Expected redirect for the fragment response mode.\ngot: %q", got)
+		}
+
+		// The form_post response mode.
+		authReq.ResponseMode = responseModeFormPost
+		writer = httptest.NewRecorder()
+		request = httptest.NewRequest(http.MethodPost, pathAuthAccept, nil)
+
+		srv.sendAuthorizationResponse(writer, request, authReq, params())
+
+		body := writer.Body.String()
+
+		for _, want := range []string{
+			`action="https://app.example.net/callback"`,
+			`name="code" value="abcdef"`,
+			`name="state" value="1234567890"`,
+			`name="iss" value="` + srv.issuer + `"`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf(
+					"FAILED test %s: The form for the form_post response mode does not contain %s.\ngot: %s",
+					t.Name(),
+					want,
+					body,
+				)
+			}
+		}
+
+		if writer.Header().Get("Location") != "" || writer.Header().Get("Hx-Redirect") != "" {
+			t.Errorf(
+				"FAILED test %s: The code was sent in a redirect for the form_post response mode.",
+				t.Name(),
+			)
+		}
+	}
+}
+
+func TestValidateResponseMode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		responseMode string
+		redirectURI  string
+		check        func(error) bool
+	}{
+		{
+			responseMode: "",
+			redirectURI:  "https://app.example.net/callback",
+			check:        func(err error) bool { return err == nil },
+		},
+		{
+			responseMode: responseModeFormPost,
+			redirectURI:  "https://app.example.net/callback",
+			check:        func(err error) bool { return err == nil },
+		},
+		{
+			responseMode: responseModeFragment,
+			redirectURI:  "com.example.app:/callback",
+			check:        func(err error) bool { return err == nil },
+		},
+		{
+			responseMode: responseModeFormPost,
+			redirectURI:  "com.example.app:/callback",
+			check:        func(err error) bool { return errors.Is(err, ErrInvalidFormPostRedirectURI) },
+		},
+		{
+			responseMode: "web_message",
+			redirectURI:  "https://app.example.net/callback",
+			check:        func(err error) bool { return errors.As(err, &UnsupportedResponseModeError{}) },
+		},
+	}
+
+	for _, tc := range testCases {
+		if err := validateResponseMode(tc.responseMode, tc.redirectURI); !tc.check(err) {
+			t.Errorf(
+				"FAILED test %s: Unexpected result for the response mode %q with the redirect URI %q.\ngot: %v",
+				t.Name(),
+				tc.responseMode,
+				tc.redirectURI,
+				err,
+			)
+		} else {
+			t.Logf("Expected result for the response mode %q with the redirect URI %q.", tc.responseMode, tc.redirectURI)
+		}
+	}
+}
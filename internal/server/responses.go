@@ -7,11 +7,18 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/cache"
 )
 
+// cacheFullRetryAfter is the number of seconds that the clients are asked to wait
+// before sending a new request when the server's cache is full.
+const cacheFullRetryAfter string = "60"
+
 func (s *Server) sendHTMLResponseWithTemplate(
 	writer http.ResponseWriter,
 	templateName string,
@@ -130,3 +137,62 @@ func sendErrorResponse(writer http.ResponseWriter, statusCode int, msg string, e
 
 	http.Error(writer, http.StatusText(statusCode), statusCode)
 }
+
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// sendOAuthError sends an OAuth 2.0 error response (RFC 6749, section 5.2) to the client.
+// The error description is only included for client errors.
+func sendOAuthError(writer http.ResponseWriter, statusCode int, errorCode string, err error) {
+	msg := "Client error"
+	description := err.Error()
+
+	if statusCode >= http.StatusInternalServerError {
+		msg = "Server error"
+		description = ""
+	}
+
+	slog.LogAttrs(
+		context.Background(),
+		slog.LevelError,
+		msg,
+		slog.String("oauth_error", errorCode),
+		slog.Any("error", err),
+		slog.String("request_id", writer.Header().Get("X-Request-ID")),
+	)
+
+	response := oauthErrorResponse{
+		Error:            errorCode,
+		ErrorDescription: description,
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(writer, statusCode, response)
+}
+
+// sendCacheError sends an error response for an error from saving a request to the
+// server's cache. The client is asked to try again later if the cache is full.
+func sendCacheError(writer http.ResponseWriter, err error) {
+	if errors.Is(err, cache.ErrCacheFull) {
+		writer.Header().Set("Retry-After", cacheFullRetryAfter)
+		sendErrorResponse(writer, http.StatusServiceUnavailable, "Server busy", err)
+
+		return
+	}
+
+	sendServerError(writer, err)
+}
+
+// sendOAuthCacheError is the OAuth 2.0 version of sendCacheError.
+func sendOAuthCacheError(writer http.ResponseWriter, err error) {
+	if errors.Is(err, cache.ErrCacheFull) {
+		writer.Header().Set("Retry-After", cacheFullRetryAfter)
+		sendOAuthError(writer, http.StatusServiceUnavailable, "temporarily_unavailable", err)
+
+		return
+	}
+
+	sendOAuthError(writer, http.StatusInternalServerError, "server_error", err)
+}
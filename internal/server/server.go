@@ -20,14 +20,22 @@ import (
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/cache"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/config"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/encryption"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/outbound"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/scopes"
 	"codeflow.dananglin.me.uk/apollo/beacon/internal/ui"
-	bolt "go.etcd.io/bbolt"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
 )
 
 const (
 	maxRequestSize int64 = 32 << 10 // 32KB
 
+	replayCacheNamespace string = "replay"
+
+	recordPurgeInterval time.Duration = 1 * time.Hour
+
 	activeTabSettings string = "settings"
 	activeTabHome     string = "home"
 
@@ -36,11 +44,14 @@ const (
 	qKeyCodeChallenge       string = "code_challenge"
 	qKeyCodeChallengeMethod string = "code_challenge_method"
 	qKeyError               string = "error"
+	qKeyErrorDescription    string = "error_description"
 	qKeyIssuer              string = "iss"
 	qKeyLoginType           string = "login_type"
 	qKeyMe                  string = "me"
 	qKeyProfileID           string = "profile_id"
 	qKeyRedirectURI         string = "redirect_uri"
+	qKeyRequestURI          string = "request_uri"
+	qKeyResponseMode        string = "response_mode"
 	qKeyResponseType        string = "response_type"
 	qKeyScope               string = "scope"
 	qKeyState               string = "state"
@@ -48,10 +59,15 @@ const (
 	loginTypeProfile   string = "profile"
 	loginTypeIndieauth string = "indieauth"
 
+	pathMetadata   string = "/.well-known/oauth-authorization-server"
 	pathAuth       string = "/indieauth/authorize"
 	pathAuthAccept string = pathAuth + "/accept"
 	pathAuthReject string = pathAuth + "/reject"
 	pathToken      string = "/indieauth/token" // #nosec G101 -- This is not hardcoded credentials.
+	pathPAR        string = "/indieauth/par"
+	pathDeviceAuth string = "/indieauth/device"
+	pathIntrospect string = "/indieauth/introspect"
+	pathClientLogo string = "/client/logo/"
 
 	responseFailureFmt    string = `<div id="status" class="failure">%s</div>`
 	responseSuccessFmt    string = `<div id="status" class="success">%s</div>`
@@ -69,17 +85,42 @@ type (
 
 	Server struct {
 		httpServer              *http.Server
-		boltdb                  *bolt.DB
+		store                   database.Store
 		cache                   *cache.Cache
+		replayCache             *cache.Cache
+		scopes                  *scopes.Registry
+		clientMetadata          *discovery.MetadataCache
+		clientLogos             *discovery.LogoCache
+		outboundGuard           *outbound.Guard
+		outboundClient          *http.Client
+		localhostClientIDs      []string
+		configClientPolicy      database.ClientPolicy
+		defaultTokenLifetime    time.Duration
+		requirePAR              bool
+		legacyTokenVerification bool
+		forwardAuthAllowedHosts []string
 		gracefulShutdownTimeout time.Duration
+		profileSyncInterval     time.Duration
+		backupInterval          time.Duration
+		backupDir               string
+		backupRetention         int
+		adminSocketPath         string
+		adminServer             *http.Server
 		htmlTemplate            *template.Template
 		dbInitialized           bool
 		domainName              string
+		cookieDomain            string
 		jwtSecret               string
 		jwtCookieName           string
+		metadataURL             string
 		authEndpoint            string
 		issuer                  string
 		tokenEndpoint           string
+		parEndpoint             string
+		deviceAuthEndpoint      string
+		introspectionEndpoint   string
+		ticketEndpoint          string
+		deviceVerificationURI   string
 	}
 )
 
@@ -89,18 +130,137 @@ func NewServer(configPath string) (*Server, error) {
 		return nil, fmt.Errorf("error loading the configuration: %w", err)
 	}
 
-	boltdb, err := database.Open(cfg.Database.Path)
+	keyring, err := encryption.LoadKeyring(cfg.Encryption.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the encryption keys: %w", err)
+	}
+
+	if keyring == nil {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelWarn,
+			"No encryption key is set so the sensitive records are stored in plaintext. "+
+				"Set the encryption key file in the configuration or the "+encryption.KeyEnvVar+
+				" environment variable to encrypt them.",
+		)
+	} else {
+		slog.LogAttrs(
+			context.Background(),
+			slog.LevelInfo,
+			"The sensitive records are encrypted.",
+			slog.String("primary_key_id", keyring.PrimaryKeyID()),
+		)
+	}
+
+	store, err := database.OpenStore(cfg.Database.Driver, cfg.Database.Path, keyring)
 	if err != nil {
 		return nil, fmt.Errorf("error opening the database: %w", err)
 	}
 
+	if boltdb, ok := database.BoltDB(store); ok {
+		migrations, err := database.Migrate(boltdb)
+		if err != nil {
+			return nil, fmt.Errorf("error migrating the database: %w", err)
+		}
+
+		for _, migration := range migrations {
+			slog.LogAttrs(
+				context.Background(),
+				slog.LevelInfo,
+				"Migrated the database.",
+				slog.Int("schema_version", migration.Version),
+				slog.String("description", migration.Description),
+			)
+		}
+	}
+
 	tmpl, err := template.New("").ParseFS(ui.TemplateFS, ui.TemplatesDir+"/*")
 	if err != nil {
 		return nil, fmt.Errorf("error creating the HTML template: %w", err)
 	}
 
+	scopeDefinitions := make([]scopes.Scope, len(cfg.Scopes.Definitions))
+	for ind, definition := range cfg.Scopes.Definitions {
+		scopeDefinitions[ind] = scopes.Scope{
+			Name:        definition.Name,
+			Description: definition.Description,
+			Risk:        scopes.Risk(definition.Risk),
+			Allowed:     definition.Allowed,
+		}
+	}
+
+	scopeRegistry, err := scopes.NewRegistry(scopes.Policy(cfg.Scopes.UnknownScopePolicy), scopeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the scope registry: %w", err)
+	}
+
+	denyRanges := cfg.Outbound.DenyRanges
+	if len(denyRanges) == 0 {
+		denyRanges = outbound.DefaultDenyRanges()
+	}
+
+	outboundGuard, err := outbound.NewGuard(denyRanges)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the outbound guard: %w", err)
+	}
+
+	outboundClient := outbound.NewClient(
+		outboundGuard,
+		outbound.Settings{
+			ConnectTimeout:  time.Duration(cfg.Outbound.ConnectTimeout) * time.Second,
+			Timeout:         time.Duration(cfg.Outbound.Timeout) * time.Second,
+			MaxResponseSize: cfg.Outbound.MaxResponseSize,
+		},
+	)
+
+	localhostClientIDs := make([]string, len(cfg.Development.LocalhostClientIDs))
+	for ind, clientID := range cfg.Development.LocalhostClientIDs {
+		canonicalizedClientID, err := utilities.ValidateAndCanonicalizeClientID(clientID)
+		if err != nil {
+			return nil, fmt.Errorf("error canonicalizing the localhost client ID %q: %w", clientID, err)
+		}
+
+		localhostClientIDs[ind] = canonicalizedClientID
+	}
+
+	configClientPolicy, err := newConfigClientPolicy(cfg.ClientPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the client policy: %w", err)
+	}
+
 	setupLogging(cfg.Log.Level)
 
+	// The server's cache holds the pending requests which anyone can create so the
+	// number of entries is bounded and the entries closest to expiring are evicted.
+	// The replay cache holds the authorization codes and the records of the used DPoP
+	// proofs and client assertions. These entries must not be lost while they are valid
+	// so the replay cache never evicts them. It is also bounded and the requests that
+	// would need a new entry are refused while it is full.
+	var serverCache, replayCache *cache.Cache
+
+	if cfg.Cache.Persistent {
+		serverCache, err = cache.NewPersistentCache("", 1*time.Minute, cfg.Cache.MaxEntries, store)
+		if err != nil {
+			return nil, fmt.Errorf("error creating the persistent cache: %w", err)
+		}
+
+		replayCache, err = cache.NewPersistentCache(
+			replayCacheNamespace,
+			1*time.Minute,
+			cfg.Cache.MaxEntries,
+			store,
+			cache.WithoutEviction(),
+		)
+		if err != nil {
+			serverCache.Close()
+
+			return nil, fmt.Errorf("error creating the persistent replay cache: %w", err)
+		}
+	} else {
+		serverCache = cache.NewCache(1*time.Minute, cfg.Cache.MaxEntries)
+		replayCache = cache.NewCache(1*time.Minute, cfg.Cache.MaxEntries, cache.WithoutEviction())
+	}
+
 	server := Server{
 		httpServer: &http.Server{
 			Addr:              fmt.Sprintf("%s:%d", cfg.BindAddress, cfg.Port),
@@ -108,19 +268,57 @@ func NewServer(configPath string) (*Server, error) {
 			ReadTimeout:       30 * time.Second,
 			WriteTimeout:      30 * time.Second,
 		},
-		boltdb:                  boltdb,
-		cache:                   cache.NewCache(1 * time.Minute),
+		store:                   store,
+		cache:                   serverCache,
+		replayCache:             replayCache,
+		scopes:                  scopeRegistry,
+		outboundGuard:           outboundGuard,
+		outboundClient:          outboundClient,
+		localhostClientIDs:      localhostClientIDs,
+		configClientPolicy:      configClientPolicy,
+		defaultTokenLifetime:    time.Duration(cfg.ClientPolicy.DefaultTokenLifetime) * time.Second,
+		requirePAR:              cfg.Authorization.RequirePAR,
+		legacyTokenVerification: cfg.Compatibility.LegacyTokenVerification,
+		forwardAuthAllowedHosts: cfg.ForwardAuth.AllowedHosts,
 		gracefulShutdownTimeout: time.Duration(cfg.GracefulShutdownTimeout) * time.Second,
+		profileSyncInterval:     time.Duration(cfg.ProfileSync.Interval) * time.Second,
+		backupInterval:          time.Duration(cfg.Backup.Interval) * time.Second,
+		backupDir:               cfg.Backup.Directory,
+		backupRetention:         cfg.Backup.Retention,
+		adminSocketPath:         cfg.Admin.SocketPath,
 		htmlTemplate:            tmpl,
 		domainName:              cfg.Domain,
+		cookieDomain:            cfg.ForwardAuth.CookieDomain,
 		jwtSecret:               cfg.JWT.Secret,
 		jwtCookieName:           cfg.JWT.CookieName,
+		metadataURL:             fmt.Sprintf("https://%s%s", cfg.Domain, pathMetadata),
 		authEndpoint:            fmt.Sprintf("https://%s%s", cfg.Domain, pathAuth),
 		issuer:                  fmt.Sprintf("https://%s/", cfg.Domain),
 		tokenEndpoint:           fmt.Sprintf("https://%s%s", cfg.Domain, pathToken),
+		parEndpoint:             fmt.Sprintf("https://%s%s", cfg.Domain, pathPAR),
+		deviceAuthEndpoint:      fmt.Sprintf("https://%s%s", cfg.Domain, pathDeviceAuth),
+		introspectionEndpoint:   fmt.Sprintf("https://%s%s", cfg.Domain, pathIntrospect),
+		ticketEndpoint:          fmt.Sprintf("https://%s%s", cfg.Domain, pathTicket),
+		deviceVerificationURI:   fmt.Sprintf("https://%s%s", cfg.Domain, pathDevice),
 	}
 
-	dbInitialized, err := database.Initialized(server.boltdb)
+	server.clientMetadata = discovery.NewMetadataCache(
+		store,
+		outboundClient,
+		server.issuer,
+		time.Duration(cfg.ClientMetadataCache.DefaultMaxAge)*time.Second,
+		time.Duration(cfg.ClientMetadataCache.StaleGracePeriod)*time.Second,
+	)
+
+	server.clientLogos = discovery.NewLogoCache(
+		store,
+		outboundClient,
+		server.issuer,
+		time.Duration(cfg.ClientMetadataCache.DefaultMaxAge)*time.Second,
+		cfg.ClientMetadataCache.LogoMaxSize,
+	)
+
+	dbInitialized, err := server.store.Initialized()
 	if err != nil {
 		return nil, fmt.Errorf(
 			"error determining if the database has been initialized or not: %w",
@@ -156,6 +354,12 @@ func (s *Server) Serve() error {
 		}
 	}()
 
+	if s.adminSocketPath != "" {
+		if err := s.serveAdmin(); err != nil {
+			return fmt.Errorf("error serving the admin socket: %w", err)
+		}
+	}
+
 	// Create the context for receiving the shutdown signal
 	shutdownSignal, stop := signal.NotifyContext(
 		context.Background(),
@@ -164,6 +368,16 @@ func (s *Server) Serve() error {
 	)
 	defer stop()
 
+	if s.profileSyncInterval > 0 {
+		go s.syncProfiles(shutdownSignal, s.profileSyncInterval)
+	}
+
+	if s.backupInterval > 0 {
+		go s.backupOnSchedule(shutdownSignal)
+	}
+
+	go s.purgeExpiredRecords(shutdownSignal, recordPurgeInterval)
+
 	<-shutdownSignal.Done()
 	stop()
 
@@ -199,23 +413,51 @@ func (s *Server) setupRouter() {
 	mux.Handle("GET /static/", neuter(http.FileServerFS(ui.StaticFS)))
 	mux.Handle("GET /setup", s.entrypoint(http.HandlerFunc(s.setup)))
 	mux.Handle("POST /setup", s.entrypoint(parseForm(s.setup)))
+	mux.Handle("POST "+pathSetupImport, s.entrypoint(parseForm(s.importSetupProfileInformation)))
 	mux.Handle("GET /{$}", s.entrypoint(s.profileAuthorization(redirectRoot, s.profileRedirectToLogin)))
-	mux.Handle("GET /.well-known/oauth-authorization-server", s.entrypoint(http.HandlerFunc(s.getMetadata)))
+	mux.Handle("GET "+pathMetadata, s.entrypoint(http.HandlerFunc(s.getMetadata)))
 	mux.Handle("GET /profile", s.entrypoint(http.HandlerFunc(s.redirectProfile)))
 	mux.Handle("GET /profile/login", s.entrypoint(http.HandlerFunc(s.getLoginPage)))
 	mux.Handle("POST /profile/login", s.entrypoint(parseForm(s.authenticate)))
 	mux.Handle("GET /profile/overview", s.entrypoint(s.profileAuthorization(s.getOverviewPage, s.profileRedirectToLogin)))
+	mux.Handle("POST "+pathOverviewCheck, s.entrypoint(parseForm(s.profileAuthorization(s.checkProfileWebsite, s.profileRedirectToLogin))))
 	mux.Handle("POST /profile/logout", s.entrypoint(parseForm(s.profileAuthorization(s.logout, s.profileRedirectToLogin))))
 	mux.Handle("GET /profile/settings", s.entrypoint(http.HandlerFunc(s.redirectProfileSettings)))
 	mux.Handle("GET /profile/settings/info", s.entrypoint(s.profileAuthorization(s.getUpdateProfileInfoPage, s.profileRedirectToLogin)))
 	mux.Handle("POST /profile/settings/info", s.entrypoint(parseForm(s.profileAuthorization(s.updateProfileInformation, s.profileRedirectToLogin))))
+	mux.Handle("POST "+pathSettingsInfoImport, s.entrypoint(parseForm(s.profileAuthorization(s.importProfileInformation, s.profileRedirectToLogin))))
 	mux.Handle("GET /profile/settings/password", s.entrypoint(s.profileAuthorization(s.getUpdatePasswordPage, s.profileRedirectToLogin)))
 	mux.Handle("POST /profile/settings/password", s.entrypoint(parseForm(s.profileAuthorization(s.updateProfilePassword, s.profileRedirectToLogin))))
+	mux.Handle("GET "+pathSettingsClientPolicy, s.entrypoint(s.profileAuthorization(s.getClientPolicyPage, s.profileRedirectToLogin)))
+	mux.Handle("POST "+pathSettingsClientPolicy+"/hosts", s.entrypoint(parseForm(s.profileAuthorization(s.updateClientPolicyHosts, s.profileRedirectToLogin))))
+	mux.Handle("POST "+pathSettingsClientPolicy+"/rules", s.entrypoint(parseForm(s.profileAuthorization(s.saveClientPolicyRule, s.profileRedirectToLogin))))
+	mux.Handle("POST "+pathSettingsClientPolicy+"/rules/delete", s.entrypoint(parseForm(s.profileAuthorization(s.deleteClientPolicyRule, s.profileRedirectToLogin))))
+	mux.Handle("GET "+pathSettingsClientRegistry, s.entrypoint(s.profileAuthorization(s.getClientRegistryPage, s.profileRedirectToLogin)))
+	mux.Handle("POST "+pathSettingsClientRegistry, s.entrypoint(parseForm(s.profileAuthorization(s.registerClient, s.profileRedirectToLogin))))
+	mux.Handle("POST "+pathSettingsClientRegistry+"/delete", s.entrypoint(parseForm(s.profileAuthorization(s.deleteRegisteredClient, s.profileRedirectToLogin))))
+	mux.Handle("GET "+pathSettingsTickets, s.entrypoint(s.profileAuthorization(s.getTicketsPage, s.profileRedirectToLogin)))
+	mux.Handle("POST "+pathSettingsTickets, s.entrypoint(parseForm(s.profileAuthorization(s.grantAccess, s.profileRedirectToLogin))))
+	mux.Handle("POST "+pathSettingsTickets+"/delete", s.entrypoint(parseForm(s.profileAuthorization(s.deleteReceivedToken, s.profileRedirectToLogin))))
 	mux.Handle("GET "+pathAuth, s.entrypoint(s.profileAuthorization(s.authorize, s.authorizeRedirectToLogin)))
 	mux.Handle("POST "+pathAuth, s.entrypoint(parseForm(s.exchangeAuthorization(s.profileExchange))))
 	mux.Handle("POST "+pathAuthAccept, s.entrypoint(parseForm(s.profileAuthorization(s.authorizeAccept, nil))))
 	mux.Handle("POST "+pathAuthReject, s.entrypoint(parseForm(s.profileAuthorization(s.authorizeReject, nil))))
-	mux.Handle("POST "+pathToken, s.entrypoint(parseForm(s.exchangeAuthorization(s.tokenExchange))))
+	mux.Handle("POST "+pathToken, s.entrypoint(parseForm(s.tokenGrant)))
+	mux.Handle("POST "+pathPAR, s.entrypoint(parseForm(s.pushAuthorizationRequest)))
+	mux.Handle("POST "+pathDeviceAuth, s.entrypoint(parseForm(s.authorizeDevice)))
+	mux.Handle("POST "+pathIntrospect, s.entrypoint(parseForm(s.introspectToken)))
+	mux.Handle("POST "+pathTicket, s.entrypoint(parseForm(s.receiveTicket)))
+	mux.Handle("GET "+pathDevice, s.entrypoint(s.profileAuthorization(s.getDevicePage, s.profileRedirectToLogin)))
+	mux.Handle("POST "+pathDevice, s.entrypoint(parseForm(s.profileAuthorization(s.deviceConsent, s.profileRedirectToLogin))))
+	mux.Handle("POST "+pathDeviceAccept, s.entrypoint(parseForm(s.profileAuthorization(s.deviceAccept, nil))))
+	mux.Handle("POST "+pathDeviceReject, s.entrypoint(parseForm(s.profileAuthorization(s.deviceReject, nil))))
+	mux.Handle("GET "+pathClientLogo+"{id}", s.entrypoint(s.profileAuthorization(s.getClientLogo, nil)))
+	mux.Handle(pathForwardAuth, s.entrypoint(s.profileAuthorization(s.verifyForwardAuth, s.forwardAuthRedirectToLogin)))
+
+	// Resource servers using the older IndieAuth method verify access tokens with a GET request.
+	if s.legacyTokenVerification {
+		mux.Handle("GET "+pathToken, s.entrypoint(http.HandlerFunc(s.verifyAccessToken)))
+	}
 
 	s.httpServer.Handler = mux
 }
@@ -231,13 +473,28 @@ func (s *Server) shutdown(ctx context.Context) error {
 		return fmt.Errorf("error shutting down the HTTP server: %w", err)
 	}
 
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			return fmt.Errorf("error shutting down the admin server: %w", err)
+		}
+	}
+
+	slog.LogAttrs(
+		context.Background(),
+		slog.LevelInfo,
+		"Stopping the caches.",
+	)
+
+	s.cache.Close()
+	s.replayCache.Close()
+
 	slog.LogAttrs(
 		context.Background(),
 		slog.LevelInfo,
 		"Closing the database.",
 	)
 
-	if err := s.boltdb.Close(); err != nil {
+	if err := s.store.Close(); err != nil {
 		return fmt.Errorf("error closing the database: %w", err)
 	}
 
@@ -26,4 +26,22 @@ func TestServer(t *testing.T) {
 	}()
 
 	t.Run("Test Server Metadata", testGetMetadata(testServer))
+	t.Run("Test Pushed Authorization Requests", testPushedAuthRequests(testServer))
+	t.Run("Test Consent Requires Validated Request", testConsentRequiresValidatedRequest(testServer))
+	t.Run("Test Client Authentication", testClientAuthentication(testServer))
+	t.Run("Test Client Assertion Replay", testClientAssertionReplay(testServer))
+	t.Run("Test Device Authorization", testDeviceAuthorization(testServer))
+	t.Run("Test Device Authorization Concurrency", testDeviceAuthorizationConcurrency(testServer))
+	t.Run("Test Authorization Responses", testAuthorizationResponses(testServer))
+	t.Run("Test DPoP Bound Tokens", testDPoPBoundTokens(testServer))
+	t.Run("Test Authorization Code Replay", testAuthorizationCodeReplay(testServer))
+	t.Run("Test Authorization Code Cache Keys", testAuthorizationCodeCacheKeys(testServer))
+	t.Run("Test Cache Flood", testCacheFlood(testServer))
+	t.Run("Test Forward Auth", testForwardAuth(testServer))
+	t.Run("Test Legacy Token Verification", testLegacyTokenVerification(testServer))
+	t.Run("Test Ticket Auth", testTicketAuth(testServer))
+	t.Run("Test Profile Link Check", testProfileLinkCheck(testServer))
+	t.Run("Test Profile Import", testProfileImport(testServer))
+	t.Run("Test Admin Backup", testAdminBackup(testServer))
+	t.Run("Test Admin Stats", testAdminStats(testServer))
 }
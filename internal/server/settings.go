@@ -31,7 +31,7 @@ type settingsUpdateProfileInfoPage struct {
 }
 
 func (s *Server) getUpdateProfileInfoPage(writer http.ResponseWriter, _ *http.Request, profileID string) {
-	profileInfo, err := database.GetProfileInformation(s.boltdb, profileID)
+	profileInfo, err := s.store.GetProfileInformation(profileID)
 	if err != nil {
 		sendServerError(
 			writer,
@@ -70,7 +70,7 @@ func (s *Server) updateProfileInformation(writer http.ResponseWriter, request *h
 		Email:    request.PostFormValue("profileEmail"),
 	}
 
-	if err := database.UpdateProfileInformation(s.boltdb, profileID, newProfileInfo); err != nil {
+	if err := s.store.UpdateProfileInformation(profileID, newProfileInfo); err != nil {
 		s.sendHTMLResponse(
 			writer,
 			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to update your profile"),
@@ -175,7 +175,7 @@ func (s *Server) updateProfilePassword(writer http.ResponseWriter, request *http
 		return
 	}
 
-	profile, err := database.GetProfile(s.boltdb, profileID)
+	profile, err := s.store.GetProfile(profileID)
 	if err != nil {
 		s.sendHTMLResponse(
 			writer,
@@ -214,7 +214,7 @@ func (s *Server) updateProfilePassword(writer http.ResponseWriter, request *http
 		return
 	}
 
-	err = database.UpdateHashedPassword(s.boltdb, profileID, newHashedPassword)
+	err = s.store.UpdateHashedPassword(profileID, newHashedPassword)
 	if err != nil {
 		s.sendHTMLResponse(
 			writer,
@@ -6,6 +6,7 @@ package server
 
 import (
 	"fmt"
+	"html"
 	"net/http"
 	"strings"
 	"unicode/utf8"
@@ -29,6 +30,7 @@ type setupForm struct {
 	profileID         string
 	password          string
 	confirmedPassword string
+	skipLinkCheck     bool
 	profile           struct {
 		displayName string
 		url         string
@@ -63,7 +65,7 @@ func (f *setupForm) validate() (fieldErrorLabel, error) {
 }
 
 func (s *Server) setup(writer http.ResponseWriter, request *http.Request) {
-	dbInitialized, err := database.Initialized(s.boltdb)
+	dbInitialized, err := s.store.Initialized()
 	if err != nil {
 		sendServerError(
 			writer,
@@ -124,6 +126,7 @@ func (s *Server) setupAccount(writer http.ResponseWriter, request *http.Request)
 		profileID:         request.PostFormValue("profileID"),
 		password:          request.PostFormValue("password"),
 		confirmedPassword: request.PostFormValue("confirmedPassword"),
+		skipLinkCheck:     request.PostFormValue("skipLinkCheck") == "on",
 		profile: struct {
 			displayName string
 			url         string
@@ -182,6 +185,39 @@ func (s *Server) setupAccount(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
+	// Check that the website at the profile URL delegates to this server
+	// unless the user chose to set up their website later.
+	if !form.skipLinkCheck {
+		problems, err := s.checkProfileLinks(request.Context(), canonicalisedProfielID)
+		if err != nil {
+			s.sendHTMLResponse(
+				writer,
+				fmt.Appendf(
+					[]byte{},
+					responseFailureFmt,
+					"Unable to fetch your website: "+html.EscapeString(err.Error()),
+				),
+				http.StatusUnprocessableEntity,
+				fmt.Errorf("error checking the profile links: %w", err),
+				nil,
+			)
+
+			return
+		}
+
+		if len(problems) > 0 {
+			s.sendHTMLResponse(
+				writer,
+				fmt.Appendf([]byte{}, responseFailureFmt, profileLinkProblemsHTML(problems)),
+				http.StatusUnprocessableEntity,
+				ProfileLinkError{problems: problems},
+				nil,
+			)
+
+			return
+		}
+	}
+
 	// Hash the password
 	hashedPassword, err := auth.HashPassword(form.password)
 	if err != nil {
@@ -207,7 +243,7 @@ func (s *Server) setupAccount(writer http.ResponseWriter, request *http.Request)
 		},
 	}
 
-	if err := database.Setup(s.boltdb, canonicalisedProfielID, profile); err != nil {
+	if err := s.store.Setup(canonicalisedProfielID, profile); err != nil {
 		s.sendHTMLResponse(
 			writer,
 			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to set up your profile"),
@@ -220,7 +256,7 @@ func (s *Server) setupAccount(writer http.ResponseWriter, request *http.Request)
 	}
 
 	// Ensure that the database has been initialized successfully.
-	dbInitialized, err := database.Initialized(s.boltdb)
+	dbInitialized, err := s.store.Initialized()
 	if err != nil {
 		s.sendHTMLResponse(
 			writer,
@@ -0,0 +1,649 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/discovery"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/info"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/policy"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/scopes"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/utilities"
+)
+
+const (
+	ticketGrantType string = "ticket"
+
+	qKeyTicket   string = "ticket"
+	qKeyResource string = "resource"
+	qKeySubject  string = "subject"
+
+	pathTicket string = "/indieauth/ticket"
+
+	settingsTickets     string = "tickets"
+	pathSettingsTickets string = "/profile/settings/tickets"
+
+	ticketKeyFmt       string = "ticket:%s"
+	ticketLifetime            = 10 * time.Minute
+	defaultTicketScope string = "read"
+
+	// receivedTicketsKeyFmt is the cache key of the number of tickets received for a
+	// profile within the current window. Each received ticket makes Beacon fetch the
+	// resource and the issuer's token endpoint so the number of tickets is limited.
+	receivedTicketsKeyFmt string = "received_tickets:%s"
+	receivedTicketLimit          = 10
+	receivedTicketWindow         = 1 * time.Minute
+)
+
+// ticketGrant is the access that the profile owner granted to the subject with a ticket
+// (IndieAuth Ticket Auth). The ticket is redeemed at the token endpoint for an access
+// token that is tied to the subject's URL.
+type ticketGrant struct {
+	Me        string    `json:"me"`
+	Subject   string    `json:"subject"`
+	Resource  string    `json:"resource"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ticketTokenExchange redeems the ticket for an access token. The ticket can only be
+// redeemed once. The access token is issued on behalf of the profile owner to the subject
+// of the ticket so that the subject's reader can access the private resources.
+func (s *Server) ticketTokenExchange(writer http.ResponseWriter, request *http.Request, proof *auth.DPoPProof) {
+	entry, exists := s.cache.GetAndDelete(ticketKey(request.PostFormValue(qKeyTicket)))
+	if !exists {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_grant", ErrInvalidTicket)
+
+		return
+	}
+
+	var grant ticketGrant
+
+	if err := entry.Decode(&grant); err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("unable to decode the ticket from the cache: %w", err),
+		)
+
+		return
+	}
+
+	if entry.Expired() || time.Now().After(grant.ExpiresAt) {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_grant", ErrInvalidTicket)
+
+		return
+	}
+
+	// The scopes may have been removed from the registry since the ticket was issued.
+	resolvedScopes, err := s.scopes.Resolve(grant.Scopes)
+	if err != nil {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_scope", err)
+
+		return
+	}
+
+	if len(resolvedScopes) == 0 {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_scope", ErrNoTicketScopes)
+
+		return
+	}
+
+	grant.Scopes = scopes.Names(resolvedScopes)
+
+	if err := s.recordDPoPProof(proof); err != nil {
+		sendDPoPProofError(writer, err)
+
+		return
+	}
+
+	keyThumbprint := dpopKeyThumbprint(proof)
+
+	tokenType := tokenTypeBearer
+	if keyThumbprint != "" {
+		tokenType = tokenTypeDPoP
+	}
+
+	issuedAt := time.Now()
+
+	accessToken, err := s.createAccessToken(database.AccessToken{
+		ClientID:  grant.Subject,
+		Me:        grant.Me,
+		Scopes:    grant.Scopes,
+		IssuedAt:  issuedAt,
+		ExpiresAt: issuedAt.Add(s.defaultTokenLifetime),
+		JKT:       keyThumbprint,
+	})
+	if err != nil {
+		sendOAuthError(writer, http.StatusInternalServerError, "server_error", err)
+
+		return
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+
+	sendJSONResponse(
+		writer,
+		http.StatusOK,
+		ticketTokenResponse{
+			AccessToken: accessToken,
+			TokenType:   tokenType,
+			Scope:       strings.Join(grant.Scopes, " "),
+			ExpiresIn:   int64(s.defaultTokenLifetime.Seconds()),
+			Me:          grant.Subject,
+			Resource:    grant.Resource,
+		},
+	)
+}
+
+type ticketTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+	Me          string `json:"me"`
+	Resource    string `json:"resource,omitempty"`
+}
+
+// issueTicket creates a new ticket for the grant and saves it in the cache.
+func (s *Server) issueTicket(grant ticketGrant) (string, error) {
+	ticketBytes := make([]byte, 32)
+
+	if _, err := rand.Read(ticketBytes); err != nil {
+		return "", fmt.Errorf("unable to create random bytes: %w", err)
+	}
+
+	ticket := hex.EncodeToString(ticketBytes)
+
+	grant.ExpiresAt = time.Now().Add(ticketLifetime)
+
+	if err := s.cache.Add(ticketKey(ticket), grant, grant.ExpiresAt); err != nil {
+		return "", fmt.Errorf("error saving the ticket: %w", err)
+	}
+
+	return ticket, nil
+}
+
+// ticketKey returns the cache key of the ticket. The key holds the hash of the ticket
+// so that the saved cache entries cannot be redeemed.
+func ticketKey(ticket string) string {
+	return fmt.Sprintf(ticketKeyFmt, auth.HashBearerToken(ticket))
+}
+
+// receiveTicket handles the tickets sent to the ticket endpoint by other websites for one of the
+// profiles. Anyone can send a ticket so the token endpoint is always discovered from the resource
+// and the ticket's issuer must match the issuer that the resource advertises. The ticket is
+// redeemed for an access token which is saved for the subject. An access token that was received
+// for the resource from a different authorization server is not replaced. The number of tickets
+// received for each profile is limited since each ticket makes Beacon send outbound requests.
+func (s *Server) receiveTicket(writer http.ResponseWriter, request *http.Request) {
+	var (
+		ticket   = request.PostFormValue(qKeyTicket)
+		resource = request.PostFormValue(qKeyResource)
+		issuer   = request.PostFormValue(qKeyIssuer)
+	)
+
+	if ticket == "" || resource == "" {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_request", ErrIncompleteTicket)
+
+		return
+	}
+
+	subject, err := utilities.ValidateAndCanonicalizeURL(request.PostFormValue(qKeySubject), false)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_request",
+			fmt.Errorf("error canonicalizing the subject: %w", err),
+		)
+
+		return
+	}
+
+	exists, err := s.store.ProfileExists(subject)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error looking up the subject in the database: %w", err),
+		)
+
+		return
+	}
+
+	if !exists {
+		sendOAuthError(writer, http.StatusBadRequest, "invalid_request", UnknownTicketSubjectError{subject: subject})
+
+		return
+	}
+
+	allowed, err := s.allowReceivedTicket(subject)
+	if err != nil {
+		sendOAuthCacheError(writer, fmt.Errorf("error counting the received tickets: %w", err))
+
+		return
+	}
+
+	if !allowed {
+		writer.Header().Set("Retry-After", strconv.Itoa(int(receivedTicketWindow.Seconds())))
+		sendOAuthError(writer, http.StatusTooManyRequests, "temporarily_unavailable", ErrTooManyTickets)
+
+		return
+	}
+
+	endpoints, err := s.ticketIssuerEndpoints(request.Context(), issuer, resource)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_request",
+			fmt.Errorf("error discovering the token endpoint of the ticket's issuer: %w", err),
+		)
+
+		return
+	}
+
+	if err := s.checkReceivedTokenIssuer(subject, resource, endpoints); err != nil {
+		if errors.As(err, &ReceivedTokenIssuerMismatchError{}) {
+			sendOAuthError(writer, http.StatusBadRequest, "invalid_request", err)
+		} else {
+			sendOAuthError(writer, http.StatusInternalServerError, "server_error", err)
+		}
+
+		return
+	}
+
+	token, err := s.redeemTicket(request.Context(), endpoints.TokenEndpoint, ticket)
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusBadRequest,
+			"invalid_grant",
+			fmt.Errorf("error redeeming the ticket: %w", err),
+		)
+
+		return
+	}
+
+	token.Resource = resource
+	token.Subject = subject
+	token.Issuer = endpoints.Issuer
+	token.TokenEndpoint = endpoints.TokenEndpoint
+
+	if err := s.store.SaveReceivedToken(token); err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error saving the received token: %w", err),
+		)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// allowReceivedTicket counts the ticket received for the subject and returns false if the
+// subject already received the maximum number of tickets within the current window.
+func (s *Server) allowReceivedTicket(subject string) (bool, error) {
+	key := fmt.Sprintf(receivedTicketsKeyFmt, subject)
+
+	for {
+		entry, exists := s.cache.Get(key)
+		if !exists || entry.Expired() {
+			added, err := s.cache.AddIfAbsent(key, 1, time.Now().Add(receivedTicketWindow))
+			if err != nil {
+				return false, err
+			}
+
+			if added {
+				return true, nil
+			}
+
+			continue
+		}
+
+		var count int
+
+		if err := entry.Decode(&count); err != nil {
+			return false, err
+		}
+
+		if count >= receivedTicketLimit {
+			return false, nil
+		}
+
+		// The count is read again if another ticket was counted at the same time.
+		swapped, err := s.cache.CompareAndSwap(key, entry, count+1, entry.ExpiresAt())
+		if err != nil {
+			return false, err
+		}
+
+		if swapped {
+			return true, nil
+		}
+	}
+}
+
+// ticketIssuerEndpoints returns the endpoints of the authorization server of the resource.
+// The endpoints are discovered from the resource so that the ticket's sender cannot choose
+// the token endpoint, and the ticket's issuer, if given, must match the issuer in the
+// resource's metadata.
+func (s *Server) ticketIssuerEndpoints(ctx context.Context, issuer, resource string) (discovery.Endpoints, error) {
+	endpoints, err := discovery.DiscoverEndpoints(ctx, s.outboundClient, resource, s.issuer)
+	if err != nil {
+		return discovery.Endpoints{}, err
+	}
+
+	if issuer != "" && endpoints.Issuer != issuer {
+		return discovery.Endpoints{}, ErrInvalidTicketIssuer
+	}
+
+	if endpoints.TokenEndpoint == "" {
+		return discovery.Endpoints{}, ErrMissingTokenEndpoint
+	}
+
+	return endpoints, nil
+}
+
+// checkReceivedTokenIssuer returns an error if the subject already has an access token for
+// the resource that was received from a different authorization server.
+func (s *Server) checkReceivedTokenIssuer(subject, resource string, endpoints discovery.Endpoints) error {
+	receivedTokens, err := s.store.GetReceivedTokens(subject)
+	if err != nil {
+		return fmt.Errorf("error getting the received tokens: %w", err)
+	}
+
+	for _, token := range receivedTokens {
+		if token.Resource != resource {
+			continue
+		}
+
+		if token.Issuer != endpoints.Issuer || token.TokenEndpoint != endpoints.TokenEndpoint {
+			return ReceivedTokenIssuerMismatchError{resource: resource, issuer: token.Issuer}
+		}
+	}
+
+	return nil
+}
+
+// redeemTicket redeems the ticket at the issuer's token endpoint.
+func (s *Server) redeemTicket(ctx context.Context, tokenEndpoint, ticket string) (database.ReceivedToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", ticketGrantType)
+	form.Set(qKeyTicket, ticket)
+
+	response, err := s.postForm(ctx, tokenEndpoint, form)
+	if err != nil {
+		return database.ReceivedToken{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return database.ReceivedToken{}, UnexpectedStatusError{url: tokenEndpoint, status: response.Status}
+	}
+
+	var tokenResponse ticketTokenResponse
+
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return database.ReceivedToken{}, fmt.Errorf("unable to decode the token response: %w", err)
+	}
+
+	if tokenResponse.AccessToken == "" {
+		return database.ReceivedToken{}, errors.New("the token response does not contain an access token")
+	}
+
+	receivedAt := time.Now().UTC()
+
+	token := database.ReceivedToken{
+		AccessToken: tokenResponse.AccessToken,
+		TokenType:   tokenResponse.TokenType,
+		Scope:       tokenResponse.Scope,
+		ReceivedAt:  receivedAt,
+	}
+
+	if tokenResponse.ExpiresIn > 0 {
+		token.ExpiresAt = receivedAt.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// postForm sends the form to the URL with the outbound client.
+func (s *Server) postForm(ctx context.Context, target string, form url.Values) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating the HTTP request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set(
+		"User-Agent",
+		fmt.Sprintf("%s/%s (+%s)", info.ApplicationTitledName, info.BinaryVersion, s.issuer),
+	)
+
+	response, err := s.outboundClient.Do(request) //#nosec G704 - The outbound client checks every address that it connects to.
+	if err != nil {
+		return nil, fmt.Errorf("error sending the request to %s: %w", target, err)
+	}
+
+	return response, nil
+}
+
+// sendTicket sends a ticket to the ticket endpoint advertised by the subject's website.
+// The ticket grants the subject access to the resource on behalf of the profile owner.
+func (s *Server) sendTicket(ctx context.Context, ticketEndpoint string, grant ticketGrant) error {
+	ticket, err := s.issueTicket(grant)
+	if err != nil {
+		return fmt.Errorf("error issuing the ticket: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set(qKeyTicket, ticket)
+	form.Set(qKeyResource, grant.Resource)
+	form.Set(qKeySubject, grant.Subject)
+	form.Set(qKeyIssuer, s.issuer)
+
+	response, err := s.postForm(ctx, ticketEndpoint, form)
+	if err != nil {
+		s.cache.Delete(ticketKey(ticket))
+
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		s.cache.Delete(ticketKey(ticket))
+
+		return UnexpectedStatusError{url: ticketEndpoint, status: response.Status}
+	}
+
+	return nil
+}
+
+type settingsTicketsPage struct {
+	ActiveTab        string
+	ProfileID        string
+	Title            string
+	SettingsCategory string
+	DefaultScope     string
+	ReceivedTokens   []database.ReceivedToken
+}
+
+func (s *Server) getTicketsPage(writer http.ResponseWriter, _ *http.Request, profileID string) {
+	receivedTokens, err := s.store.GetReceivedTokens(profileID)
+	if err != nil {
+		sendServerError(
+			writer,
+			fmt.Errorf("error getting the received tokens: %w", err),
+		)
+
+		return
+	}
+
+	page := settingsTicketsPage{
+		ActiveTab:        activeTabSettings,
+		ProfileID:        profileID,
+		Title:            ticketsPageTitle(),
+		SettingsCategory: settingsTickets,
+		DefaultScope:     defaultTicketScope,
+		ReceivedTokens:   receivedTokens,
+	}
+
+	s.sendHTMLResponseWithTemplate(
+		writer,
+		"settings",
+		http.StatusOK,
+		page,
+		nil,
+		nil,
+	)
+}
+
+func (s *Server) grantAccess(writer http.ResponseWriter, request *http.Request, profileID string) {
+	subject, err := utilities.ValidateAndCanonicalizeURL(request.PostFormValue("subject"), false)
+	if err != nil {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "subject_error",
+				message: "Please enter the URL of the person's website",
+			},
+			fmt.Errorf("error canonicalizing the subject: %w", err),
+		)
+
+		return
+	}
+
+	resource := request.PostFormValue("resource")
+	if resource == "" {
+		resource = profileID
+	}
+
+	if parsedResource, err := url.Parse(resource); err != nil || !parsedResource.IsAbs() {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "resource_error",
+				message: "Please enter the URL of the resource",
+			},
+			formValidationError{reason: "the resource " + resource + " is not an absolute URL"},
+		)
+
+		return
+	}
+
+	requestedScopes := policy.ParseList(request.PostFormValue("scope"))
+	if len(requestedScopes) == 0 {
+		requestedScopes = []string{defaultTicketScope}
+	}
+
+	// Only the scopes in the registry can be granted with a ticket.
+	resolvedScopes, err := s.scopes.Resolve(requestedScopes)
+	if err == nil && len(resolvedScopes) == 0 {
+		err = ErrNoTicketScopes
+	}
+
+	if err != nil {
+		s.sendFieldError(
+			writer,
+			fieldErrorLabel{
+				labelID: "scope_error",
+				message: "Please enter scopes that are supported by the server",
+			},
+			err,
+		)
+
+		return
+	}
+
+	endpoints, err := discovery.DiscoverEndpoints(request.Context(), s.outboundClient, subject, s.issuer)
+	if err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to discover the endpoints of "+subject),
+			http.StatusBadGateway,
+			nil,
+			fmt.Errorf("error discovering the subject's endpoints: %w", err),
+		)
+
+		return
+	}
+
+	if endpoints.TicketEndpoint == "" {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, subject+" does not advertise a ticket endpoint"),
+			http.StatusUnprocessableEntity,
+			fmt.Errorf("%w: %s", ErrMissingTicketEndpoint, subject),
+			nil,
+		)
+
+		return
+	}
+
+	if err := s.sendTicket(request.Context(), endpoints.TicketEndpoint, ticketGrant{
+		Me:       profileID,
+		Subject:  subject,
+		Resource: resource,
+		Scopes:   scopes.Names(resolvedScopes),
+	}); err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to send the ticket to "+subject),
+			http.StatusBadGateway,
+			nil,
+			fmt.Errorf("error sending the ticket: %w", err),
+		)
+
+		return
+	}
+
+	s.sendHTMLResponse(
+		writer,
+		fmt.Appendf([]byte{}, responseSuccessFmt, "Successfully sent the ticket to "+subject),
+		http.StatusOK,
+		nil,
+		nil,
+	)
+}
+
+func (s *Server) deleteReceivedToken(writer http.ResponseWriter, request *http.Request, profileID string) {
+	if err := s.store.DeleteReceivedToken(profileID, request.PostFormValue("resource")); err != nil {
+		s.sendHTMLResponse(
+			writer,
+			fmt.Appendf([]byte{}, responseFailureFmt, "Unable to delete the access token"),
+			http.StatusInternalServerError,
+			nil,
+			fmt.Errorf("error deleting the received token: %w", err),
+		)
+
+		return
+	}
+
+	writer.Header().Set("Hx-Redirect", pathSettingsTickets)
+}
+
+func ticketsPageTitle() string {
+	return "Tickets - Settings - " + info.ApplicationTitledName
+}
@@ -0,0 +1,347 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/outbound"
+)
+
+func testTicketAuth(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		profileID := "https://tickets.example.net/"
+		subject := "https://reader.example.org/"
+
+		if err := srv.store.CreateProfile(profileID, database.Profile{}); err != nil {
+			t.Fatalf("FAILED test %s: Unable to create the profile: %v", t.Name(), err)
+		}
+
+		// The ticket sent to the subject is redeemed once for an access token tied to the subject's URL.
+		ticket, err := srv.issueTicket(ticketGrant{
+			Me:       profileID,
+			Subject:  subject,
+			Resource: profileID,
+			Scopes:   []string{"read"},
+		})
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to issue the ticket: %v", t.Name(), err)
+		}
+
+		redeemForm := url.Values{
+			"grant_type": {ticketGrantType},
+			qKeyTicket:   {ticket},
+		}
+
+		response := sendTestForm(t, srv.tokenGrant, redeemForm)
+
+		var token ticketTokenResponse
+
+		if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+			t.Fatalf("FAILED test %s: Unable to decode the token response: %v", t.Name(), err)
+		}
+
+		if response.Code != http.StatusOK ||
+			token.AccessToken == "" ||
+			token.Me != subject ||
+			token.Scope != "read" ||
+			token.TokenType != tokenTypeBearer {
+			t.Errorf(
+				"FAILED test %s: Unexpected response after redeeming the ticket.\ngot: %d %+v",
+				t.Name(),
+				response.Code,
+				token,
+			)
+		} else {
+			t.Logf("Expected access token issued for the subject %s.", token.Me)
+		}
+
+		checkOAuthError(t, sendTestForm(t, srv.tokenGrant, redeemForm), "invalid_grant")
+
+		// The access token is issued on behalf of the profile owner to the subject.
+		issuedToken, found, err := srv.store.GetAccessToken(auth.HashBearerToken(token.AccessToken))
+		if err != nil || !found {
+			t.Fatalf("FAILED test %s: Unable to get the issued access token.\nfound: %t\nerror: %v", t.Name(), found, err)
+		}
+
+		if issuedToken.Me != profileID || issuedToken.ClientID != subject {
+			t.Errorf(
+				"FAILED test %s: Unexpected access token saved after redeeming the ticket.\nwant: me=%s client_id=%s\ngot: me=%s client_id=%s",
+				t.Name(),
+				profileID,
+				subject,
+				issuedToken.Me,
+				issuedToken.ClientID,
+			)
+		} else {
+			t.Logf("Expected access token saved for %s on behalf of %s.", issuedToken.ClientID, issuedToken.Me)
+		}
+
+		// Tickets with scopes that are not in the scope registry are rejected.
+		ticket, err = srv.issueTicket(ticketGrant{
+			Me:       profileID,
+			Subject:  subject,
+			Resource: profileID,
+			Scopes:   []string{"unknown-scope"},
+		})
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to issue the ticket: %v", t.Name(), err)
+		}
+
+		redeemForm.Set(qKeyTicket, ticket)
+		checkOAuthError(t, sendTestForm(t, srv.tokenGrant, redeemForm), "invalid_scope")
+
+		// Only one of the concurrent requests with the same ticket is issued an access token.
+		ticket, err = srv.issueTicket(ticketGrant{
+			Me:       profileID,
+			Subject:  subject,
+			Resource: profileID,
+			Scopes:   []string{"read"},
+		})
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to issue the ticket: %v", t.Name(), err)
+		}
+
+		redeemForm.Set(qKeyTicket, ticket)
+
+		var (
+			wg     sync.WaitGroup
+			issued atomic.Int32
+		)
+
+		for range 10 {
+			wg.Go(func() {
+				if sendTestForm(t, srv.tokenGrant, redeemForm).Code == http.StatusOK {
+					issued.Add(1)
+				}
+			})
+		}
+
+		wg.Wait()
+
+		if issued.Load() != 1 {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of access tokens issued for the same ticket.\nwant: 1\ngot: %d",
+				t.Name(),
+				issued.Load(),
+			)
+		} else {
+			t.Log("Only one access token was issued for the ticket.")
+		}
+
+		// A ticket received for one of the profiles is redeemed at the issuer's token endpoint.
+		defer useTestOutboundClient(t, srv)()
+
+		mux := http.NewServeMux()
+		issuer := httptest.NewServer(mux)
+
+		defer issuer.Close()
+
+		mux.HandleFunc("GET /.well-known/oauth-authorization-server", func(writer http.ResponseWriter, _ *http.Request) {
+			writer.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(writer).Encode(map[string]string{
+				"issuer":         issuer.URL + "/",
+				"token_endpoint": issuer.URL + "/token",
+			})
+		})
+
+		// The resource links to the metadata of its authorization server.
+		mux.HandleFunc("GET /private/", func(writer http.ResponseWriter, _ *http.Request) {
+			writer.Header().Set("Link", `</.well-known/oauth-authorization-server>; rel="indieauth-metadata"`)
+			writer.Header().Set("Content-Type", "text/html")
+			_, _ = writer.Write([]byte("<html></html>"))
+		})
+
+		mux.HandleFunc("POST /token", func(writer http.ResponseWriter, request *http.Request) {
+			if request.PostFormValue("grant_type") != ticketGrantType || request.PostFormValue(qKeyTicket) != "received-ticket" {
+				writer.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			writer.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(writer).Encode(ticketTokenResponse{
+				AccessToken: "received-access-token",
+				TokenType:   tokenTypeBearer,
+				Scope:       "read",
+				ExpiresIn:   3600,
+				Me:          profileID,
+			})
+		})
+
+		resource := issuer.URL + "/private/"
+
+		response = sendTestForm(t, srv.receiveTicket, url.Values{
+			qKeyTicket:   {"received-ticket"},
+			qKeySubject:  {profileID},
+			qKeyResource: {resource},
+			qKeyIssuer:   {issuer.URL + "/"},
+		})
+
+		if response.Code != http.StatusAccepted {
+			t.Fatalf(
+				"FAILED test %s: Unexpected status code received from the ticket endpoint.\nwant: %d\ngot: %d (%s)",
+				t.Name(),
+				http.StatusAccepted,
+				response.Code,
+				response.Body.String(),
+			)
+		}
+
+		receivedTokens, err := srv.store.GetReceivedTokens(profileID)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to get the received tokens: %v", t.Name(), err)
+		}
+
+		if len(receivedTokens) != 1 ||
+			receivedTokens[0].AccessToken != "received-access-token" ||
+			receivedTokens[0].Resource != resource ||
+			receivedTokens[0].TokenEndpoint != issuer.URL+"/token" {
+			t.Errorf(
+				"FAILED test %s: Unexpected tokens saved after receiving the ticket.\ngot: %+v",
+				t.Name(),
+				receivedTokens,
+			)
+		} else {
+			t.Logf("Expected access token saved after receiving the ticket.\ngot: %+v", receivedTokens[0])
+		}
+
+		// Tickets from an issuer that is not the resource's authorization server are rejected.
+		checkOAuthError(t, sendTestForm(t, srv.receiveTicket, url.Values{
+			qKeyTicket:   {"received-ticket"},
+			qKeySubject:  {profileID},
+			qKeyResource: {resource},
+			qKeyIssuer:   {"https://attacker.example.net/"},
+		}), "invalid_request")
+
+		// The access token received from a different issuer is not replaced.
+		otherToken := database.ReceivedToken{
+			Resource:      resource,
+			Subject:       profileID,
+			Issuer:        "https://other.example.net/",
+			TokenEndpoint: "https://other.example.net/token",
+			AccessToken:   "other-access-token",
+			TokenType:     tokenTypeBearer,
+			ReceivedAt:    time.Now().UTC(),
+		}
+
+		if err := srv.store.SaveReceivedToken(otherToken); err != nil {
+			t.Fatalf("FAILED test %s: Unable to save the received token: %v", t.Name(), err)
+		}
+
+		checkOAuthError(t, sendTestForm(t, srv.receiveTicket, url.Values{
+			qKeyTicket:   {"received-ticket"},
+			qKeySubject:  {profileID},
+			qKeyResource: {resource},
+			qKeyIssuer:   {issuer.URL + "/"},
+		}), "invalid_request")
+
+		receivedTokens, err = srv.store.GetReceivedTokens(profileID)
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to get the received tokens: %v", t.Name(), err)
+		}
+
+		if len(receivedTokens) != 1 || receivedTokens[0].AccessToken != otherToken.AccessToken {
+			t.Errorf(
+				"FAILED test %s: The access token received from a different issuer was replaced.\ngot: %+v",
+				t.Name(),
+				receivedTokens,
+			)
+		} else {
+			t.Log("The access token received from a different issuer was not replaced.")
+		}
+
+		// Tickets for unknown subjects are rejected.
+		checkOAuthError(t, sendTestForm(t, srv.receiveTicket, url.Values{
+			qKeyTicket:   {"received-ticket"},
+			qKeySubject:  {"https://unknown.example.net/"},
+			qKeyResource: {resource},
+		}), "invalid_request")
+
+		// The tickets are saved in the cache by their hash.
+		ticket, err = srv.issueTicket(ticketGrant{
+			Me:       profileID,
+			Subject:  subject,
+			Resource: profileID,
+			Scopes:   []string{"read"},
+		})
+		if err != nil {
+			t.Fatalf("FAILED test %s: Unable to issue the ticket: %v", t.Name(), err)
+		}
+
+		if _, exists := srv.cache.Get(fmt.Sprintf(ticketKeyFmt, ticket)); exists {
+			t.Errorf("FAILED test %s: The ticket was saved in the cache by its value.", t.Name())
+		} else {
+			t.Log("The ticket was not saved in the cache by its value.")
+		}
+
+		if _, exists := srv.cache.GetAndDelete(ticketKey(ticket)); !exists {
+			t.Errorf("FAILED test %s: The ticket was not saved in the cache by its hash.", t.Name())
+		}
+
+		// The number of tickets received for each profile is limited.
+		limitedSubject := "https://limited.example.net/"
+		allowed := 0
+
+		for range receivedTicketLimit + 5 {
+			ok, err := srv.allowReceivedTicket(limitedSubject)
+			if err != nil {
+				t.Fatalf("FAILED test %s: Unable to count the received ticket: %v", t.Name(), err)
+			}
+
+			if ok {
+				allowed++
+			}
+		}
+
+		if allowed != receivedTicketLimit {
+			t.Errorf(
+				"FAILED test %s: Unexpected number of received tickets allowed.\nwant: %d\ngot: %d",
+				t.Name(),
+				receivedTicketLimit,
+				allowed,
+			)
+		} else {
+			t.Logf("%d of the received tickets were allowed.", allowed)
+		}
+	}
+}
+
+// useTestOutboundClient replaces the server's outbound client with one that can
+// connect to the test servers on the loopback address. The returned function
+// restores the original outbound client.
+func useTestOutboundClient(t *testing.T, srv *Server) func() {
+	t.Helper()
+
+	guard, err := outbound.NewGuard([]string{})
+	if err != nil {
+		t.Fatalf("FAILED test %s: Unable to create the outbound guard: %v", t.Name(), err)
+	}
+
+	outboundClient := srv.outboundClient
+
+	srv.outboundClient = outbound.NewClient(
+		guard,
+		outbound.Settings{
+			ConnectTimeout:  1 * time.Second,
+			Timeout:         5 * time.Second,
+			MaxResponseSize: 1 << 20,
+		},
+	)
+
+	return func() {
+		srv.outboundClient = outboundClient
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+)
+
+type tokenVerificationResponse struct {
+	Me       string `json:"me"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// verifyAccessToken handles the token verification requests from the resource servers using
+// the older IndieAuth method where the access token is sent in the Authorization header of a
+// GET request to the token endpoint. DPoP bound access tokens cannot be verified this way as
+// the resource server does not forward the client's proof. The response is form-encoded for
+// the resource servers that explicitly ask for it, otherwise the response is in JSON.
+func (s *Server) verifyAccessToken(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "no-store")
+
+	bearerToken, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.TrimSpace(bearerToken) == "" {
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+		sendOAuthError(writer, http.StatusUnauthorized, "invalid_request", ErrMissingBearerToken)
+
+		return
+	}
+
+	token, found, err := s.store.GetAccessToken(auth.HashBearerToken(strings.TrimSpace(bearerToken)))
+	if err != nil {
+		sendOAuthError(
+			writer,
+			http.StatusInternalServerError,
+			"server_error",
+			fmt.Errorf("error getting the access token: %w", err),
+		)
+
+		return
+	}
+
+	switch {
+	case !found, time.Now().After(token.ExpiresAt):
+		err = ErrInvalidAccessToken
+	case token.JKT != "":
+		err = ErrDPoPBoundAccessToken
+	}
+
+	if err != nil {
+		writer.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		sendOAuthError(writer, http.StatusUnauthorized, "invalid_token", err)
+
+		return
+	}
+
+	response := tokenVerificationResponse{
+		Me:       token.Me,
+		ClientID: token.ClientID,
+		Scope:    strings.Join(token.Scopes, " "),
+	}
+
+	if strings.Contains(request.Header.Get("Accept"), "application/x-www-form-urlencoded") &&
+		!strings.Contains(request.Header.Get("Accept"), "application/json") {
+		form := url.Values{}
+		form.Set(qKeyMe, response.Me)
+		form.Set(qKeyClientID, response.ClientID)
+		form.Set(qKeyScope, response.Scope)
+
+		writer.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(form.Encode()))
+
+		return
+	}
+
+	sendJSONResponse(writer, http.StatusOK, response)
+}
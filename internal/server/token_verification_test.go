@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2026 Dan Anglin <d.n.i.anglin@gmail.com>
+//
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/auth"
+	"codeflow.dananglin.me.uk/apollo/beacon/internal/database"
+)
+
+func testLegacyTokenVerification(srv *Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		saveToken := func(token database.AccessToken) string {
+			bearerToken, err := auth.CreateBearerToken()
+			if err != nil {
+				t.Fatalf("FAILED test %s: Unable to create the bearer token: %v", t.Name(), err)
+			}
+
+			if err := srv.store.SaveAccessToken(auth.HashBearerToken(bearerToken), token); err != nil {
+				t.Fatalf("FAILED test %s: Unable to save the access token: %v", t.Name(), err)
+			}
+
+			return bearerToken
+		}
+
+		verify := func(bearerToken, accept string) *httptest.ResponseRecorder {
+			request := httptest.NewRequest(http.MethodGet, pathToken, nil)
+			request.Header.Set("Authorization", "Bearer "+bearerToken)
+
+			if accept != "" {
+				request.Header.Set("Accept", accept)
+			}
+
+			writer := httptest.NewRecorder()
+			srv.verifyAccessToken(writer, request)
+
+			return writer
+		}
+
+		now := time.Now()
+
+		validToken := saveToken(database.AccessToken{
+			ClientID:  "https://micropub.example.net/",
+			Me:        "https://billjones.example.net/",
+			Scopes:    []string{"create", "update"},
+			IssuedAt:  now,
+			ExpiresAt: now.Add(1 * time.Hour),
+		})
+
+		want := tokenVerificationResponse{
+			Me:       "https://billjones.example.net/",
+			ClientID: "https://micropub.example.net/",
+			Scope:    "create update",
+		}
+
+		response := verify(validToken, "application/json")
+
+		var got tokenVerificationResponse
+
+		if err := json.NewDecoder(response.Body).Decode(&got); err != nil || response.Code != http.StatusOK || got != want {
+			t.Errorf(
+				"FAILED test %s: Unexpected JSON response for the valid access token.\nwant: %+v\ngot: %d %+v (%v)",
+				t.Name(),
+				want,
+				response.Code,
+				got,
+				err,
+			)
+		} else {
+			t.Logf("Expected JSON response for the valid access token.\ngot: %+v", got)
+		}
+
+		response = verify(validToken, "application/x-www-form-urlencoded")
+
+		form, err := url.ParseQuery(response.Body.String())
+		if err != nil || form.Get(qKeyMe) != want.Me || form.Get(qKeyClientID) != want.ClientID || form.Get(qKeyScope) != want.Scope {
+			t.Errorf(
+				"FAILED test %s: Unexpected form-encoded response for the valid access token.\ngot: %q (%v)",
+				t.Name(),
+				response.Body.String(),
+				err,
+			)
+		} else {
+			t.Logf("Expected form-encoded response for the valid access token.\ngot: %q", response.Body.String())
+		}
+
+		testCases := []struct {
+			name  string
+			token string
+		}{
+			{
+				name: "expired access token",
+				token: saveToken(database.AccessToken{
+					ClientID:  "https://micropub.example.net/",
+					Me:        "https://billjones.example.net/",
+					Scopes:    []string{"create"},
+					IssuedAt:  now.Add(-2 * time.Hour),
+					ExpiresAt: now.Add(-1 * time.Hour),
+				}),
+			},
+			{
+				name: "DPoP bound access token",
+				token: saveToken(database.AccessToken{
+					ClientID:  "https://micropub.example.net/",
+					Me:        "https://billjones.example.net/",
+					Scopes:    []string{"create"},
+					IssuedAt:  now,
+					ExpiresAt: now.Add(1 * time.Hour),
+					JKT:       "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I",
+				}),
+			},
+			{
+				name:  "unknown access token",
+				token: "unknown",
+			},
+		}
+
+		for _, tc := range testCases {
+			if response := verify(tc.token, ""); response.Code != http.StatusUnauthorized {
+				t.Errorf(
+					"FAILED test %s: Unexpected status code for the %s.\nwant: %d\ngot: %d",
+					t.Name(),
+					tc.name,
+					http.StatusUnauthorized,
+					response.Code,
+				)
+			} else {
+				t.Logf("Expected status code received for the %s.", tc.name)
+			}
+		}
+	}
+}
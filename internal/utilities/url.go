@@ -7,7 +7,7 @@ package utilities
 import (
 	"errors"
 	"fmt"
-	"net"
+	"net/netip"
 	"net/url"
 	"regexp"
 )
@@ -28,10 +28,26 @@ var (
 	ErrURLHasNoPathSegment       = errors.New("the URL does not contain a path segment")
 )
 
+type urlValidationOptions struct {
+	allowPort       bool
+	allowLoopbackIP bool
+}
+
 // ValidateAndCanonicalizeURL validates the given URL according to the indieauth
 // specification. The canonicalized URL is returned after it passes the
 // validation checks.
 func ValidateAndCanonicalizeURL(inputURL string, allowPort bool) (string, error) {
+	return validateAndCanonicalizeURL(inputURL, urlValidationOptions{allowPort: allowPort})
+}
+
+// ValidateAndCanonicalizeClientID validates the given client ID according to the
+// indieauth specification. Unlike profile URLs, client IDs may contain a port and
+// the host may be the IPv4 loopback address 127.0.0.1 or the IPv6 loopback address [::1].
+func ValidateAndCanonicalizeClientID(clientID string) (string, error) {
+	return validateAndCanonicalizeURL(clientID, urlValidationOptions{allowPort: true, allowLoopbackIP: true})
+}
+
+func validateAndCanonicalizeURL(inputURL string, opts urlValidationOptions) (string, error) {
 	// This regular expression pattern is used to get the URL's scheme
 	// to check if it is missing. If missing, the scheme is set to https.
 	schemePattern := regexp.MustCompile(`^[a-z].*:\/\/|^[a-z].*:`)
@@ -50,14 +66,14 @@ func ValidateAndCanonicalizeURL(inputURL string, allowPort bool) (string, error)
 		parsedURL.Path = "/"
 	}
 
-	if err := validateURL(parsedURL, allowPort); err != nil {
+	if err := validateURL(parsedURL, opts); err != nil {
 		return "", err
 	}
 
 	return parsedURL.String(), nil
 }
 
-func validateURL(inputURL *url.URL, allowPort bool) error {
+func validateURL(inputURL *url.URL, opts urlValidationOptions) error {
 	if inputURL.Scheme != httpsScheme && inputURL.Scheme != httpScheme {
 		return ErrInvalidURLScheme
 	}
@@ -74,12 +90,14 @@ func validateURL(inputURL *url.URL, allowPort bool) error {
 		return ErrURLContainsFragment
 	}
 
-	if !allowPort && inputURL.Port() != "" {
+	if !opts.allowPort && inputURL.Port() != "" {
 		return ErrURLContainsPort
 	}
 
-	if ip := net.ParseIP(inputURL.Hostname()); ip != nil {
-		return ErrHostIsIPAddress
+	if addr, err := netip.ParseAddr(inputURL.Hostname()); err == nil {
+		if !opts.allowLoopbackIP || !IsLoopbackIPLiteral(addr) {
+			return ErrHostIsIPAddress
+		}
 	}
 
 	if inputURL.User.String() != "" {
@@ -95,3 +113,9 @@ func validateURL(inputURL *url.URL, allowPort bool) error {
 
 	return nil
 }
+
+// IsLoopbackIPLiteral returns true if the address is either the IPv4 loopback
+// address 127.0.0.1 or the IPv6 loopback address ::1.
+func IsLoopbackIPLiteral(addr netip.Addr) bool {
+	return addr == netip.MustParseAddr("127.0.0.1") || addr == netip.IPv6Loopback()
+}
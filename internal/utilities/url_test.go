@@ -69,6 +69,8 @@ func TestValidateAndCanonicalizeURL(t *testing.T) {
 		t.Run(tc.name, testValidURL(tc.url, tc.want, true))
 	}
 
+	t.Run("Client IDs using loopback addresses", testLoopbackClientIDs)
+
 	errorCases := []struct {
 		name      string
 		url       string
@@ -188,3 +190,53 @@ func testInvalidURL(url string, wantErr error) func(t *testing.T) {
 		}
 	}
 }
+
+func testLoopbackClientIDs(t *testing.T) {
+	t.Parallel()
+
+	validClientIDs := map[string]string{
+		"http://127.0.0.1:3000":      "http://127.0.0.1:3000/",
+		"http://[::1]:3000/app":      "http://[::1]:3000/app",
+		"http://localhost:8080/app/": "http://localhost:8080/app/",
+	}
+
+	for clientID, want := range validClientIDs {
+		got, err := utilities.ValidateAndCanonicalizeClientID(clientID)
+		if err != nil {
+			t.Errorf(
+				"FAILED test %s: Unexpected error received after canonicalizing the client ID %q.\ngot %q",
+				t.Name(),
+				clientID,
+				err.Error(),
+			)
+
+			continue
+		}
+
+		if got != want {
+			t.Errorf(
+				"FAILED test %s: Unexpected canonicalized client ID returned.\nwant: %q\ngot: %q",
+				t.Name(),
+				want,
+				got,
+			)
+		} else {
+			t.Logf("Expected canonicalized client ID returned.\ngot: %q", got)
+		}
+	}
+
+	// Only the loopback addresses are permitted.
+	for _, clientID := range []string{"http://127.0.0.2:3000/", "http://192.168.82.56/"} {
+		if _, err := utilities.ValidateAndCanonicalizeClientID(clientID); !errors.Is(err, utilities.ErrHostIsIPAddress) {
+			t.Errorf(
+				"FAILED test %s: Unexpected error received using the client ID %q.\nwant: %q\ngot: %v",
+				t.Name(),
+				clientID,
+				utilities.ErrHostIsIPAddress.Error(),
+				err,
+			)
+		} else {
+			t.Logf("Expected error received using the client ID %q.\ngot: %q", clientID, err.Error())
+		}
+	}
+}